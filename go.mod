@@ -6,6 +6,10 @@ require (
 	//github.com/self-cream/SCV v0.0.0-20201213061001-6c337bbac7a5
 	github.com/NJUPT-ISL/SCV v0.0.0-20200908005541-d990930d5755
 	github.com/go-logr/logr v0.3.0
+	github.com/golang/protobuf v1.4.3
+	github.com/prometheus/client_golang v1.7.1
+	google.golang.org/grpc v1.27.1
+	google.golang.org/protobuf v1.25.0
 	k8s.io/api v0.20.0
 	k8s.io/apimachinery v0.20.0
 	k8s.io/client-go v0.20.0
@@ -16,20 +16,32 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"github.com/NJUPT-ISL/NodeSimulator/pkg/controllers/pod"
 	scv1 "github.com/NJUPT-ISL/SCV/api/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/util/workqueue"
 	"k8s.io/klog"
 	"os"
+	"strings"
+	"time"
 
 	simv1 "github.com/NJUPT-ISL/NodeSimulator/pkg/api/v1"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/autoscaler"
 	"github.com/NJUPT-ISL/NodeSimulator/pkg/controllers/node"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/controllers/workload"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/metricsapi"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/report"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/snapshot"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/trace"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/util"
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	// +kubebuilder:scaffold:imports
 )
@@ -49,7 +61,39 @@ func init() {
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
+	var metricsAPIAddr, metricsAPICert, metricsAPIKey string
+	var tracePath, traceFormat, traceNamespace, traceScheduler string
+	var traceSpeed float64
+	var traceGPUMemory uint64
+	var traceLimit int
+	var reportDir string
+	var reportInterval time.Duration
+	var clockSpeed float64
+	var clockState string
+	var seed int64
+	var snapshotPath, restorePath string
+	var autoscalerAddr, autoscalerCert, autoscalerKey string
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8081", "The address the metric endpoint binds to.")
+	flag.StringVar(&metricsAPIAddr, "metrics-api-addr", ":4443", "The address the metrics.k8s.io API binds to, empty to disable it.")
+	flag.StringVar(&metricsAPICert, "metrics-api-cert", "", "The serving certificate of the metrics.k8s.io API, self-signed when empty.")
+	flag.StringVar(&metricsAPIKey, "metrics-api-key", "", "The key of the serving certificate of the metrics.k8s.io API.")
+	flag.StringVar(&tracePath, "trace", "", "The trace file whose jobs are replayed as pods, empty to disable the replay.")
+	flag.StringVar(&traceFormat, "trace-format", trace.NativeFormat, "The format of the trace: native, philly, pai or helios.")
+	flag.StringVar(&traceNamespace, "trace-namespace", "default", "The namespace of the pods of the trace.")
+	flag.StringVar(&traceScheduler, "trace-scheduler", "", "The scheduler of the pods of the trace, the default scheduler when empty.")
+	flag.Float64Var(&traceSpeed, "trace-speed", 1, "How many times faster than recorded the trace is replayed.")
+	flag.Uint64Var(&traceGPUMemory, "trace-gpu-memory", 32000, "The memory of a card, taken by fractional GPU requests of the trace.")
+	flag.IntVar(&traceLimit, "trace-limit", 0, "The maximum number of jobs replayed, 0 for all.")
+	flag.StringVar(&reportDir, "report-dir", "", "The directory the experiment report is written to, empty to disable it.")
+	flag.DurationVar(&reportInterval, "report-interval", 30*time.Second, "How often in simulated time the pods and the GPU utilization are sampled for the report.")
+	flag.Float64Var(&clockSpeed, "clock-speed", 1, "How many times faster than the wall clock simulated time runs, e.g. 60 to simulate an hour per minute.")
+	flag.StringVar(&clockState, "clock-state", "kube-system/nodesimulator-clock", "The namespace/name of the ConfigMap keeping simulated time across restarts, empty to start it over at the wall clock time.")
+	flag.Int64Var(&seed, "seed", 0, "The seed of every random decision of the simulation, a random one when 0.")
+	flag.StringVar(&snapshotPath, "snapshot", "", "Write the simulated state of the cluster to this file and exit.")
+	flag.StringVar(&restorePath, "restore", "", "Recreate the simulated state of a snapshot file before the controllers start.")
+	flag.StringVar(&autoscalerAddr, "autoscaler-addr", "", "The address the externalgrpc cloud provider of the cluster-autoscaler binds to, empty to disable it.")
+	flag.StringVar(&autoscalerCert, "autoscaler-cert", "", "The serving certificate of the cloud provider, plaintext when empty.")
+	flag.StringVar(&autoscalerKey, "autoscaler-key", "", "The key of the serving certificate of the cloud provider.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
 	flag.Parse()
@@ -57,10 +101,71 @@ func main() {
 	ctrl.SetLogger(zap.New(func(o *zap.Options) {
 		o.Development = true
 	}))
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	util.SetSeed(seed)
+	setupLog.Info("seeding the simulation", "seed", seed)
 
 	mgrConfig := ctrl.GetConfigOrDie()
 	mgrConfig.QPS = 1000
 	mgrConfig.Burst = 1000
+
+	// Init ClientSet
+	clientSet, err := kubernetes.NewForConfig(mgrConfig)
+	if err != nil {
+		setupLog.Error(err, "unable to init clientSet")
+		os.Exit(1)
+	}
+
+	clock := util.NewVirtualClock(clockSpeed)
+	if clockState != "" {
+		parts := strings.SplitN(clockState, "/", 2)
+		if len(parts) != 2 {
+			setupLog.Error(fmt.Errorf("want namespace/name, got %q", clockState), "invalid clock state")
+			os.Exit(1)
+		}
+		if snapshotPath != "" {
+			// The snapshot is taken at the simulated time of the running simulator, without moving its clock
+			clock, err = util.ReadClock(context.TODO(), clientSet.CoreV1(), parts[0], parts[1])
+		} else {
+			clock, err = util.LoadClock(context.TODO(), clientSet.CoreV1(), parts[0], parts[1], clockSpeed)
+		}
+		if err != nil {
+			setupLog.Error(err, "unable to load clock")
+			os.Exit(1)
+		}
+	}
+	util.SetClock(clock)
+	setupLog.Info("starting the clock", "time", clock.Now(), "speed", clock.Speed())
+
+	if snapshotPath != "" || restorePath != "" {
+		directClient, err := client.New(mgrConfig, client.Options{Scheme: scheme})
+		if err != nil {
+			setupLog.Error(err, "unable to init client")
+			os.Exit(1)
+		}
+		if snapshotPath != "" {
+			s, err := snapshot.Take(context.TODO(), directClient)
+			if err == nil {
+				err = s.Write(snapshotPath)
+			}
+			if err != nil {
+				setupLog.Error(err, "unable to take snapshot")
+				os.Exit(1)
+			}
+			setupLog.Info("wrote snapshot", "file", snapshotPath)
+			os.Exit(0)
+		}
+		s, err := snapshot.Load(restorePath)
+		if err == nil {
+			err = snapshot.Restore(context.TODO(), directClient, s)
+		}
+		if err != nil {
+			setupLog.Error(err, "unable to restore snapshot")
+			os.Exit(1)
+		}
+	}
 	mgr, err := ctrl.NewManager(mgrConfig, ctrl.Options{
 		Scheme:             scheme,
 		MetricsBindAddress: metricsAddr,
@@ -72,13 +177,6 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Init ClientSet
-	clientSet, err := kubernetes.NewForConfig(mgrConfig)
-	if err != nil {
-		setupLog.Error(err, "unable to init clientSet")
-		os.Exit(1)
-	}
-
 	if err = (&node.NodeSimReconciler{
 		Client:    mgr.GetClient(),
 		ClientSet: clientSet,
@@ -93,15 +191,25 @@ func main() {
 		Client:    mgr.GetClient(),
 		ClientSet: clientSet,
 		Scheme:    mgr.GetScheme(),
+		Recorder:  mgr.GetEventRecorderFor("PodSimulator"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "PodSimulator")
 		os.Exit(1)
 	}
+
+	if err = (&workload.WorkloadGeneratorReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("WorkloadGenerator"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "WorkloadGenerator")
+		os.Exit(1)
+	}
 	// +kubebuilder:scaffold:builder
 
 	stopChan := make(chan struct{}, 0)
 	nodeUpdater, err := node.NewNodeUpdater(mgr.GetClient(),
-		workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "NodeUpdater"),
 		stopChan)
 
 	if err == nil {
@@ -111,7 +219,7 @@ func main() {
 	}
 
 	resourceUtilizationUpdater, err := node.NewResourceUtilizationUpdater(mgr.GetClient(),
-		workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "ResourceUtilizationUpdater"),
 		stopChan)
 
 	if err == nil {
@@ -120,6 +228,96 @@ func main() {
 		klog.Errorf("New NodeUpdate Error: %v", err)
 	}
 
+	gpuHealthUpdater, err := pod.NewGPUHealthUpdater(mgr.GetClient(),
+		mgr.GetEventRecorderFor("GPUHealth"),
+		workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "GPUHealthUpdater"),
+		stopChan)
+
+	if err == nil {
+		go gpuHealthUpdater.Run(5, stopChan)
+	} else {
+		klog.Errorf("New GPUHealthUpdater Error: %v", err)
+	}
+
+	gpuLoadUpdater, err := pod.NewGPULoadUpdater(mgr.GetClient(),
+		workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "GPULoadUpdater"),
+		stopChan)
+
+	if err == nil {
+		go gpuLoadUpdater.Run(5, stopChan)
+	} else {
+		klog.Errorf("New GPULoadUpdater Error: %v", err)
+	}
+
+	usageTracker := pod.NewUsageTracker()
+	podUsageUpdater, err := pod.NewPodUsageUpdater(mgr.GetClient(),
+		mgr.GetEventRecorderFor("PodUsage"),
+		usageTracker,
+		workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "PodUsageUpdater"),
+		stopChan)
+
+	if err == nil {
+		go podUsageUpdater.Run(5, stopChan)
+	} else {
+		klog.Errorf("New PodUsageUpdater Error: %v", err)
+	}
+
+	containerUpdater, err := pod.NewContainerUpdater(mgr.GetClient(),
+		mgr.GetEventRecorderFor("kubelet"),
+		usageTracker,
+		workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "ContainerUpdater"),
+		stopChan)
+
+	if err == nil {
+		go containerUpdater.Run(5, stopChan)
+	} else {
+		klog.Errorf("New ContainerUpdater Error: %v", err)
+	}
+
+	if metricsAPIAddr != "" {
+		metricsAPIServer, err := metricsapi.NewServer(mgr.GetClient(), mgr.GetAPIReader(), usageTracker, metricsAPIAddr, metricsAPICert, metricsAPIKey)
+		if err == nil {
+			err = mgr.Add(metricsAPIServer)
+		}
+		if err != nil {
+			klog.Errorf("New Metrics API Server Error: %v", err)
+		}
+	}
+
+	if autoscalerAddr != "" {
+		autoscalerServer, err := autoscaler.NewServer(mgr.GetClient(), mgr.GetAPIReader(), autoscalerAddr, autoscalerCert, autoscalerKey)
+		if err == nil {
+			err = mgr.Add(autoscalerServer)
+		}
+		if err != nil {
+			klog.Errorf("New Cluster Autoscaler Server Error: %v", err)
+		}
+	}
+
+	if tracePath != "" {
+		jobs, err := trace.Load(tracePath, traceFormat, trace.Options{GPUMemory: traceGPUMemory, Limit: traceLimit})
+		if err == nil {
+			var replayer *trace.Replayer
+			replayer, err = trace.NewReplayer(mgr.GetClient(), jobs, traceNamespace, traceScheduler, traceSpeed)
+			if err == nil {
+				err = mgr.Add(replayer)
+			}
+		}
+		if err != nil {
+			klog.Errorf("New Trace Replayer Error: %v", err)
+		}
+	}
+
+	if reportDir != "" {
+		collector, err := report.NewCollector(mgr.GetClient(), reportDir, reportInterval)
+		if err == nil {
+			err = mgr.Add(collector)
+		}
+		if err != nil {
+			klog.Errorf("New Report Collector Error: %v", err)
+		}
+	}
+
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		setupLog.Error(err, "problem running manager")
@@ -34,6 +34,29 @@ type NodeSimulatorSpec struct {
 	PodCidr   string `json:"podCidr"`
 	GpuModel  string `json:"gpuModel,omitempty"`
 	GPU       GPU    `json:"gpu,omitempty"`
+	// Usage is the usage of the pods on the nodes that do not declare their own.
+	Usage PodUsage `json:"usage,omitempty"`
+	// Autoscaling makes the nodes a node group of the cluster-autoscaler.
+	Autoscaling *Autoscaling `json:"autoscaling,omitempty"`
+}
+
+// Autoscaling is the node group of a NodeSimulator, which the cluster-autoscaler
+// resizes through Number and by deleting nodes.
+type Autoscaling struct {
+	MinSize int `json:"minSize"`
+	MaxSize int `json:"maxSize"`
+	// ProvisioningDelay is how long new nodes take to become Ready, e.g. "2m".
+	ProvisioningDelay string `json:"provisioningDelay,omitempty"`
+	// Removed lists the indexes of the nodes the cluster-autoscaler deleted. The nodes
+	// are the first Number indexes that are not removed.
+	Removed []int `json:"removed,omitempty"`
+}
+
+// PodUsage holds the JSON load profiles of the CPU and memory usage of pods, as a
+// fraction of their requests, e.g. {"type": "normal", "level": 0.6, "stddev": 0.1}.
+type PodUsage struct {
+	CPU    string `json:"cpu,omitempty"`
+	Memory string `json:"memory,omitempty"`
 }
 
 type GPU struct {
@@ -42,6 +65,33 @@ type GPU struct {
 	Core       string `json:"core,omitempty"`
 	Bandwidth  string `json:"bandwidth,omitempty"`
 	CoreNumber int    `json:"coreNumber,omitempty"`
+	// MIG partitions every card into the listed MIG profiles, e.g. ["3g.20gb", "2g.10gb", "1g.5gb"].
+	MIG []string `json:"mig,omitempty"`
+	// Topology describes how the cards are interconnected.
+	Topology GPUTopology `json:"topology,omitempty"`
+	// Faults schedules cards to become unhealthy.
+	Faults []GPUFault `json:"faults,omitempty"`
+}
+
+// GPUFault makes a card of one of the simulated nodes unhealthy for a while.
+type GPUFault struct {
+	// Node is the index of the simulated node, Card the ID of its card.
+	Node int `json:"node"`
+	Card int `json:"card"`
+	// After is how long after the creation of the NodeSimulator the card fails, e.g. "10m".
+	After string `json:"after,omitempty"`
+	// Duration is how long the card stays unhealthy, for good when empty.
+	Duration string `json:"duration,omitempty"`
+}
+
+// GPUTopology lists groups of card IDs, e.g. nvlinks: [[0, 1], [2, 3]].
+type GPUTopology struct {
+	// NVLinks are the groups of cards fully connected by NVLink.
+	NVLinks [][]int `json:"nvlinks,omitempty"`
+	// PCIeSwitches are the groups of cards behind the same PCIe switch.
+	PCIeSwitches [][]int `json:"pcieSwitches,omitempty"`
+	// Sockets are the groups of cards attached to the same CPU socket (NUMA node).
+	Sockets [][]int `json:"sockets,omitempty"`
 }
 
 // NodeSimulatorStatus defines the observed state of NodeSimulator
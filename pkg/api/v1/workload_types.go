@@ -0,0 +1,115 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// WorkloadGeneratorSpec defines the pods a WorkloadGenerator creates
+type WorkloadGeneratorSpec struct {
+	// Count is the number of pods to create.
+	Count int `json:"count"`
+	// Arrival is when the pods are created.
+	Arrival Arrival `json:"arrival"`
+	// Seed seeds the arrivals and the demands of the pods, the same seed generates the same workload.
+	// Without a seed, the workload is seeded by the seed of the simulator.
+	Seed int64 `json:"seed,omitempty"`
+	// SchedulerName is the scheduler of the pods, the default scheduler when empty.
+	SchedulerName string `json:"schedulerName,omitempty"`
+	// Labels are added to the pods, e.g. sim.k8s.io/Affinity.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// GPUNumber is the number of cards of a pod and GPUMemory the memory it takes on
+	// each of them, whole cards without it.
+	GPUNumber Distribution `json:"gpuNumber,omitempty"`
+	GPUMemory Distribution `json:"gpuMemory,omitempty"`
+	// CPU and Memory are the requests of a pod, as quantities.
+	CPU    Distribution `json:"cpu,omitempty"`
+	Memory Distribution `json:"memory,omitempty"`
+	// Duration is how long a pod runs, as durations, until deleted without it.
+	Duration Distribution `json:"duration,omitempty"`
+	// FailureProbability is the probability a pod fails at the end of its run, e.g. "0.1".
+	FailureProbability string `json:"failureProbability,omitempty"`
+}
+
+// Arrival describes the arrival process of the pods of a WorkloadGenerator.
+type Arrival struct {
+	// Type is poisson, one pod at a time, or burst, BurstSize pods at once.
+	Type string `json:"type"`
+	// Interval is the mean time between two pods of a poisson arrival, or between two bursts, e.g. "30s".
+	Interval string `json:"interval"`
+	// BurstSize is the number of pods of a burst.
+	BurstSize int `json:"burstSize,omitempty"`
+}
+
+// Distribution describes the values a demand of the pods is drawn from, quantities
+// or durations depending on the demand, e.g. {type: uniform, min: "1", max: "4"}.
+type Distribution struct {
+	// Type is constant, uniform, normal, exponential or choice, none when empty.
+	Type string `json:"type,omitempty"`
+	// Value is the constant value, or the mean of a normal or exponential distribution.
+	Value string `json:"value,omitempty"`
+	// Stddev is the standard deviation of a normal distribution.
+	Stddev string `json:"stddev,omitempty"`
+	// Min and Max bound a uniform distribution, and clamp the others.
+	Min string `json:"min,omitempty"`
+	Max string `json:"max,omitempty"`
+	// Values are picked from uniformly by a choice.
+	Values []string `json:"values,omitempty"`
+}
+
+// WorkloadGeneratorStatus counts the pods of a WorkloadGenerator
+type WorkloadGeneratorStatus struct {
+	// Phase is Generating while pods arrive, Running until they all ended, then Finished.
+	Phase     string `json:"phase,omitempty"`
+	Submitted int    `json:"submitted"`
+	Running   int    `json:"running"`
+	Completed int    `json:"completed"`
+	Failed    int    `json:"failed"`
+	// Created is the number of pods created so far. Pods are only created beyond it,
+	// so a deleted pod is not created again.
+	Created int `json:"created,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Submitted",type=integer,JSONPath=`.status.submitted`
+// +kubebuilder:printcolumn:name="Running",type=integer,JSONPath=`.status.running`
+// +kubebuilder:printcolumn:name="Completed",type=integer,JSONPath=`.status.completed`
+
+// WorkloadGenerator is the Schema for the workloadgenerators API
+type WorkloadGenerator struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   WorkloadGeneratorSpec   `json:"spec,omitempty"`
+	Status WorkloadGeneratorStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// WorkloadGeneratorList contains a list of WorkloadGenerator
+type WorkloadGeneratorList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []WorkloadGenerator `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&WorkloadGenerator{}, &WorkloadGeneratorList{})
+}
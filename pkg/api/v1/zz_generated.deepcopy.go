@@ -23,9 +23,75 @@ import (
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Arrival) DeepCopyInto(out *Arrival) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Arrival.
+func (in *Arrival) DeepCopy() *Arrival {
+	if in == nil {
+		return nil
+	}
+	out := new(Arrival)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Autoscaling) DeepCopyInto(out *Autoscaling) {
+	*out = *in
+	if in.Removed != nil {
+		in, out := &in.Removed, &out.Removed
+		*out = make([]int, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Autoscaling.
+func (in *Autoscaling) DeepCopy() *Autoscaling {
+	if in == nil {
+		return nil
+	}
+	out := new(Autoscaling)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Distribution) DeepCopyInto(out *Distribution) {
+	*out = *in
+	if in.Values != nil {
+		in, out := &in.Values, &out.Values
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Distribution.
+func (in *Distribution) DeepCopy() *Distribution {
+	if in == nil {
+		return nil
+	}
+	out := new(Distribution)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *GPU) DeepCopyInto(out *GPU) {
 	*out = *in
+	if in.MIG != nil {
+		in, out := &in.MIG, &out.MIG
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.Topology.DeepCopyInto(&out.Topology)
+	if in.Faults != nil {
+		in, out := &in.Faults, &out.Faults
+		*out = make([]GPUFault, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GPU.
@@ -38,12 +104,75 @@ func (in *GPU) DeepCopy() *GPU {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GPUFault) DeepCopyInto(out *GPUFault) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GPUFault.
+func (in *GPUFault) DeepCopy() *GPUFault {
+	if in == nil {
+		return nil
+	}
+	out := new(GPUFault)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GPUTopology) DeepCopyInto(out *GPUTopology) {
+	*out = *in
+	if in.NVLinks != nil {
+		in, out := &in.NVLinks, &out.NVLinks
+		*out = make([][]int, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = make([]int, len(*in))
+				copy(*out, *in)
+			}
+		}
+	}
+	if in.PCIeSwitches != nil {
+		in, out := &in.PCIeSwitches, &out.PCIeSwitches
+		*out = make([][]int, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = make([]int, len(*in))
+				copy(*out, *in)
+			}
+		}
+	}
+	if in.Sockets != nil {
+		in, out := &in.Sockets, &out.Sockets
+		*out = make([][]int, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = make([]int, len(*in))
+				copy(*out, *in)
+			}
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GPUTopology.
+func (in *GPUTopology) DeepCopy() *GPUTopology {
+	if in == nil {
+		return nil
+	}
+	out := new(GPUTopology)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodeSimulator) DeepCopyInto(out *NodeSimulator) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	out.Status = in.Status
 }
 
@@ -100,7 +229,13 @@ func (in *NodeSimulatorList) DeepCopyObject() runtime.Object {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NodeSimulatorSpec) DeepCopyInto(out *NodeSimulatorSpec) {
 	*out = *in
-	out.GPU = in.GPU
+	in.GPU.DeepCopyInto(&out.GPU)
+	out.Usage = in.Usage
+	if in.Autoscaling != nil {
+		in, out := &in.Autoscaling, &out.Autoscaling
+		*out = new(Autoscaling)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NodeSimulatorSpec.
@@ -127,3 +262,120 @@ func (in *NodeSimulatorStatus) DeepCopy() *NodeSimulatorStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PodUsage) DeepCopyInto(out *PodUsage) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PodUsage.
+func (in *PodUsage) DeepCopy() *PodUsage {
+	if in == nil {
+		return nil
+	}
+	out := new(PodUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkloadGenerator) DeepCopyInto(out *WorkloadGenerator) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadGenerator.
+func (in *WorkloadGenerator) DeepCopy() *WorkloadGenerator {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkloadGenerator)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *WorkloadGenerator) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkloadGeneratorList) DeepCopyInto(out *WorkloadGeneratorList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]WorkloadGenerator, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadGeneratorList.
+func (in *WorkloadGeneratorList) DeepCopy() *WorkloadGeneratorList {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkloadGeneratorList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *WorkloadGeneratorList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkloadGeneratorSpec) DeepCopyInto(out *WorkloadGeneratorSpec) {
+	*out = *in
+	out.Arrival = in.Arrival
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	in.GPUNumber.DeepCopyInto(&out.GPUNumber)
+	in.GPUMemory.DeepCopyInto(&out.GPUMemory)
+	in.CPU.DeepCopyInto(&out.CPU)
+	in.Memory.DeepCopyInto(&out.Memory)
+	in.Duration.DeepCopyInto(&out.Duration)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadGeneratorSpec.
+func (in *WorkloadGeneratorSpec) DeepCopy() *WorkloadGeneratorSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkloadGeneratorSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WorkloadGeneratorStatus) DeepCopyInto(out *WorkloadGeneratorStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WorkloadGeneratorStatus.
+func (in *WorkloadGeneratorStatus) DeepCopy() *WorkloadGeneratorStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(WorkloadGeneratorStatus)
+	in.DeepCopyInto(out)
+	return out
+}
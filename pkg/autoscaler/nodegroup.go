@@ -0,0 +1,340 @@
+package autoscaler
+
+import (
+	"context"
+	"fmt"
+	simv1 "github.com/NJUPT-ISL/NodeSimulator/pkg/api/v1"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/autoscaler/protos"
+	nodecontroller "github.com/NJUPT-ISL/NodeSimulator/pkg/controllers/node"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/util"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/anypb"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+	"strings"
+)
+
+// NodeGroups returns the NodeSimulators that set Autoscaling.
+func (s *Server) NodeGroups(ctx context.Context, req *protos.NodeGroupsRequest) (*protos.NodeGroupsResponse, error) {
+	nodeSims, err := s.listNodeGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := &protos.NodeGroupsResponse{}
+	for i := range nodeSims {
+		res.NodeGroups = append(res.NodeGroups, nodeGroup(&nodeSims[i]))
+	}
+	return res, nil
+}
+
+// NodeGroupForNode returns the node group of a node from its UniqueLabelKey label,
+// or a node group with an empty id for the nodes not simulated by a node group.
+func (s *Server) NodeGroupForNode(ctx context.Context, req *protos.NodeGroupForNodeRequest) (*protos.NodeGroupForNodeResponse, error) {
+	res := &protos.NodeGroupForNodeResponse{NodeGroup: &protos.NodeGroup{}}
+	if req.GetNode() == nil {
+		return res, nil
+	}
+	id, ok := req.GetNode().GetLabels()[nodecontroller.UniqueLabelKey]
+	if !ok {
+		return res, nil
+	}
+	nodeSims, err := s.listNodeGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range nodeSims {
+		if nodeSims[i].GetNamespace()+"-"+nodeSims[i].GetName() == id {
+			res.NodeGroup = nodeGroup(&nodeSims[i])
+			break
+		}
+	}
+	return res, nil
+}
+
+// PricingNodePrice is not simulated.
+func (s *Server) PricingNodePrice(ctx context.Context, req *protos.PricingNodePriceRequest) (*protos.PricingNodePriceResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "pricing is not simulated")
+}
+
+// PricingPodPrice is not simulated.
+func (s *Server) PricingPodPrice(ctx context.Context, req *protos.PricingPodPriceRequest) (*protos.PricingPodPriceResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "pricing is not simulated")
+}
+
+// GPULabel returns no label: the cards of a simulated node are allocatable as soon
+// as it is Ready, so the autoscaler need not wait for a GPU device plugin.
+func (s *Server) GPULabel(ctx context.Context, req *protos.GPULabelRequest) (*protos.GPULabelResponse, error) {
+	return &protos.GPULabelResponse{}, nil
+}
+
+// GetAvailableGPUTypes returns the GPU models of the node groups.
+func (s *Server) GetAvailableGPUTypes(ctx context.Context, req *protos.GetAvailableGPUTypesRequest) (*protos.GetAvailableGPUTypesResponse, error) {
+	nodeSims, err := s.listNodeGroups(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := &protos.GetAvailableGPUTypesResponse{GpuTypes: make(map[string]*anypb.Any)}
+	for _, nodeSim := range nodeSims {
+		if nodeSim.Spec.GpuModel != "" {
+			res.GpuTypes[nodeSim.Spec.GpuModel] = &anypb.Any{}
+		}
+	}
+	return res, nil
+}
+
+// Cleanup has nothing to clean up.
+func (s *Server) Cleanup(ctx context.Context, req *protos.CleanupRequest) (*protos.CleanupResponse, error) {
+	return &protos.CleanupResponse{}, nil
+}
+
+// Refresh has nothing to refresh, every call reads the NodeSimulators.
+func (s *Server) Refresh(ctx context.Context, req *protos.RefreshRequest) (*protos.RefreshResponse, error) {
+	return &protos.RefreshResponse{}, nil
+}
+
+// NodeGroupTargetSize returns the Number of the NodeSimulator.
+func (s *Server) NodeGroupTargetSize(ctx context.Context, req *protos.NodeGroupTargetSizeRequest) (*protos.NodeGroupTargetSizeResponse, error) {
+	nodeSim, err := s.getNodeGroup(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return &protos.NodeGroupTargetSizeResponse{TargetSize: int32(nodeSim.Spec.Number)}, nil
+}
+
+// NodeGroupIncreaseSize raises the Number of the NodeSimulator, bringing back the
+// nodes it removed first.
+func (s *Server) NodeGroupIncreaseSize(ctx context.Context, req *protos.NodeGroupIncreaseSizeRequest) (*protos.NodeGroupIncreaseSizeResponse, error) {
+	if req.GetDelta() <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "size increase must be positive, got %v", req.GetDelta())
+	}
+	nodeSim, err := s.getNodeGroup(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	delta := int(req.GetDelta())
+	if nodeSim.Spec.Number+delta > nodeSim.Spec.Autoscaling.MaxSize {
+		return nil, status.Errorf(codes.InvalidArgument, "size increase too large, desired: %v max: %v",
+			nodeSim.Spec.Number+delta, nodeSim.Spec.Autoscaling.MaxSize)
+	}
+
+	removed := append([]int(nil), nodeSim.Spec.Autoscaling.Removed...)
+	sort.Ints(removed)
+	if delta < len(removed) {
+		removed = removed[delta:]
+	} else {
+		removed = nil
+	}
+	if err := s.resize(ctx, nodeSim, nodeSim.Spec.Number+delta, removed); err != nil {
+		return nil, err
+	}
+	return &protos.NodeGroupIncreaseSizeResponse{}, nil
+}
+
+// NodeGroupDeleteNodes removes the nodes from the NodeSimulator, lowering its Number.
+// The NodeSimulator controller deletes them with their leases and Scvs.
+func (s *Server) NodeGroupDeleteNodes(ctx context.Context, req *protos.NodeGroupDeleteNodesRequest) (*protos.NodeGroupDeleteNodesResponse, error) {
+	nodeSim, err := s.getNodeGroup(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if nodeSim.Spec.Number-len(req.GetNodes()) < nodeSim.Spec.Autoscaling.MinSize {
+		return nil, status.Errorf(codes.FailedPrecondition, "min size reached, nodes will not be deleted, size: %v min: %v",
+			nodeSim.Spec.Number, nodeSim.Spec.Autoscaling.MinSize)
+	}
+
+	current := make(map[int]bool)
+	for _, index := range nodecontroller.NodeIndexes(nodeSim) {
+		current[index] = true
+	}
+	removed := append([]int(nil), nodeSim.Spec.Autoscaling.Removed...)
+	for _, node := range req.GetNodes() {
+		name := node.GetName()
+		if name == "" {
+			name = strings.TrimPrefix(node.GetProviderID(), nodecontroller.ProviderIDPrefix)
+		}
+		index, ok := nodecontroller.NodeIndex(nodeSim, name)
+		if !ok || !current[index] {
+			return nil, status.Errorf(codes.InvalidArgument, "node %v does not belong to node group %v", name, req.GetId())
+		}
+		delete(current, index)
+		removed = append(removed, index)
+	}
+	sort.Ints(removed)
+	if err := s.resize(ctx, nodeSim, nodeSim.Spec.Number-len(req.GetNodes()), removed); err != nil {
+		return nil, err
+	}
+	return &protos.NodeGroupDeleteNodesResponse{}, nil
+}
+
+// NodeGroupDecreaseTargetSize lowers the Number of the NodeSimulator without
+// deleting a node that exists already.
+func (s *Server) NodeGroupDecreaseTargetSize(ctx context.Context, req *protos.NodeGroupDecreaseTargetSizeRequest) (*protos.NodeGroupDecreaseTargetSizeResponse, error) {
+	if req.GetDelta() >= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "size decrease must be negative, got %v", req.GetDelta())
+	}
+	nodeSim, err := s.getNodeGroup(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := s.listNodes(ctx, nodeSim)
+	if err != nil {
+		return nil, err
+	}
+	existing := 0
+	for _, index := range nodecontroller.NodeIndexes(nodeSim) {
+		if _, ok := nodes[nodecontroller.NodeName(nodeSim, index)]; ok {
+			existing++
+		}
+	}
+	target := nodeSim.Spec.Number + int(req.GetDelta())
+	if target < existing {
+		return nil, status.Errorf(codes.FailedPrecondition, "attempt to delete existing nodes, target size: %v delta: %v existing nodes: %v",
+			nodeSim.Spec.Number, req.GetDelta(), existing)
+	}
+	if err := s.resize(ctx, nodeSim, target, nodeSim.Spec.Autoscaling.Removed); err != nil {
+		return nil, err
+	}
+	return &protos.NodeGroupDecreaseTargetSizeResponse{}, nil
+}
+
+// NodeGroupNodes returns the nodes of the NodeSimulator, the ones not created or
+// still in their provisioning delay as being created.
+func (s *Server) NodeGroupNodes(ctx context.Context, req *protos.NodeGroupNodesRequest) (*protos.NodeGroupNodesResponse, error) {
+	nodeSim, err := s.getNodeGroup(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := s.listNodes(ctx, nodeSim)
+	if err != nil {
+		return nil, err
+	}
+	res := &protos.NodeGroupNodesResponse{}
+	for _, index := range nodecontroller.NodeIndexes(nodeSim) {
+		name := nodecontroller.NodeName(nodeSim, index)
+		state := protos.InstanceStatus_instanceRunning
+		if node, ok := nodes[name]; !ok {
+			state = protos.InstanceStatus_instanceCreating
+		} else if _, provisioning := nodecontroller.Provisioning(node); provisioning {
+			state = protos.InstanceStatus_instanceCreating
+		}
+		res.Instances = append(res.Instances, &protos.Instance{
+			Id:     nodecontroller.ProviderID(name),
+			Status: &protos.InstanceStatus{InstanceState: state},
+		})
+	}
+	return res, nil
+}
+
+// NodeGroupTemplateNodeInfo returns a Ready node of the NodeSimulator.
+func (s *Server) NodeGroupTemplateNodeInfo(ctx context.Context, req *protos.NodeGroupTemplateNodeInfoRequest) (*protos.NodeGroupTemplateNodeInfoResponse, error) {
+	nodeSim, err := s.getNodeGroup(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	node, err := nodecontroller.GenNode(nodeSim.DeepCopy())
+	if err != nil {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	node.SetName(nodeSim.GetNamespace() + "-" + nodeSim.GetName() + "-template")
+	node.Spec.ProviderID = nodecontroller.ProviderID(node.GetName())
+	now := metav1.Time{Time: util.Now()}
+	node.Status.Conditions = []v1.NodeCondition{
+		{
+			LastHeartbeatTime:  now,
+			LastTransitionTime: now,
+			Message:            nodecontroller.KubeletMessage,
+			Status:             v1.ConditionTrue,
+			Reason:             nodecontroller.KubeletReason,
+			Type:               v1.NodeReady,
+		},
+	}
+	return &protos.NodeGroupTemplateNodeInfoResponse{NodeInfo: node}, nil
+}
+
+// NodeGroupGetOptions leaves the autoscaler to its default options.
+func (s *Server) NodeGroupGetOptions(ctx context.Context, req *protos.NodeGroupAutoscalingOptionsRequest) (*protos.NodeGroupAutoscalingOptionsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "node group options are not simulated")
+}
+
+func nodeGroup(nodeSim *simv1.NodeSimulator) *protos.NodeGroup {
+	return &protos.NodeGroup{
+		Id:      nodeSim.GetNamespace() + "/" + nodeSim.GetName(),
+		MinSize: int32(nodeSim.Spec.Autoscaling.MinSize),
+		MaxSize: int32(nodeSim.Spec.Autoscaling.MaxSize),
+		Debug: fmt.Sprintf("NodeSim: %v/%v (min: %v, max: %v, target: %v)", nodeSim.GetNamespace(), nodeSim.GetName(),
+			nodeSim.Spec.Autoscaling.MinSize, nodeSim.Spec.Autoscaling.MaxSize, nodeSim.Spec.Number),
+	}
+}
+
+func (s *Server) listNodeGroups(ctx context.Context) ([]simv1.NodeSimulator, error) {
+	nodeSimList := &simv1.NodeSimulatorList{}
+	if err := s.Reader.List(ctx, nodeSimList); err != nil {
+		klog.Errorf("Cluster Autoscaler List NodeSims Error: %v", err)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	nodeSims := make([]simv1.NodeSimulator, 0, len(nodeSimList.Items))
+	for _, nodeSim := range nodeSimList.Items {
+		if nodeSim.Spec.Autoscaling != nil {
+			nodeSims = append(nodeSims, nodeSim)
+		}
+	}
+	return nodeSims, nil
+}
+
+// getNodeGroup returns the NodeSimulator of the node group id, "<namespace>/<name>".
+func (s *Server) getNodeGroup(ctx context.Context, id string) (*simv1.NodeSimulator, error) {
+	parts := strings.Split(id, "/")
+	if len(parts) != 2 {
+		return nil, status.Errorf(codes.NotFound, "node group %v not found", id)
+	}
+	nodeSim := &simv1.NodeSimulator{}
+	err := s.Reader.Get(ctx, types.NamespacedName{Namespace: parts[0], Name: parts[1]}, nodeSim)
+	if apierrors.IsNotFound(err) {
+		return nil, status.Errorf(codes.NotFound, "node group %v not found", id)
+	}
+	if err != nil {
+		klog.Errorf("Cluster Autoscaler Get NodeSim: %v Error: %v", id, err)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if nodeSim.Spec.Autoscaling == nil {
+		return nil, status.Errorf(codes.NotFound, "node group %v not found", id)
+	}
+	return nodeSim, nil
+}
+
+// listNodes returns the nodes of the NodeSimulator by name.
+func (s *Server) listNodes(ctx context.Context, nodeSim *simv1.NodeSimulator) (map[string]*v1.Node, error) {
+	nodeList := &v1.NodeList{}
+	if err := s.Client.List(ctx, nodeList, &client.MatchingLabels{
+		nodecontroller.UniqueLabelKey: nodeSim.GetNamespace() + "-" + nodeSim.GetName(),
+	}); err != nil {
+		klog.Errorf("Cluster Autoscaler List Nodes of NodeSim: %v/%v Error: %v", nodeSim.GetNamespace(), nodeSim.GetName(), err)
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	nodes := make(map[string]*v1.Node, len(nodeList.Items))
+	for i := range nodeList.Items {
+		nodes[nodeList.Items[i].GetName()] = &nodeList.Items[i]
+	}
+	return nodes, nil
+}
+
+// resize sets the Number and the removed nodes of the NodeSimulator. The patch
+// fails if the NodeSimulator changed since it was read.
+func (s *Server) resize(ctx context.Context, nodeSim *simv1.NodeSimulator, number int, removed []int) error {
+	newNodeSim := nodeSim.DeepCopy()
+	newNodeSim.Spec.Number = number
+	newNodeSim.Spec.Autoscaling.Removed = removed
+	patch := client.MergeFromWithOptions(nodeSim, client.MergeFromWithOptimisticLock{})
+	if err := s.Client.Patch(ctx, newNodeSim, patch); err != nil {
+		klog.Errorf("Cluster Autoscaler Resize NodeSim: %v/%v Error: %v", nodeSim.GetNamespace(), nodeSim.GetName(), err)
+		return status.Error(codes.Aborted, err.Error())
+	}
+	klog.Infof("Cluster Autoscaler Resized NodeSim: %v/%v to %v Nodes", nodeSim.GetNamespace(), nodeSim.GetName(), number)
+	return nil
+}
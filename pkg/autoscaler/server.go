@@ -0,0 +1,67 @@
+package autoscaler
+
+import (
+	"context"
+	"errors"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/autoscaler/protos"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"k8s.io/klog"
+	"net"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Server serves the externalgrpc cloud provider API of the cluster-autoscaler, with
+// every NodeSimulator that sets Autoscaling as a node group. It does not
+// authenticate its clients, and serves without TLS when no certificate is given.
+type Server struct {
+	protos.UnimplementedCloudProviderServer
+
+	Client client.Client
+	// Reader reads the NodeSimulators from the API server rather than the cache, so
+	// the autoscaler sees the target size it has just set.
+	Reader client.Reader
+	Addr   string
+	// CertFile and KeyFile are the serving certificate, plaintext when empty.
+	CertFile string
+	KeyFile  string
+}
+
+func NewServer(serverClient client.Client, reader client.Reader, addr, certFile, keyFile string) (*Server, error) {
+	if serverClient == nil || reader == nil || addr == "" {
+		return nil, errors.New("New Cluster Autoscaler Server Error, parameters contains nil ")
+	}
+	return &Server{
+		Client:   serverClient,
+		Reader:   reader,
+		Addr:     addr,
+		CertFile: certFile,
+		KeyFile:  keyFile,
+	}, nil
+}
+
+// Start serves the API until ctx is done. It implements manager.Runnable.
+func (s *Server) Start(ctx context.Context) error {
+	var opts []grpc.ServerOption
+	if s.CertFile != "" && s.KeyFile != "" {
+		creds, err := credentials.NewServerTLSFromFile(s.CertFile, s.KeyFile)
+		if err != nil {
+			return err
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+
+	listener, err := net.Listen("tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	server := grpc.NewServer(opts...)
+	protos.RegisterCloudProviderServer(server, s)
+	go func() {
+		<-ctx.Done()
+		server.GracefulStop()
+	}()
+
+	klog.Infof("Starting cluster autoscaler cloud provider on %v", s.Addr)
+	return server.Serve(listener)
+}
@@ -0,0 +1,91 @@
+package node
+
+import (
+	simv1 "github.com/NJUPT-ISL/NodeSimulator/pkg/api/v1"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/util"
+	scv1 "github.com/NJUPT-ISL/SCV/api/v1"
+	v1 "k8s.io/api/core/v1"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NodeIndexes returns the indexes of the nodes of the NodeSimulator: the first
+// Number indexes the cluster-autoscaler did not remove.
+func NodeIndexes(nodeSim *simv1.NodeSimulator) []int {
+	removed := make(map[int]bool)
+	if nodeSim.Spec.Autoscaling != nil {
+		for _, index := range nodeSim.Spec.Autoscaling.Removed {
+			removed[index] = true
+		}
+	}
+	indexes := make([]int, 0, nodeSim.Spec.Number)
+	for i := 0; len(indexes) < nodeSim.Spec.Number; i++ {
+		if !removed[i] {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// NodeName returns the name of the node of the NodeSimulator with the given index.
+func NodeName(nodeSim *simv1.NodeSimulator, index int) string {
+	return nodeSim.GetNamespace() + "-" + nodeSim.GetName() + "-" + strconv.Itoa(index)
+}
+
+// NodeIndex returns the index of a node of the NodeSimulator from its name.
+func NodeIndex(nodeSim *simv1.NodeSimulator, nodeName string) (int, bool) {
+	prefix := nodeSim.GetNamespace() + "-" + nodeSim.GetName() + "-"
+	if !strings.HasPrefix(nodeName, prefix) {
+		return 0, false
+	}
+	index, err := strconv.Atoi(strings.TrimPrefix(nodeName, prefix))
+	if err != nil || index < 0 {
+		return 0, false
+	}
+	return index, true
+}
+
+// ProviderID returns the provider ID of a simulated node.
+func ProviderID(nodeName string) string {
+	return ProviderIDPrefix + nodeName
+}
+
+// ProvisioningDelay returns how long new nodes of the NodeSimulator take to become Ready.
+func ProvisioningDelay(nodeSim *simv1.NodeSimulator) time.Duration {
+	if nodeSim.Spec.Autoscaling == nil || nodeSim.Spec.Autoscaling.ProvisioningDelay == "" {
+		return 0
+	}
+	delay, err := time.ParseDuration(nodeSim.Spec.Autoscaling.ProvisioningDelay)
+	if err != nil {
+		return 0
+	}
+	return delay
+}
+
+// Provisioning reports whether the node is not Ready yet, and when it will be in simulated time.
+func Provisioning(node *v1.Node) (time.Time, bool) {
+	value, ok := node.GetAnnotations()[ReadyAtAnnotation]
+	if !ok {
+		return time.Time{}, false
+	}
+	readyAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return readyAt, util.Now().Before(readyAt)
+}
+
+// SameCards reports whether the cards of a Scv are the cards of the spec, ignoring their state.
+func SameCards(cur, cards scv1.CardList) bool {
+	if len(cur) != len(cards) {
+		return false
+	}
+	for i := range cards {
+		if cur[i].ID != cards[i].ID || cur[i].Model != cards[i].Model || cur[i].TotalMemory != cards[i].TotalMemory ||
+			cur[i].Core != cards[i].Core || cur[i].Bandwidth != cards[i].Bandwidth || cur[i].CoreNumber != cards[i].CoreNumber {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+package node
+
+import (
+	simv1 "github.com/NJUPT-ISL/NodeSimulator/pkg/api/v1"
+	"reflect"
+	"testing"
+)
+
+func TestNodeIndexes(t *testing.T) {
+	tests := []struct {
+		name        string
+		number      int
+		autoscaling *simv1.Autoscaling
+		want        []int
+	}{
+		{
+			name: "no nodes",
+			want: []int{},
+		},
+		{
+			name:   "without autoscaling",
+			number: 3,
+			want:   []int{0, 1, 2},
+		},
+		{
+			name:        "nothing removed",
+			number:      3,
+			autoscaling: &simv1.Autoscaling{},
+			want:        []int{0, 1, 2},
+		},
+		{
+			name:        "removed node skipped",
+			number:      3,
+			autoscaling: &simv1.Autoscaling{Removed: []int{1}},
+			want:        []int{0, 2, 3},
+		},
+		{
+			name:        "several removed",
+			number:      2,
+			autoscaling: &simv1.Autoscaling{Removed: []int{0, 2}},
+			want:        []int{1, 3},
+		},
+		{
+			name:        "removed beyond the nodes",
+			number:      2,
+			autoscaling: &simv1.Autoscaling{Removed: []int{5}},
+			want:        []int{0, 1},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			nodeSim := &simv1.NodeSimulator{Spec: simv1.NodeSimulatorSpec{Number: test.number, Autoscaling: test.autoscaling}}
+			if got := NodeIndexes(nodeSim); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("NodeIndexes() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
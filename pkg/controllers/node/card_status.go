@@ -0,0 +1,190 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/util"
+	scv1 "github.com/NJUPT-ISL/SCV/api/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CardStatus is the simulated state of a GPU card that scv1.Card has no field for.
+// The status of all cards of a node is kept as JSON in the CardStatusAnnotation of its Scv.
+type CardStatus struct {
+	ID uint `json:"id"`
+	// TotalCore and FreeCore are the compute of the card in percent, see CardCompute.
+	TotalCore uint64 `json:"totalCore"`
+	FreeCore  uint64 `json:"freeCore"`
+	// MIGDevices are the MIG instances of a partitioned card.
+	MIGDevices []MIGDevice `json:"migDevices,omitempty"`
+	// NVLinks are the cards connected to this one by NVLink.
+	NVLinks []uint `json:"nvlinks,omitempty"`
+	// PCIeSwitch and Socket are the group indexes of the card, -1 when not declared.
+	PCIeSwitch int `json:"pcieSwitch"`
+	Socket     int `json:"socket"`
+	// Utilization is the simulated compute utilization of the card, between 0 and 1.
+	Utilization float64 `json:"utilization"`
+	// Temperature is the simulated temperature of the card in Celsius.
+	Temperature float64 `json:"temperature"`
+	// MaxPower and MaxClock are the power and clock of the card under full load.
+	MaxPower uint `json:"maxPower"`
+	MaxClock uint `json:"maxClock"`
+}
+
+type CardStatusList []CardStatus
+
+// NewCardStatus returns the status of an idle card.
+func NewCardStatus(card scv1.Card) CardStatus {
+	return CardStatus{
+		ID:          card.ID,
+		TotalCore:   CardCompute,
+		FreeCore:    CardCompute,
+		PCIeSwitch:  -1,
+		Socket:      -1,
+		Temperature: idleTemperature,
+		MaxPower:    card.Power,
+		MaxClock:    card.Clock,
+	}
+}
+
+// GetCardStatus returns the card status of the Scv, ordered like Scv.Status.CardList.
+// Cards missing from the annotation get the status of an idle card.
+func GetCardStatus(scv *scv1.Scv) CardStatusList {
+	stored := make(CardStatusList, 0)
+	if value, ok := scv.GetAnnotations()[CardStatusAnnotation]; ok {
+		if err := json.Unmarshal([]byte(value), &stored); err != nil {
+			klog.Errorf("Scv: %v Parse Card Status Error: %v", scv.GetName(), err)
+		}
+	}
+
+	statusList := make(CardStatusList, len(scv.Status.CardList))
+	for i, card := range scv.Status.CardList {
+		statusList[i] = NewCardStatus(card)
+		for _, status := range stored {
+			if status.ID == card.ID {
+				statusList[i] = status
+				break
+			}
+		}
+	}
+	return statusList
+}
+
+// SameCardStatus reports whether two card status lists describe the same cards, MIG
+// layout and topology, ignoring what is allocated and the telemetry.
+func SameCardStatus(cur, statusList CardStatusList) bool {
+	if len(cur) != len(statusList) {
+		return false
+	}
+	for i := range statusList {
+		a, b := cur[i], statusList[i]
+		if a.ID != b.ID || a.TotalCore != b.TotalCore || a.PCIeSwitch != b.PCIeSwitch || a.Socket != b.Socket ||
+			a.MaxPower != b.MaxPower || a.MaxClock != b.MaxClock || len(a.MIGDevices) != len(b.MIGDevices) || len(a.NVLinks) != len(b.NVLinks) {
+			return false
+		}
+		for j := range b.MIGDevices {
+			x, y := a.MIGDevices[j], b.MIGDevices[j]
+			if x.Profile != y.Profile || x.Start != y.Start || x.Size != y.Size || x.Memory != y.Memory || x.Core != y.Core {
+				return false
+			}
+		}
+		for j := range b.NVLinks {
+			if a.NVLinks[j] != b.NVLinks[j] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// SetCardStatus stores the card status in the annotations of the Scv.
+func SetCardStatus(scv *scv1.Scv, statusList CardStatusList) {
+	data, err := json.Marshal(statusList)
+	if err != nil {
+		klog.Errorf("Scv: %v Marshal Card Status Error: %v", scv.GetName(), err)
+		return
+	}
+	annotations := scv.GetAnnotations()
+	if annotations == nil {
+		annotations = make(map[string]string)
+	}
+	annotations[CardStatusAnnotation] = string(data)
+	scv.SetAnnotations(annotations)
+}
+
+// statusCard is a card of the Scv status together with its compute share.
+type statusCard struct {
+	scv1.Card
+	TotalCore uint64 `json:"totalCore"`
+	FreeCore  uint64 `json:"freeCore"`
+}
+
+// scvStatus is the Scv status as written back by the simulator: scv1.Card has no
+// field for the compute share, so every card carries its TotalCore and FreeCore.
+type scvStatus struct {
+	scv1.ScvStatus
+	CardList []statusCard `json:"cardList,omitempty"`
+}
+
+// statusWithCompute returns the status of the Scv with the compute share of each card.
+func statusWithCompute(scv *scv1.Scv) interface{} {
+	statusList := GetCardStatus(scv)
+	status := scvStatus{ScvStatus: scv.Status, CardList: make([]statusCard, len(scv.Status.CardList))}
+	for i, card := range scv.Status.CardList {
+		status.CardList[i] = statusCard{Card: card, TotalCore: statusList[i].TotalCore, FreeCore: statusList[i].FreeCore}
+	}
+	return status
+}
+
+// ScvPatchOps returns the ops writing back the status and the card status of the
+// Scv. They fail if the Scv changed since it was read, so that no writer overwrites
+// the cards another one just allocated or released.
+func ScvPatchOps(scv *scv1.Scv) []util.Ops {
+	return []util.Ops{
+		{
+			Op:    "test",
+			Path:  "/metadata/resourceVersion",
+			Value: scv.GetResourceVersion(),
+		},
+		{
+			Op:    "replace",
+			Path:  "/status",
+			Value: statusWithCompute(scv),
+		},
+		{
+			Op:    "add",
+			Path:  "/metadata/annotations",
+			Value: scv.GetAnnotations(),
+		},
+	}
+}
+
+// IsScvConflict reports whether a Scv patch failed because the Scv changed since it was read.
+func IsScvConflict(err error) bool {
+	return apierrors.IsConflict(err) || apierrors.IsInvalid(err)
+}
+
+// UpdateScv reads the Scv of the node, lets update change it and writes it back.
+// If the Scv changed meanwhile, it starts over from a fresh read, so update must
+// derive the Scv from what it reads. update returns false to leave the Scv as it is.
+// Every failed patch is counted, including the conflicts that are retried.
+func UpdateScv(ctx context.Context, c client.Client, nodeName string, update func(scv *scv1.Scv) bool) error {
+	return retry.OnError(retry.DefaultBackoff, IsScvConflict, func() error {
+		scv := &scv1.Scv{}
+		if err := c.Get(ctx, types.NamespacedName{Name: nodeName}, scv); err != nil {
+			return err
+		}
+		if !update(scv) {
+			return nil
+		}
+		err := c.Patch(ctx, scv, &util.Patch{PatchOps: ScvPatchOps(scv)})
+		if err != nil {
+			RecordScvPatchError(err)
+		}
+		return err
+	})
+}
@@ -0,0 +1,45 @@
+package node
+
+import (
+	"encoding/json"
+	scv1 "github.com/NJUPT-ISL/SCV/api/v1"
+	"testing"
+)
+
+func TestScvPatchOpsCompute(t *testing.T) {
+	scv := &scv1.Scv{Status: scv1.ScvStatus{
+		CardList:   scv1.CardList{{ID: 0, TotalMemory: 16000, Core: 1410}, {ID: 1, TotalMemory: 16000, Core: 1410}},
+		CardNumber: 2,
+	}}
+	statusList := CardStatusList{NewCardStatus(scv.Status.CardList[0]), NewCardStatus(scv.Status.CardList[1])}
+	statusList[1].FreeCore = 30
+	SetCardStatus(scv, statusList)
+
+	var status scvStatus
+	for _, op := range ScvPatchOps(scv) {
+		if op.Path != "/status" {
+			continue
+		}
+		data, err := json.Marshal(op.Value)
+		if err != nil {
+			t.Fatalf("json.Marshal() error: %v", err)
+		}
+		if err := json.Unmarshal(data, &status); err != nil {
+			t.Fatalf("json.Unmarshal() error: %v", err)
+		}
+	}
+
+	if status.CardNumber != 2 || len(status.CardList) != 2 {
+		t.Fatalf("status = %+v, want 2 cards", status)
+	}
+	want := []statusCard{
+		{Card: scv.Status.CardList[0], TotalCore: CardCompute, FreeCore: CardCompute},
+		{Card: scv.Status.CardList[1], TotalCore: CardCompute, FreeCore: 30},
+	}
+	for i, card := range status.CardList {
+		if card.ID != want[i].ID || card.Core != want[i].Core || card.TotalMemory != want[i].TotalMemory ||
+			card.TotalCore != want[i].TotalCore || card.FreeCore != want[i].FreeCore {
+			t.Errorf("card %d = %+v, want %+v", i, card, want[i])
+		}
+	}
+}
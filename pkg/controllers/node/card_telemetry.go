@@ -0,0 +1,45 @@
+package node
+
+import scv1 "github.com/NJUPT-ISL/SCV/api/v1"
+
+const (
+	// Temperature of an idle card and its rise under full load, in Celsius
+	idleTemperature     = 30.0
+	loadTemperatureRise = 52.0
+	// Share of the distance to the target temperature covered in one sample
+	temperatureSmoothing = 0.3
+	// Cards hotter than this throttle their clock
+	throttleTemperature = 80.0
+	throttleClockRatio  = 0.85
+
+	// Power and clock of an idle card, relative to full load
+	idlePowerRatio = 0.25
+	idleClockRatio = 0.5
+)
+
+// UpdateCardTelemetry moves the utilization, temperature, power and clock of the
+// card to the given compute utilization. The temperature follows the load with a
+// lag, and a hot card throttles its clock.
+func UpdateCardTelemetry(card *scv1.Card, status *CardStatus, utilization float64) {
+	if status.MaxPower == 0 {
+		status.MaxPower = DefaultCardPower
+	}
+	if status.MaxClock == 0 {
+		status.MaxClock = DefaultCardClock
+	}
+	if status.Temperature == 0 {
+		status.Temperature = idleTemperature
+	}
+
+	status.Utilization = utilization
+	target := idleTemperature + loadTemperatureRise*utilization
+	status.Temperature += (target - status.Temperature) * temperatureSmoothing
+
+	card.Power = uint(float64(status.MaxPower) * (idlePowerRatio + (1-idlePowerRatio)*utilization))
+
+	clock := float64(status.MaxClock) * (idleClockRatio + (1-idleClockRatio)*utilization)
+	if status.Temperature > throttleTemperature {
+		clock *= throttleClockRatio
+	}
+	card.Clock = uint(clock)
+}
@@ -36,4 +36,67 @@ const (
 	Affinity     = "sim.k8s.io/Affinity"
 	AntiAffinity = "sim.k8s.io/AntiAffinity"
 	Exclusion    = "sim.k8s.io/Exclusion"
+
+	// Scv annotation holding the simulated card status
+	CardStatusAnnotation = "sim.k8s.io/card-status"
+
+	// Provider ID of the simulated nodes, followed by the node name
+	ProviderIDPrefix = "nodesim://"
+
+	// Node annotation holding when a new node of a node group becomes Ready, in simulated time
+	ReadyAtAnnotation = "sim.k8s.io/ready-at"
+
+	// Ready condition of a node still provisioning
+	ProvisioningMessage = "node is provisioning"
+	ProvisioningReason  = "KubeletNotReady"
+
+	// Node annotation listing the IDs of the unhealthy cards, e.g. "1,3"
+	UnhealthyGPUAnnotation = "sim.k8s.io/unhealthy-gpus"
+
+	// Power and clock of a card under full load
+	DefaultCardPower = 250
+	DefaultCardClock = 6000
+
+	// Card health
+	CardHealthy   = "Healthy"
+	CardUnhealthy = "Unhealthy"
+
+	// Node annotation holding the CPU and memory usage of its pods, e.g. {"cpu":"1500m","memory":"3Gi"}
+	NodeUsageAnnotation = "sim.k8s.io/usage"
+
+	// Memory pressure condition
+	MemoryPressureMessage = "kubelet has insufficient memory available"
+	MemoryPressureReason  = "KubeletHasInsufficientMemory"
+
+	// Pod labels holding the cards and MIG instances a pod was given
+	ScheduleGPUID = "scheduleGPUID"
+	ScheduleMIGID = "scheduleMIGID"
+
+	// GPUIDSeparator joins the card IDs of a pod spanning several cards,
+	// e.g. scheduleGPUID: "0-1-2-3".
+	GPUIDSeparator = "-"
+	// MIGIDSeparator joins the card ID and start slice of a MIG instance,
+	// e.g. scheduleMIGID: "0.4-1.0".
+	MIGIDSeparator = "."
+
+	// GPU request labels
+	ScvMemory = "scv/memory"
+	ScvNumber = "scv/number"
+	ScvCore   = "scv/core"
+
+	// SidecarsAnnotation lists the init containers to run as sidecars, e.g. "istio-proxy,log".
+	// Init containers with restartPolicy Always are sidecars without it on Kubernetes 1.28+.
+	SidecarsAnnotation = "sim.k8s.io/sidecars"
+
+	// GPU extended resources
+	ResourceGPUNumber v1.ResourceName = "gpu/number"
+	ResourceGPUMemory v1.ResourceName = "gpu/memory"
+	ResourceGPUCore   v1.ResourceName = "gpu/core"
+	ResourceNvidiaGPU v1.ResourceName = "nvidia.com/gpu"
+	// ResourceGPUCompute is the compute share of all cards of a node, in percent of a card.
+	// Unlike it, gpu/core is the core value of one card, as set in the NodeSimulator.
+	ResourceGPUCompute v1.ResourceName = "gpu/compute"
+
+	// CardCompute is the compute of a whole card, in percent
+	CardCompute = 100
 )
@@ -0,0 +1,156 @@
+package node
+
+import (
+	scv1 "github.com/NJUPT-ISL/SCV/api/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+	"strconv"
+	"strings"
+)
+
+// GetScheduledGPUIDs returns the card IDs recorded in the scheduleGPUID label.
+func GetScheduledGPUIDs(labels map[string]string) ([]int, bool) {
+	value, ok := labels[ScheduleGPUID]
+	if !ok || value == "" {
+		return nil, false
+	}
+	ids := make([]int, 0)
+	for _, s := range strings.Split(value, GPUIDSeparator) {
+		id, err := strconv.Atoi(s)
+		if err != nil || id < 0 {
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+	return ids, true
+}
+
+// MIGRef names a MIG instance by its card and first memory slice.
+type MIGRef struct {
+	Card  int
+	Start int
+}
+
+// GetScheduledMIGIDs returns the MIG instances recorded in the scheduleMIGID label.
+func GetScheduledMIGIDs(labels map[string]string) ([]MIGRef, bool) {
+	value, ok := labels[ScheduleMIGID]
+	if !ok || value == "" {
+		return nil, false
+	}
+	refs := make([]MIGRef, 0)
+	for _, s := range strings.Split(value, GPUIDSeparator) {
+		parts := strings.Split(s, MIGIDSeparator)
+		if len(parts) != 2 {
+			return nil, false
+		}
+		card, err := strconv.Atoi(parts[0])
+		if err != nil || card < 0 {
+			return nil, false
+		}
+		start, err := strconv.Atoi(parts[1])
+		if err != nil || start < 0 {
+			return nil, false
+		}
+		refs = append(refs, MIGRef{Card: card, Start: start})
+	}
+	return refs, true
+}
+
+// allocateCards deducts the demand from each of the given cards.
+func allocateCards(cardList scv1.CardList, statusList CardStatusList, ids []int, demand GPUDemand) {
+	for _, id := range ids {
+		if id < 0 || id >= len(cardList) || id >= len(statusList) {
+			continue
+		}
+		mem := demand.MemoryOn(cardList[id])
+		if mem > cardList[id].FreeMemory {
+			mem = cardList[id].FreeMemory
+		}
+		cardList[id].FreeMemory -= mem
+
+		core := demand.CoreOn(statusList[id])
+		if core > statusList[id].FreeCore {
+			core = statusList[id].FreeCore
+		}
+		statusList[id].FreeCore -= core
+	}
+}
+
+// allocateMIG binds the MIG instances to the pod and deducts their memory and
+// compute from the cards.
+func allocateMIG(cardList scv1.CardList, statusList CardStatusList, refs []MIGRef, podKey string) {
+	for _, ref := range refs {
+		if ref.Card >= len(cardList) || ref.Card >= len(statusList) {
+			continue
+		}
+		status := &statusList[ref.Card]
+		for i, device := range status.MIGDevices {
+			if device.Start != ref.Start || device.Pod != "" {
+				continue
+			}
+			status.MIGDevices[i].Pod = podKey
+
+			mem := device.Memory
+			if mem > cardList[ref.Card].FreeMemory {
+				mem = cardList[ref.Card].FreeMemory
+			}
+			cardList[ref.Card].FreeMemory -= mem
+
+			core := device.Core
+			if core > status.FreeCore {
+				core = status.FreeCore
+			}
+			status.FreeCore -= core
+			break
+		}
+	}
+}
+
+// resetCards marks every card as idle.
+func resetCards(cardList scv1.CardList, statusList CardStatusList) {
+	for i, card := range cardList {
+		cardList[i].FreeMemory = card.TotalMemory
+	}
+	for i, status := range statusList {
+		statusList[i].FreeCore = status.TotalCore
+		for j := range status.MIGDevices {
+			statusList[i].MIGDevices[j].Pod = ""
+		}
+	}
+}
+
+// HoldsGPU reports whether the pod still occupies the cards recorded in its labels.
+// A deleted pod holds them until its containers stopped.
+func HoldsGPU(pod *v1.Pod) bool {
+	return pod.Status.Phase != v1.PodFailed && pod.Status.Phase != v1.PodSucceeded
+}
+
+// RebuildCards recomputes the free resources of the cards from the pods holding them.
+func RebuildCards(cardList scv1.CardList, statusList CardStatusList, pods []v1.Pod) {
+	resetCards(cardList, statusList)
+	for _, pod := range pods {
+		if _, ok := GetScheduledGPUIDs(pod.GetLabels()); !ok || !HoldsGPU(&pod) {
+			continue
+		}
+		demand, err := GetGPUDemand(&pod)
+		if err != nil {
+			klog.Errorf("Pod: %v/%v GPU Request Error: %v", pod.GetNamespace(), pod.GetName(), err)
+			continue
+		}
+		AllocatePod(cardList, statusList, &pod, demand)
+	}
+}
+
+// AllocatePod deducts the GPUs recorded in the labels of the pod from the cards.
+func AllocatePod(cardList scv1.CardList, statusList CardStatusList, pod *v1.Pod, demand GPUDemand) {
+	labels := pod.GetLabels()
+	if demand.MIGProfile != "" {
+		if refs, ok := GetScheduledMIGIDs(labels); ok {
+			allocateMIG(cardList, statusList, refs, pod.GetNamespace()+"/"+pod.GetName())
+		}
+		return
+	}
+	if ids, ok := GetScheduledGPUIDs(labels); ok {
+		allocateCards(cardList, statusList, ids, demand)
+	}
+}
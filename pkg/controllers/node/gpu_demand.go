@@ -0,0 +1,287 @@
+package node
+
+import (
+	"fmt"
+	scv1 "github.com/NJUPT-ISL/SCV/api/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"strconv"
+	"strings"
+)
+
+// GPUDemand describes the GPU resources a pod asks for.
+type GPUDemand struct {
+	// Number is the number of cards the pod spans.
+	Number int
+	// Memory is the GPU memory taken on each of the cards.
+	Memory uint64
+	// Core is the GPU compute taken on each of the cards, in percent of a card.
+	Core uint64
+	// Whole means the pod takes entire cards, Memory and Core are ignored.
+	Whole bool
+	// MIGProfile is set when the pod takes Number instances of a MIG profile instead of cards.
+	MIGProfile string
+}
+
+// MemoryOn returns the memory the demand takes on the given card.
+func (d GPUDemand) MemoryOn(card scv1.Card) uint64 {
+	if d.Whole {
+		return card.TotalMemory
+	}
+	return d.Memory
+}
+
+// CoreOn returns the compute the demand takes on the given card, in percent.
+func (d GPUDemand) CoreOn(status CardStatus) uint64 {
+	if d.Whole {
+		return status.TotalCore
+	}
+	return d.Core
+}
+
+// Fits reports whether the card can hold one share of the demand.
+// Compute is not enforced on cards without a core capacity. Unhealthy cards fit
+// nothing, cards partitioned with MIG are only handed out as MIG instances.
+func (d GPUDemand) Fits(card scv1.Card, status CardStatus) bool {
+	if card.Health == CardUnhealthy || len(status.MIGDevices) > 0 {
+		return false
+	}
+	if d.Whole {
+		return card.FreeMemory == card.TotalMemory && status.FreeCore == status.TotalCore
+	}
+	return card.FreeMemory >= d.Memory && (status.TotalCore == 0 || status.FreeCore >= d.Core)
+}
+
+// GetGPUDemand returns the GPU demand of a pod.
+//
+// The gpu/number, nvidia.com/gpu, gpu/memory and gpu/compute extended resources are
+// read from the container requests, or the limits when no request is set. As in
+// Kubernetes, the effective value of a resource is the larger of the sum over the
+// containers and the maximum over the init containers. Memory and compute are pod
+// totals, the way the scheduler accounts them against the node allocatable, and
+// are split evenly across the cards. Compute is in percent of a card. A pod asking
+// for cards without gpu/memory takes whole cards.
+//
+// A pod may instead ask for instances of one MIG profile through its
+// nvidia.com/mig-<profile> resource.
+//
+// Pods without any GPU resource fall back to the legacy scv/number, scv/memory and
+// scv/core labels, whose memory and compute are per card.
+func GetGPUDemand(pod *v1.Pod) (GPUDemand, error) {
+	quantities := make(map[v1.ResourceName]*resource.Quantity)
+	for _, name := range []v1.ResourceName{ResourceGPUNumber, ResourceNvidiaGPU, ResourceGPUMemory, ResourceGPUCompute} {
+		q, err := EffectiveRequest(pod, name)
+		if err != nil {
+			return GPUDemand{}, err
+		}
+		if q != nil {
+			quantities[name] = q
+		}
+	}
+
+	profile, count, err := migRequest(pod)
+	if err != nil {
+		return GPUDemand{}, err
+	}
+	if profile != "" {
+		if len(quantities) > 0 {
+			return GPUDemand{}, fmt.Errorf("MIG profile %v cannot be requested together with whole or shared GPUs", profile)
+		}
+		return GPUDemand{Number: count, MIGProfile: profile}, nil
+	}
+
+	if len(quantities) == 0 {
+		return labelGPUDemand(pod.GetLabels())
+	}
+
+	demand := GPUDemand{Number: 1}
+	number := int64(0)
+	for _, name := range []v1.ResourceName{ResourceGPUNumber, ResourceNvidiaGPU} {
+		q, ok := quantities[name]
+		if !ok {
+			continue
+		}
+		if q.MilliValue()%1000 != 0 {
+			return GPUDemand{}, fmt.Errorf("%v must be a whole number of cards, got %v", name, q.String())
+		}
+		if number != 0 && number != q.Value() {
+			return GPUDemand{}, fmt.Errorf("%v and %v disagree: %d != %d", ResourceGPUNumber, ResourceNvidiaGPU, number, q.Value())
+		}
+		number = q.Value()
+	}
+
+	if number > 0 {
+		demand.Number = int(number)
+		if _, ok := quantities[ResourceGPUMemory]; !ok {
+			demand.Whole = true
+		}
+	}
+	if q, ok := quantities[ResourceGPUMemory]; ok {
+		demand.Memory = splitAcross(q.Value(), demand.Number)
+	}
+	if q, ok := quantities[ResourceGPUCompute]; ok {
+		demand.Core = splitAcross(q.Value(), demand.Number)
+	}
+
+	return demand, nil
+}
+
+// labelGPUDemand reads the demand from the scv/number, scv/memory and scv/core labels.
+// A pod with scv/number but without scv/memory requests whole cards.
+func labelGPUDemand(labels map[string]string) (GPUDemand, error) {
+	demand := GPUDemand{Number: 1}
+	if value, ok := labels[ScvNumber]; ok {
+		number, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || number == 0 {
+			return GPUDemand{}, fmt.Errorf("invalid %v label %q", ScvNumber, value)
+		}
+		demand.Number = int(number)
+		if _, ok := labels[ScvMemory]; !ok {
+			demand.Whole = true
+		}
+	}
+	if value, ok := labels[ScvMemory]; ok {
+		mem, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return GPUDemand{}, fmt.Errorf("invalid %v label %q", ScvMemory, value)
+		}
+		demand.Memory = mem
+	}
+	if value, ok := labels[ScvCore]; ok {
+		core, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return GPUDemand{}, fmt.Errorf("invalid %v label %q", ScvCore, value)
+		}
+		demand.Core = core
+	}
+	return demand, nil
+}
+
+// EffectiveRequest returns the effective request of a resource for the pod, or
+// nil if no container asks for it. As in Kubernetes 1.28+, sidecars keep running
+// next to the containers: their requests add to those of the containers and to
+// those of the init containers that start after them.
+func EffectiveRequest(pod *v1.Pod, name v1.ResourceName) (*resource.Quantity, error) {
+	var sum, initMax, sidecarSum *resource.Quantity
+	sidecars := AnnotatedSidecars(pod)
+
+	for _, container := range pod.Spec.Containers {
+		q, ok, err := containerRequest(container, name)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		sum = addQuantity(sum, q)
+	}
+
+	for _, container := range pod.Spec.InitContainers {
+		q, ok, err := containerRequest(container, name)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		// A sidecar starts next to the sidecars before it
+		if sidecars[container.Name] {
+			sidecarSum = addQuantity(sidecarSum, q)
+			q = sidecarSum.DeepCopy()
+		} else if sidecarSum != nil {
+			q.Add(*sidecarSum)
+		}
+		if initMax == nil || q.Cmp(*initMax) > 0 {
+			initMax = &q
+		}
+	}
+
+	if sidecarSum != nil {
+		sum = addQuantity(sum, *sidecarSum)
+	}
+	if sum == nil || (initMax != nil && initMax.Cmp(*sum) > 0) {
+		return initMax, nil
+	}
+	return sum, nil
+}
+
+// addQuantity returns the sum of total and q, total is nil before the first term.
+func addQuantity(total *resource.Quantity, q resource.Quantity) *resource.Quantity {
+	if total == nil {
+		sum := q.DeepCopy()
+		return &sum
+	}
+	total.Add(q)
+	return total
+}
+
+// AnnotatedSidecars returns the init containers listed in the SidecarsAnnotation of the pod.
+func AnnotatedSidecars(pod *v1.Pod) map[string]bool {
+	sidecars := make(map[string]bool)
+	value, ok := pod.GetAnnotations()[SidecarsAnnotation]
+	if !ok {
+		return sidecars
+	}
+	for _, name := range strings.Split(value, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			sidecars[name] = true
+		}
+	}
+	return sidecars
+}
+
+// migRequest returns the MIG profile the pod asks for and the number of instances.
+func migRequest(pod *v1.Pod) (string, int, error) {
+	names := make(map[v1.ResourceName]bool)
+	for _, containers := range [][]v1.Container{pod.Spec.Containers, pod.Spec.InitContainers} {
+		for _, container := range containers {
+			for _, list := range []v1.ResourceList{container.Resources.Requests, container.Resources.Limits} {
+				for name := range list {
+					if strings.HasPrefix(string(name), MIGResourcePrefix) {
+						names[name] = true
+					}
+				}
+			}
+		}
+	}
+
+	if len(names) == 0 {
+		return "", 0, nil
+	}
+	if len(names) > 1 {
+		return "", 0, fmt.Errorf("only one MIG profile may be requested, got %d", len(names))
+	}
+
+	for name := range names {
+		q, err := EffectiveRequest(pod, name)
+		if err != nil {
+			return "", 0, err
+		}
+		if q.MilliValue()%1000 != 0 || q.Value() <= 0 {
+			return "", 0, fmt.Errorf("%v must be a positive whole number of instances, got %v", name, q.String())
+		}
+		return strings.TrimPrefix(string(name), MIGResourcePrefix), int(q.Value()), nil
+	}
+	return "", 0, nil
+}
+
+func containerRequest(container v1.Container, name v1.ResourceName) (resource.Quantity, bool, error) {
+	q, ok := container.Resources.Requests[name]
+	if !ok {
+		q, ok = container.Resources.Limits[name]
+	}
+	if !ok {
+		return resource.Quantity{}, false, nil
+	}
+	if q.Sign() < 0 {
+		return resource.Quantity{}, false, fmt.Errorf("container %v: negative %v %v", container.Name, name, q.String())
+	}
+	return q.DeepCopy(), true, nil
+}
+
+func splitAcross(total int64, number int) uint64 {
+	if number <= 1 {
+		return uint64(total)
+	}
+	return uint64((total + int64(number) - 1) / int64(number))
+}
@@ -0,0 +1,372 @@
+package node
+
+import (
+	"context"
+	scv "github.com/NJUPT-ISL/SCV/api/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	v1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"strconv"
+	"sync"
+)
+
+const metricsNamespace = "nodesim"
+
+var (
+	cardLabels = []string{"node", "card", "nodesim", "model"}
+	nodeLabels = []string{"node", "nodesim"}
+
+	cardMemoryUtilization = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "gpu_memory_utilization",
+		Help:      "Share of the memory of a GPU card allocated to pods.",
+	}, cardLabels)
+	cardCoreUtilization = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "gpu_core_utilization",
+		Help:      "Share of the compute of a GPU card allocated to pods.",
+	}, cardLabels)
+	cardLoad = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "gpu_load",
+		Help:      "Simulated compute utilization of a GPU card.",
+	}, cardLabels)
+	cardPower = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "gpu_power_watts",
+		Help:      "Simulated power draw of a GPU card.",
+	}, cardLabels)
+	cardTemperature = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "gpu_temperature_celsius",
+		Help:      "Simulated temperature of a GPU card.",
+	}, cardLabels)
+	cardClock = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "gpu_clock",
+		Help:      "Simulated clock of a GPU card.",
+	}, cardLabels)
+	cardHealthy = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "gpu_healthy",
+		Help:      "Whether a GPU card is healthy.",
+	}, cardLabels)
+
+	nodeMemoryUtilization = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "node_gpu_memory_utilization",
+		Help:      "Share of the GPU memory of a node allocated to pods.",
+	}, nodeLabels)
+	nodeCoreUtilization = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "node_gpu_core_utilization",
+		Help:      "Share of the GPU compute of a node allocated to pods.",
+	}, nodeLabels)
+	nodeLoad = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "node_gpu_load",
+		Help:      "Mean simulated compute utilization of the GPU cards of a node.",
+	}, nodeLabels)
+
+	nodeCPUUsage = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "node_cpu_usage_cores",
+		Help:      "Simulated CPU usage of the pods of a node.",
+	}, nodeLabels)
+	nodeMemoryUsage = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "node_memory_usage_bytes",
+		Help:      "Simulated memory usage of the pods of a node.",
+	}, nodeLabels)
+	nodeCPUUtilization = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "node_cpu_utilization",
+		Help:      "Simulated CPU usage of a node relative to its allocatable CPU.",
+	}, nodeLabels)
+	nodeMemoryUtilizationRatio = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "node_memory_utilization",
+		Help:      "Simulated memory usage of a node relative to its allocatable memory.",
+	}, nodeLabels)
+
+	nodePods = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "node_pods",
+		Help:      "Running simulated pods of a node by QoS class.",
+	}, append(nodeLabels, "qos_class"))
+
+	clusterMemoryUtilization = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "cluster_gpu_memory_utilization",
+		Help:      "Share of the GPU memory of all simulated nodes allocated to pods.",
+	})
+	clusterIdleCards = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "cluster_gpu_idle_cards",
+		Help:      "Healthy GPU cards with no memory allocated.",
+	})
+	clusterPartialCards = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "cluster_gpu_partial_cards",
+		Help:      "Healthy GPU cards with part of their memory allocated.",
+	})
+	clusterFragmentation = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "cluster_gpu_fragmentation",
+		Help:      "Share of the free GPU memory that lies on partially allocated cards and cannot serve a whole card request.",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		cardMemoryUtilization,
+		cardCoreUtilization,
+		cardLoad,
+		cardPower,
+		cardTemperature,
+		cardClock,
+		cardHealthy,
+		nodeMemoryUtilization,
+		nodeCoreUtilization,
+		nodeLoad,
+		nodeCPUUsage,
+		nodeMemoryUsage,
+		nodeCPUUtilization,
+		nodeMemoryUtilizationRatio,
+		nodePods,
+		clusterMemoryUtilization,
+		clusterIdleCards,
+		clusterPartialCards,
+		clusterFragmentation,
+	)
+}
+
+// exportedNode is the label sets exported for a node.
+type exportedNode struct {
+	labels prometheus.Labels
+	cards  []prometheus.Labels
+}
+
+// exported remembers the label sets exported for each node, so that the series
+// of removed nodes and cards can be dropped.
+var exported = struct {
+	sync.Mutex
+	nodes      map[string]exportedNode
+	heartbeats map[string]bool
+	usage      map[string]prometheus.Labels
+}{
+	nodes:      make(map[string]exportedNode),
+	heartbeats: make(map[string]bool),
+	usage:      make(map[string]prometheus.Labels),
+}
+
+// RecordNodeMetrics exports the utilization and card telemetry of a node.
+func RecordNodeMetrics(node *v1.Node, currentScv *scv.Scv, utilization *Utilization) {
+	nodeName := node.GetName()
+	nodeSim := node.GetLabels()[UniqueLabelKey]
+	statusList := GetCardStatus(currentScv)
+
+	cardList := make([]prometheus.Labels, 0, len(currentScv.Status.CardList))
+	for index, card := range currentScv.Status.CardList {
+		labels := prometheus.Labels{
+			"node":    nodeName,
+			"card":    strconv.Itoa(int(card.ID)),
+			"nodesim": nodeSim,
+			"model":   card.Model,
+		}
+		cardList = append(cardList, labels)
+
+		cardMemoryUtilization.With(labels).Set(utilization.CardMemory[index])
+		cardCoreUtilization.With(labels).Set(utilization.CardCore[index])
+		cardLoad.With(labels).Set(utilization.CardLoad[index])
+		cardPower.With(labels).Set(float64(card.Power))
+		cardClock.With(labels).Set(float64(card.Clock))
+		cardTemperature.With(labels).Set(statusList[index].Temperature)
+		healthy := 0.0
+		if card.Health != CardUnhealthy {
+			healthy = 1
+		}
+		cardHealthy.With(labels).Set(healthy)
+	}
+
+	labels := prometheus.Labels{"node": nodeName, "nodesim": nodeSim}
+	nodeMemoryUtilization.With(labels).Set(utilization.NodeMemory)
+	nodeCoreUtilization.With(labels).Set(utilization.NodeCore)
+	nodeLoad.With(labels).Set(utilization.NodeLoad)
+
+	exported.Lock()
+	defer exported.Unlock()
+	old := exported.nodes[nodeName]
+	for _, cardLabels := range old.cards {
+		if !containsLabels(cardList, cardLabels) {
+			deleteCardMetrics(cardLabels)
+		}
+	}
+	if old.labels != nil && old.labels["nodesim"] != nodeSim {
+		deleteNodeMetrics(old.labels)
+	}
+	exported.nodes[nodeName] = exportedNode{labels: labels, cards: cardList}
+}
+
+// ForgetNodeMetrics drops the series of the nodes that are not in the list.
+func ForgetNodeMetrics(nodeList []v1.Node) {
+	exist := make(map[string]bool, len(nodeList))
+	for _, node := range nodeList {
+		exist[node.GetName()] = true
+	}
+
+	exported.Lock()
+	defer exported.Unlock()
+	for nodeName, node := range exported.nodes {
+		if exist[nodeName] {
+			continue
+		}
+		for _, labels := range node.cards {
+			deleteCardMetrics(labels)
+		}
+		deleteNodeMetrics(node.labels)
+		delete(exported.nodes, nodeName)
+	}
+	for nodeName, labels := range exported.usage {
+		if !exist[nodeName] {
+			deleteUsageMetrics(labels)
+			delete(exported.usage, nodeName)
+		}
+	}
+	for nodeName := range exported.heartbeats {
+		if !exist[nodeName] {
+			heartbeatLag.DeleteLabelValues(nodeName)
+			delete(exported.heartbeats, nodeName)
+		}
+	}
+}
+
+var qosClasses = []v1.PodQOSClass{v1.PodQOSGuaranteed, v1.PodQOSBurstable, v1.PodQOSBestEffort}
+
+// RecordNodeUsage exports the simulated CPU and memory usage of a node and the
+// number of its running pods by QoS class.
+func RecordNodeUsage(node *v1.Node, usage v1.ResourceList, pods map[v1.PodQOSClass]int) {
+	labels := prometheus.Labels{"node": node.GetName(), "nodesim": node.GetLabels()[UniqueLabelKey]}
+	for _, class := range qosClasses {
+		nodePods.With(qosLabels(labels, class)).Set(float64(pods[class]))
+	}
+	nodeCPUUsage.With(labels).Set(float64(usage.Cpu().MilliValue()) / 1000)
+	nodeMemoryUsage.With(labels).Set(float64(usage.Memory().Value()))
+	if cpu := node.Status.Allocatable.Cpu().MilliValue(); cpu > 0 {
+		nodeCPUUtilization.With(labels).Set(float64(usage.Cpu().MilliValue()) / float64(cpu))
+	}
+	if memory := node.Status.Allocatable.Memory().Value(); memory > 0 {
+		nodeMemoryUtilizationRatio.With(labels).Set(float64(usage.Memory().Value()) / float64(memory))
+	}
+
+	exported.Lock()
+	defer exported.Unlock()
+	if old, ok := exported.usage[node.GetName()]; ok && old["nodesim"] != labels["nodesim"] {
+		deleteUsageMetrics(old)
+	}
+	exported.usage[node.GetName()] = labels
+}
+
+func deleteUsageMetrics(labels prometheus.Labels) {
+	nodeCPUUsage.Delete(labels)
+	nodeMemoryUsage.Delete(labels)
+	nodeCPUUtilization.Delete(labels)
+	nodeMemoryUtilizationRatio.Delete(labels)
+	for _, class := range qosClasses {
+		nodePods.Delete(qosLabels(labels, class))
+	}
+}
+
+func qosLabels(labels prometheus.Labels, class v1.PodQOSClass) prometheus.Labels {
+	return prometheus.Labels{"node": labels["node"], "nodesim": labels["nodesim"], "qos_class": string(class)}
+}
+
+func deleteNodeMetrics(labels prometheus.Labels) {
+	nodeMemoryUtilization.Delete(labels)
+	nodeCoreUtilization.Delete(labels)
+	nodeLoad.Delete(labels)
+}
+
+func deleteCardMetrics(labels prometheus.Labels) {
+	cardMemoryUtilization.Delete(labels)
+	cardCoreUtilization.Delete(labels)
+	cardLoad.Delete(labels)
+	cardPower.Delete(labels)
+	cardClock.Delete(labels)
+	cardTemperature.Delete(labels)
+	cardHealthy.Delete(labels)
+}
+
+func containsLabels(labelList []prometheus.Labels, labels prometheus.Labels) bool {
+	for _, l := range labelList {
+		if l["card"] == labels["card"] && l["nodesim"] == labels["nodesim"] && l["model"] == labels["model"] {
+			return true
+		}
+	}
+	return false
+}
+
+// ClusterUtilization is the GPU usage of the simulated nodes. Fragmentation is the
+// share of the free GPU memory left on partially allocated cards, Load the mean
+// simulated load of the cards.
+type ClusterUtilization struct {
+	TotalMemory       uint64
+	FreeMemory        uint64
+	PartialFreeMemory uint64
+	IdleCards         int
+	PartialCards      int
+	MemoryUtilization float64
+	Fragmentation     float64
+	Load              float64
+}
+
+// GetClusterUtilization sums the GPU usage of the Scvs of the simulated nodes. Unhealthy
+// cards count in the total memory only.
+func GetClusterUtilization(ctx context.Context, c client.Client, nodeList []v1.Node) ClusterUtilization {
+	utilization := ClusterUtilization{}
+	cards := 0
+	for _, node := range nodeList {
+		currentScv := &scv.Scv{}
+		if err := c.Get(ctx, client.ObjectKey{Name: node.GetName()}, currentScv); err != nil {
+			continue
+		}
+		statusList := GetCardStatus(currentScv)
+		for i, card := range currentScv.Status.CardList {
+			utilization.TotalMemory += card.TotalMemory
+			cards++
+			utilization.Load += statusList[i].Utilization
+			if card.Health == CardUnhealthy {
+				continue
+			}
+			utilization.FreeMemory += card.FreeMemory
+			switch {
+			case card.FreeMemory == card.TotalMemory:
+				utilization.IdleCards++
+			case card.FreeMemory > 0:
+				utilization.PartialCards++
+				utilization.PartialFreeMemory += card.FreeMemory
+			}
+		}
+	}
+
+	if utilization.TotalMemory > 0 {
+		utilization.MemoryUtilization = 1 - float64(utilization.FreeMemory)/float64(utilization.TotalMemory)
+	}
+	if utilization.FreeMemory > 0 {
+		utilization.Fragmentation = float64(utilization.PartialFreeMemory) / float64(utilization.FreeMemory)
+	}
+	if cards > 0 {
+		utilization.Load /= float64(cards)
+	}
+	return utilization
+}
+
+// RecordClusterMetrics exports the GPU memory utilization and fragmentation of the simulated nodes.
+func RecordClusterMetrics(ctx context.Context, c client.Client, nodeList []v1.Node) {
+	utilization := GetClusterUtilization(ctx, c, nodeList)
+	clusterMemoryUtilization.Set(utilization.MemoryUtilization)
+	clusterFragmentation.Set(utilization.Fragmentation)
+	clusterIdleCards.Set(float64(utilization.IdleCards))
+	clusterPartialCards.Set(float64(utilization.PartialCards))
+}
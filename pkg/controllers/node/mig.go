@@ -0,0 +1,156 @@
+package node
+
+import (
+	"fmt"
+	scv1 "github.com/NJUPT-ISL/SCV/api/v1"
+	v1 "k8s.io/api/core/v1"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MIGResourcePrefix is the prefix of the extended resource of a MIG profile, e.g. nvidia.com/mig-1g.5gb
+	MIGResourcePrefix = "nvidia.com/mig-"
+
+	// A MIG capable card has 8 memory slices and 7 compute slices
+	migMemorySlices  = 8
+	migComputeSlices = 7
+)
+
+// migGeometry is where an instance with a given number of compute slices may be
+// placed on an A100/H100 class card: it takes Size memory slices starting at one of Starts.
+type migGeometry struct {
+	Size   int
+	Starts []int
+}
+
+var migGeometries = map[int]migGeometry{
+	1: {Size: 1, Starts: []int{0, 1, 2, 3, 4, 5, 6}},
+	2: {Size: 2, Starts: []int{0, 2, 4}},
+	3: {Size: 4, Starts: []int{0, 4}},
+	4: {Size: 4, Starts: []int{0}},
+	7: {Size: 8, Starts: []int{0}},
+}
+
+// MIGDevice is a MIG instance of a simulated card.
+type MIGDevice struct {
+	Profile string `json:"profile"`
+	// Start is the first memory slice of the instance, Size the number of slices it takes.
+	Start int `json:"start"`
+	Size  int `json:"size"`
+	// Memory is the GPU memory of the instance, Core its compute in percent of the card.
+	Memory uint64 `json:"memory"`
+	Core   uint64 `json:"core"`
+	// Pod is the namespace/name of the pod bound to the instance.
+	Pod string `json:"pod,omitempty"`
+}
+
+// parseMIGProfile returns the compute slices of a profile such as "3g.20gb".
+func parseMIGProfile(profile string) (int, error) {
+	parts := strings.SplitN(profile, "g.", 2)
+	if len(parts) != 2 || !strings.HasSuffix(parts[1], "gb") {
+		return 0, fmt.Errorf("invalid MIG profile %q", profile)
+	}
+	slices, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, fmt.Errorf("invalid MIG profile %q", profile)
+	}
+	if _, ok := migGeometries[slices]; !ok {
+		return 0, fmt.Errorf("unsupported MIG profile %q", profile)
+	}
+	return slices, nil
+}
+
+// LayoutMIG partitions a card into the given MIG profiles, placing every instance
+// at one of the valid start slices of its profile without overlapping another.
+// Larger instances are placed first and earlier start slices are preferred. It
+// fails if the profiles do not fit the MIG geometry of the card.
+func LayoutMIG(profiles []string, card scv1.Card) ([]MIGDevice, error) {
+	computeSlices := make([]int, len(profiles))
+	usedCompute := 0
+	for i, profile := range profiles {
+		slices, err := parseMIGProfile(profile)
+		if err != nil {
+			return nil, err
+		}
+		computeSlices[i] = slices
+		usedCompute += slices
+	}
+	if usedCompute > migComputeSlices {
+		return nil, fmt.Errorf("MIG profiles %v take %d compute slices, only %d available", profiles, usedCompute, migComputeSlices)
+	}
+
+	order := make([]int, len(profiles))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return migGeometries[computeSlices[order[i]]].Size > migGeometries[computeSlices[order[j]]].Size
+	})
+
+	used := make([]bool, migMemorySlices)
+	starts := make([]int, len(profiles))
+
+	// place tries the instances from order[n] on, backtracking when one has no free start left.
+	var place func(n int) bool
+	place = func(n int) bool {
+		if n == len(order) {
+			return true
+		}
+		geometry := migGeometries[computeSlices[order[n]]]
+		for _, start := range geometry.Starts {
+			free := true
+			for k := start; k < start+geometry.Size; k++ {
+				if used[k] {
+					free = false
+					break
+				}
+			}
+			if !free {
+				continue
+			}
+			for k := start; k < start+geometry.Size; k++ {
+				used[k] = true
+			}
+			starts[order[n]] = start
+			if place(n + 1) {
+				return true
+			}
+			for k := start; k < start+geometry.Size; k++ {
+				used[k] = false
+			}
+		}
+		return false
+	}
+
+	if !place(0) {
+		return nil, fmt.Errorf("MIG profiles %v have no valid placement", profiles)
+	}
+
+	devices := make([]MIGDevice, 0, len(profiles))
+	for i, profile := range profiles {
+		geometry := migGeometries[computeSlices[i]]
+		devices = append(devices, MIGDevice{
+			Profile: profile,
+			Start:   starts[i],
+			Size:    geometry.Size,
+			Memory:  card.TotalMemory * uint64(geometry.Size) / migMemorySlices,
+			Core:    CardCompute * uint64(computeSlices[i]) / migComputeSlices,
+		})
+	}
+
+	sort.Slice(devices, func(i, j int) bool {
+		return devices[i].Start < devices[j].Start
+	})
+	return devices, nil
+}
+
+// MIGResources counts the instances of each MIG profile on one card.
+func MIGResources(profiles []string) map[v1.ResourceName]int {
+	resources := make(map[v1.ResourceName]int)
+	for _, profile := range profiles {
+		resources[v1.ResourceName(MIGResourcePrefix+profile)]++
+	}
+	return resources
+}
@@ -0,0 +1,91 @@
+package node
+
+import (
+	scv1 "github.com/NJUPT-ISL/SCV/api/v1"
+	"testing"
+)
+
+func TestLayoutMIG(t *testing.T) {
+	card := scv1.Card{TotalMemory: 40000}
+	tests := []struct {
+		name     string
+		profiles []string
+		// starts are the start slices of the instances, ordered by start
+		starts  []int
+		wantErr bool
+	}{
+		{
+			name:     "whole card",
+			profiles: []string{"7g.40gb"},
+			starts:   []int{0},
+		},
+		{
+			name:     "seven small instances",
+			profiles: []string{"1g.5gb", "1g.5gb", "1g.5gb", "1g.5gb", "1g.5gb", "1g.5gb", "1g.5gb"},
+			starts:   []int{0, 1, 2, 3, 4, 5, 6},
+		},
+		{
+			name:     "larger instances placed first",
+			profiles: []string{"1g.5gb", "2g.10gb", "3g.20gb"},
+			starts:   []int{0, 4, 6},
+		},
+		{
+			name:     "two halves",
+			profiles: []string{"3g.20gb", "3g.20gb"},
+			starts:   []int{0, 4},
+		},
+		{
+			name:     "3g and 4g share the card",
+			profiles: []string{"3g.20gb", "4g.20gb"},
+			starts:   []int{0, 4},
+		},
+		{
+			name:     "too many compute slices",
+			profiles: []string{"4g.20gb", "4g.20gb"},
+			wantErr:  true,
+		},
+		{
+			name:     "unsupported profile",
+			profiles: []string{"5g.30gb"},
+			wantErr:  true,
+		},
+		{
+			name:     "invalid profile",
+			profiles: []string{"big"},
+			wantErr:  true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			devices, err := LayoutMIG(test.profiles, card)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("LayoutMIG(%v) = %+v, want an error", test.profiles, devices)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LayoutMIG(%v) error: %v", test.profiles, err)
+			}
+			if len(devices) != len(test.starts) {
+				t.Fatalf("LayoutMIG(%v) = %d instances, want %d", test.profiles, len(devices), len(test.starts))
+			}
+			used := make([]bool, migMemorySlices)
+			for i, device := range devices {
+				if device.Start != test.starts[i] {
+					t.Errorf("instance %d %v starts at %d, want %d", i, device.Profile, device.Start, test.starts[i])
+				}
+				for k := device.Start; k < device.Start+device.Size; k++ {
+					if k >= migMemorySlices || used[k] {
+						t.Errorf("instance %d %v overlaps slice %d", i, device.Profile, k)
+						continue
+					}
+					used[k] = true
+				}
+				if want := card.TotalMemory * uint64(device.Size) / migMemorySlices; device.Memory != want {
+					t.Errorf("instance %d %v has memory %d, want %d", i, device.Profile, device.Memory, want)
+				}
+			}
+		})
+	}
+}
@@ -123,12 +123,19 @@ func (r *NodeSimReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return ctrl.Result{}, nil
 	}
 
-	// Delete Nodes
-	if nodeList.Items != nil && len(nodeList.Items) > nodeSim.Spec.Number {
-		for i := nodeSim.Spec.Number; i < len(nodeList.Items); i++ {
+	// Delete the nodes beyond Number or removed by the cluster-autoscaler
+	indexes := make(map[string]bool)
+	for _, index := range NodeIndexes(nodeSim) {
+		indexes[NodeName(nodeSim, index)] = true
+	}
+	if nodeList.Items != nil {
+		for _, item := range nodeList.Items {
+			if indexes[item.GetName()] {
+				continue
+			}
 
 			//Delete Node
-			nodeName := nodeSim.GetNamespace() + "-" + nodeSim.GetName() + "-" + strconv.Itoa(i)
+			nodeName := item.GetName()
 			node := &v1.Node{}
 			node.SetName(nodeName)
 			if err := r.Client.Delete(ctx, node); err != nil && !apierrors.IsNotFound(err) {
@@ -175,9 +182,10 @@ func (r *NodeSimReconciler) SyncFakeNode(ctx context.Context, nodeSim *simv1.Nod
 
 	nodeList := make([]*v1.Node, 0)
 	// Gen NodeList
-	for i := 0; i < nodeSim.Spec.Number; i++ {
+	for _, i := range NodeIndexes(nodeSim) {
 		vnode := nodeTemplate.DeepCopy()
-		vnode.SetName(nodeSim.GetNamespace() + "-" + nodeSim.GetName() + "-" + strconv.Itoa(i))
+		vnode.SetName(NodeName(nodeSim, i))
+		vnode.Spec.ProviderID = ProviderID(vnode.GetName())
 		nodeList = append(nodeList, vnode)
 	}
 
@@ -196,6 +204,10 @@ func (r *NodeSimReconciler) SyncFakeNode(ctx context.Context, nodeSim *simv1.Nod
 			Namespace: node.GetNamespace(),
 		}, fakeNode)
 		if err != nil && apierrors.IsNotFound(err) {
+			// New nodes of a node group become Ready after the provisioning delay
+			if delay := ProvisioningDelay(nodeSim); delay > 0 {
+				node.SetAnnotations(map[string]string{ReadyAtAnnotation: util.Now().Add(delay).Format(time.RFC3339)})
+			}
 			if err := r.Client.Create(ctx, node); err != nil {
 				klog.Errorf("NodeSim: %v/%v Create Node: %v Error: %v ", nodeSim.GetNamespace(), nodeSim.GetName(), node.GetName(), err)
 			}
@@ -216,6 +228,14 @@ func (r *NodeSimReconciler) SyncFakeNode(ctx context.Context, nodeSim *simv1.Nod
 			newNode.Status.Allocatable = nodeTemplate.Status.Allocatable
 			newNode.Status.Capacity = nodeTemplate.Status.Capacity
 			newNode.Status.Addresses = node.Status.Addresses
+
+			// Keep the GPUs of unhealthy cards out of allocatable, like the GPUHealthUpdater does
+			curScv := &scv1.Scv{}
+			if err := r.Client.Get(ctx, types.NamespacedName{Name: node.GetName()}, curScv); err == nil {
+				newNode.Status.Allocatable = GPUAllocatable(newNode, curScv.Status.CardList, GetCardStatus(curScv))
+			} else if !apierrors.IsNotFound(err) {
+				klog.Errorf("NodeSim: %v/%v Get Scv: %v Error: %v ", nodeSim.GetNamespace(), nodeSim.GetName(), node.GetName(), err)
+			}
 			_, _, err := util.PatchNodeStatus(r.ClientSet.CoreV1(), types.NodeName(node.GetName()), fakeNode, newNode)
 			if err != nil {
 				klog.Errorf("Patch Node: %v Error: %v", newNode.GetName(), err)
@@ -238,11 +258,11 @@ func (r *NodeSimReconciler) SyncFakeNode(ctx context.Context, nodeSim *simv1.Nod
 		for i := 0; i < nodeSim.Spec.GPU.Number; i++ {
 			card := scv1.Card{
 				ID:          uint(i),
-				Health:      "Healthy",
+				Health:      CardHealthy,
 				Model:       nodeSim.Spec.GpuModel,
-				Power:       250,
+				Power:       DefaultCardPower,
 				TotalMemory: strToUint64(nodeSim.Spec.GPU.Memory),
-				Clock:       6000,
+				Clock:       DefaultCardClock,
 				FreeMemory:  strToUint64(nodeSim.Spec.GPU.Memory),
 				Core:        strToUint(nodeSim.Spec.GPU.Core),
 				Bandwidth:   strToUint(nodeSim.Spec.GPU.Bandwidth),
@@ -253,6 +273,20 @@ func (r *NodeSimReconciler) SyncFakeNode(ctx context.Context, nodeSim *simv1.Nod
 			memSum += strToUint64(nodeSim.Spec.GPU.Memory)
 		}
 
+		statusList := make(CardStatusList, 0, len(cardList))
+		for _, card := range cardList {
+			status := NewCardStatus(card)
+			if len(nodeSim.Spec.GPU.MIG) > 0 {
+				devices, err := LayoutMIG(nodeSim.Spec.GPU.MIG, card)
+				if err != nil {
+					klog.Errorf("NodeSim: %v/%v Card: %v MIG Layout Error: %v", nodeSim.GetNamespace(), nodeSim.GetName(), card.ID, err)
+				}
+				status.MIGDevices = devices
+			}
+			statusList = append(statusList, status)
+		}
+		ApplyGPUTopology(statusList, nodeSim.Spec.GPU.Topology)
+
 		updateTime := metav1.Time{Time: time.Now()}
 
 		scv := &scv1.Scv{
@@ -271,6 +305,8 @@ func (r *NodeSimReconciler) SyncFakeNode(ctx context.Context, nodeSim *simv1.Nod
 			},
 		}
 
+		SetCardStatus(scv, statusList)
+
 		err := r.Client.Get(ctx, types.NamespacedName{
 			Name: node.GetName(),
 		}, curScv)
@@ -279,21 +315,58 @@ func (r *NodeSimReconciler) SyncFakeNode(ctx context.Context, nodeSim *simv1.Nod
 			if apierrors.IsNotFound(err) {
 				if err = r.Client.Create(ctx, scv); err != nil {
 					klog.Errorf("Create Scv: %v, Error: %v", node.GetName(), err)
+				} else if err = r.Client.Patch(ctx, scv, &util.Patch{PatchOps: ScvPatchOps(scv)}); err != nil {
+					// The created status has no compute share on its cards yet
+					klog.Errorf("Patch Scv: %v, Error: %v", node.GetName(), err)
 				}
 			} else {
 				klog.Errorf("Get Scv: %v, Error: %v", node.GetName(), err)
 			}
 
 		} else {
-			ops := []util.Ops{
-				{
-					Op:    "replace",
-					Path:  "/status",
-					Value: scv.Status,
-				},
+			podList := &v1.PodList{}
+			if err := r.Client.List(ctx, podList, &client.MatchingLabels{
+				ManageLabelKey: ManageLabelValue,
+			}); err != nil {
+				klog.Errorf("List Pod Error: %v", err)
+				return
+			}
+			podListWithNode := make([]v1.Pod, 0)
+			for _, pod := range podList.Items {
+				if pod.Spec.NodeName == node.GetName() {
+					podListWithNode = append(podListWithNode, pod)
+				}
 			}
 
-			err = r.Client.Patch(ctx, curScv, &util.Patch{PatchOps: ops})
+			err = UpdateScv(ctx, r.Client, node.GetName(), func(curScv *scv1.Scv) bool {
+				if SameCards(curScv.Status.CardList, cardList) && SameCardStatus(GetCardStatus(curScv), statusList) {
+					return false
+				}
+
+				// The cards changed: lay them out anew and charge them with the pods still holding GPUs.
+				cards := append(scv1.CardList(nil), cardList...)
+				for i := range cards {
+					if i < len(curScv.Status.CardList) && curScv.Status.CardList[i].ID == cards[i].ID {
+						cards[i].Health = curScv.Status.CardList[i].Health
+					}
+				}
+				statuses := make(CardStatusList, len(statusList))
+				for i, status := range statusList {
+					status.MIGDevices = append([]MIGDevice(nil), status.MIGDevices...)
+					statuses[i] = status
+				}
+				RebuildCards(cards, statuses, podListWithNode)
+
+				freeSum := uint64(0)
+				for _, card := range cards {
+					freeSum += card.FreeMemory
+				}
+				curScv.Status = scv.Status
+				curScv.Status.CardList = cards
+				curScv.Status.FreeMemorySum = freeSum
+				SetCardStatus(curScv, statuses)
+				return true
+			})
 			if err != nil {
 				klog.Errorf("Update Scv: %v, Error: %v", node.GetName(), err)
 			}
@@ -2,11 +2,13 @@ package node
 
 import (
 	simv1 "github.com/NJUPT-ISL/NodeSimulator/pkg/api/v1"
+	scv1 "github.com/NJUPT-ISL/SCV/api/v1"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/klog"
 	"strconv"
+	"strings"
 )
 
 func SelectGPUModel(nodesim *simv1.NodeSimulator) *simv1.NodeSimulator {
@@ -31,6 +33,27 @@ func SelectGPUModel(nodesim *simv1.NodeSimulator) *simv1.NodeSimulator {
 		nodesim.Spec.GPU.CoreNumber = 3584
 	}
 
+	if nodesim.Spec.GpuModel == "A100-40GB" {
+		nodesim.Spec.GPU.Memory = strconv.Itoa(40960)
+		nodesim.Spec.GPU.Core = strconv.Itoa(1410)
+		nodesim.Spec.GPU.Bandwidth = strconv.Itoa(1555)
+		nodesim.Spec.GPU.CoreNumber = 6912
+	}
+
+	if nodesim.Spec.GpuModel == "A100-80GB" {
+		nodesim.Spec.GPU.Memory = strconv.Itoa(81920)
+		nodesim.Spec.GPU.Core = strconv.Itoa(1410)
+		nodesim.Spec.GPU.Bandwidth = strconv.Itoa(2039)
+		nodesim.Spec.GPU.CoreNumber = 6912
+	}
+
+	if nodesim.Spec.GpuModel == "H100-80GB" {
+		nodesim.Spec.GPU.Memory = strconv.Itoa(81920)
+		nodesim.Spec.GPU.Core = strconv.Itoa(1980)
+		nodesim.Spec.GPU.Bandwidth = strconv.Itoa(3350)
+		nodesim.Spec.GPU.CoreNumber = 16896
+	}
+
 	return nodesim
 }
 
@@ -70,6 +93,14 @@ func GenNode(nodesim *simv1.NodeSimulator) (*v1.Node, error) {
 		return nil, err
 	}
 
+	// Like the kubelet, keep the eviction threshold out of the allocatable memory, so
+	// that pods using what they request never put the node under memory pressure.
+	allocatableMemory := memory.DeepCopy()
+	allocatableMemory.Sub(EvictionMemoryThreshold)
+	if allocatableMemory.Sign() < 0 {
+		allocatableMemory = *resource.NewQuantity(0, resource.BinarySI)
+	}
+
 	node := &v1.Node{
 		ObjectMeta: metav1.ObjectMeta{
 			Labels: labels,
@@ -88,7 +119,7 @@ func GenNode(nodesim *simv1.NodeSimulator) (*v1.Node, error) {
 			},
 			Allocatable: map[v1.ResourceName]resource.Quantity{
 				"cpu":       cpu,
-				"memory":    memory,
+				"memory":    allocatableMemory,
 				"pods":      pods,
 				"disk":      disk,
 				"bandwidth": bandwidth,
@@ -143,6 +174,11 @@ func GenNode(nodesim *simv1.NodeSimulator) (*v1.Node, error) {
 			node.Status.Allocatable["gpu/core"] = core
 			node.Status.Capacity["gpu/core"] = core
 
+			// gpu/compute is the compute share of all cards in percent, like gpu/memory
+			compute := *resource.NewQuantity(int64(CardCompute*nodesim.Spec.GPU.Number), resource.DecimalSI)
+			node.Status.Allocatable[ResourceGPUCompute] = compute
+			node.Status.Capacity[ResourceGPUCompute] = compute
+
 			coreNumber, err := resource.ParseQuantity(strconv.Itoa(nodesim.Spec.GPU.CoreNumber))
 			if err != nil {
 				klog.Errorf("NodeSim: %v/%v GPU Core number ParseQuantity Error: %v", nodesim.GetNamespace(), nodesim.GetName(), err)
@@ -151,8 +187,78 @@ func GenNode(nodesim *simv1.NodeSimulator) (*v1.Node, error) {
 			node.Status.Allocatable["gpu/coreNumber"] = coreNumber
 			node.Status.Capacity["gpu/coreNumber"] = coreNumber
 
+			if err := ValidateGPUTopology(nodesim.Spec.GPU.Topology, nodesim.Spec.GPU.Number); err != nil {
+				klog.Errorf("NodeSim: %v/%v GPU Topology Error: %v", nodesim.GetNamespace(), nodesim.GetName(), err)
+				return nil, err
+			}
+
+			if len(nodesim.Spec.GPU.MIG) > 0 {
+				card := scv1.Card{TotalMemory: uint64(mem)}
+				if _, err := LayoutMIG(nodesim.Spec.GPU.MIG, card); err != nil {
+					klog.Errorf("NodeSim: %v/%v GPU MIG Layout Error: %v", nodesim.GetNamespace(), nodesim.GetName(), err)
+					return nil, err
+				}
+
+				// Partitioned cards are only allocatable through their MIG instances
+				for name, count := range MIGResources(nodesim.Spec.GPU.MIG) {
+					instances := *resource.NewQuantity(int64(count*nodesim.Spec.GPU.Number), resource.DecimalSI)
+					node.Status.Allocatable[name] = instances
+					node.Status.Capacity[name] = instances
+				}
+				for _, name := range []v1.ResourceName{"gpu/number", "gpu/memory", ResourceGPUCompute} {
+					node.Status.Allocatable[name] = *resource.NewQuantity(0, resource.DecimalSI)
+				}
+			}
+
 		}
 	}
 
 	return node, nil
 }
+
+// GPUAllocatable returns the allocatable resources of the node, with the share of
+// its unhealthy cards taken off the GPU capacity.
+func GPUAllocatable(node *v1.Node, cardList scv1.CardList, statusList CardStatusList) v1.ResourceList {
+	allocatable := node.Status.Allocatable.DeepCopy()
+	if allocatable == nil {
+		allocatable = make(v1.ResourceList)
+	}
+
+	partitioned := false
+	unhealthy := map[v1.ResourceName]int64{}
+	for i, card := range cardList {
+		if i >= len(statusList) {
+			break
+		}
+		if len(statusList[i].MIGDevices) > 0 {
+			partitioned = true
+		}
+		if card.Health != CardUnhealthy {
+			continue
+		}
+		if len(statusList[i].MIGDevices) > 0 {
+			for _, device := range statusList[i].MIGDevices {
+				unhealthy[v1.ResourceName(MIGResourcePrefix+device.Profile)]++
+			}
+			continue
+		}
+		unhealthy["gpu/number"]++
+		unhealthy["gpu/memory"] += int64(card.TotalMemory)
+		unhealthy[ResourceGPUCompute] += int64(statusList[i].TotalCore)
+	}
+
+	for name, capacity := range node.Status.Capacity {
+		isMIG := strings.HasPrefix(string(name), MIGResourcePrefix)
+		isShared := name == "gpu/number" || name == "gpu/memory" || name == ResourceGPUCompute
+		// Partitioned cards are only allocatable through their MIG instances
+		if !isMIG && (!isShared || partitioned) {
+			continue
+		}
+		value := capacity.Value() - unhealthy[name]
+		if value < 0 {
+			value = 0
+		}
+		allocatable[name] = *resource.NewQuantity(value, resource.DecimalSI)
+	}
+	return allocatable
+}
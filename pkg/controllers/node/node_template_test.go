@@ -0,0 +1,138 @@
+package node
+
+import (
+	simv1 "github.com/NJUPT-ISL/NodeSimulator/pkg/api/v1"
+	scv1 "github.com/NJUPT-ISL/SCV/api/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"testing"
+)
+
+func TestGenNodeGPU(t *testing.T) {
+	tests := []struct {
+		name string
+		mig  []string
+		want map[v1.ResourceName]int64
+	}{
+		{
+			name: "whole cards",
+			want: map[v1.ResourceName]int64{"gpu/number": 4, "gpu/memory": 4 * 40960, "gpu/core": 1410, "gpu/compute": 400},
+		},
+		{
+			name: "partitioned cards",
+			mig:  []string{"3g.20gb", "3g.20gb"},
+			want: map[v1.ResourceName]int64{"gpu/number": 0, "gpu/memory": 0, "gpu/core": 1410, "gpu/compute": 0, MIGResourcePrefix + "3g.20gb": 8},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			node, err := GenNode(&simv1.NodeSimulator{Spec: simv1.NodeSimulatorSpec{
+				Cpu:       "8",
+				Memory:    "8Gi",
+				PodNumber: "110",
+				Disk:      "100Gi",
+				Bandwidth: "10G",
+				GpuModel:  "A100-40GB",
+				GPU:       simv1.GPU{Number: 4, MIG: test.mig},
+			}})
+			if err != nil {
+				t.Fatalf("GenNode() error: %v", err)
+			}
+			for name, want := range test.want {
+				if quantity, ok := node.Status.Allocatable[name]; !ok || quantity.Value() != want {
+					t.Errorf("allocatable %v = %v, want %v", name, quantity.String(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestGPUAllocatable(t *testing.T) {
+	card := func(id uint, health string) scv1.Card {
+		return scv1.Card{ID: id, Health: health, TotalMemory: 16000, Core: 1410}
+	}
+	whole := CardStatusList{NewCardStatus(card(0, CardHealthy)), NewCardStatus(card(1, CardHealthy))}
+	partitioned := CardStatusList{NewCardStatus(card(0, CardHealthy)), NewCardStatus(card(1, CardHealthy))}
+	for i := range partitioned {
+		partitioned[i].MIGDevices = []MIGDevice{{Profile: "3g.20gb", Start: 0, Size: 4}, {Profile: "3g.20gb", Start: 4, Size: 4}}
+	}
+
+	capacity := v1.ResourceList{
+		v1.ResourceCPU: resource.MustParse("8"),
+		"gpu/number":   resource.MustParse("2"),
+		"gpu/memory":   resource.MustParse("32000"),
+		"gpu/core":     resource.MustParse("1410"),
+		"gpu/compute":  resource.MustParse("200"),
+	}
+	migCapacity := v1.ResourceList{
+		v1.ResourceCPU:                resource.MustParse("8"),
+		"gpu/number":                  resource.MustParse("2"),
+		MIGResourcePrefix + "3g.20gb": resource.MustParse("4"),
+	}
+	migAllocatable := migCapacity.DeepCopy()
+	migAllocatable["gpu/number"] = resource.MustParse("0")
+
+	tests := []struct {
+		name        string
+		capacity    v1.ResourceList
+		allocatable v1.ResourceList
+		cardList    scv1.CardList
+		statusList  CardStatusList
+		want        map[v1.ResourceName]int64
+	}{
+		{
+			name:        "healthy cards",
+			capacity:    capacity,
+			allocatable: capacity,
+			cardList:    scv1.CardList{card(0, CardHealthy), card(1, CardHealthy)},
+			statusList:  whole,
+			want:        map[v1.ResourceName]int64{v1.ResourceCPU: 8, "gpu/number": 2, "gpu/memory": 32000, "gpu/core": 1410, "gpu/compute": 200},
+		},
+		{
+			name:        "unhealthy card taken off",
+			capacity:    capacity,
+			allocatable: capacity,
+			cardList:    scv1.CardList{card(0, CardHealthy), card(1, CardUnhealthy)},
+			statusList:  whole,
+			want:        map[v1.ResourceName]int64{v1.ResourceCPU: 8, "gpu/number": 1, "gpu/memory": 16000, "gpu/core": 1410, "gpu/compute": 100},
+		},
+		{
+			name:        "recovered card given back",
+			capacity:    capacity,
+			allocatable: v1.ResourceList{v1.ResourceCPU: resource.MustParse("8"), "gpu/number": resource.MustParse("1")},
+			cardList:    scv1.CardList{card(0, CardHealthy), card(1, CardHealthy)},
+			statusList:  whole,
+			want:        map[v1.ResourceName]int64{v1.ResourceCPU: 8, "gpu/number": 2, "gpu/memory": 32000, "gpu/compute": 200},
+		},
+		{
+			name:        "all cards unhealthy",
+			capacity:    capacity,
+			allocatable: capacity,
+			cardList:    scv1.CardList{card(0, CardUnhealthy), card(1, CardUnhealthy)},
+			statusList:  whole,
+			want:        map[v1.ResourceName]int64{v1.ResourceCPU: 8, "gpu/number": 0, "gpu/memory": 0, "gpu/core": 1410, "gpu/compute": 0},
+		},
+		{
+			name:        "instances of an unhealthy partitioned card taken off",
+			capacity:    migCapacity,
+			allocatable: migAllocatable,
+			cardList:    scv1.CardList{card(0, CardHealthy), card(1, CardUnhealthy)},
+			statusList:  partitioned,
+			want:        map[v1.ResourceName]int64{v1.ResourceCPU: 8, "gpu/number": 0, MIGResourcePrefix + "3g.20gb": 2},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			node := &v1.Node{Status: v1.NodeStatus{Capacity: test.capacity, Allocatable: test.allocatable}}
+			got := GPUAllocatable(node, test.cardList, test.statusList)
+			if len(got) != len(test.want) {
+				t.Errorf("GPUAllocatable() = %v, want %v", got, test.want)
+			}
+			for name, want := range test.want {
+				if quantity, ok := got[name]; !ok || quantity.Value() != want {
+					t.Errorf("GPUAllocatable()[%v] = %v, want %v", name, quantity.String(), want)
+				}
+			}
+		})
+	}
+}
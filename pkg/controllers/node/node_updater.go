@@ -107,17 +107,44 @@ func (n *NodeUpdater) Run(threadiness int, stopCh chan struct{}) {
 func (n *NodeUpdater) SyncNode(ctx context.Context, node *v1.Node) {
 
 	updateTime := metav1.Time{Time: time.Now()}
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == v1.NodeReady {
+			RecordHeartbeat(node.GetName(), condition.LastHeartbeatTime.Time, updateTime.Time)
+		}
+	}
+
+	memoryCondition := v1.NodeCondition{
+		LastHeartbeatTime:  updateTime,
+		LastTransitionTime: updateTime,
+		Message:            MemoryMessage,
+		Status:             v1.ConditionFalse,
+		Reason:             MemoryReason,
+		Type:               v1.NodeMemoryPressure,
+	}
+	if usage, ok := GetNodeUsage(node); ok && UnderMemoryPressure(node, usage) {
+		memoryCondition.Message = MemoryPressureMessage
+		memoryCondition.Status = v1.ConditionTrue
+		memoryCondition.Reason = MemoryPressureReason
+	}
+
+	readyCondition := v1.NodeCondition{
+		LastHeartbeatTime:  updateTime,
+		LastTransitionTime: updateTime,
+		Message:            KubeletMessage,
+		Status:             v1.ConditionTrue,
+		Reason:             KubeletReason,
+		Type:               v1.NodeReady,
+	}
+	// A new node of a node group is not Ready until its provisioning delay is over
+	if _, provisioning := Provisioning(node); provisioning {
+		readyCondition.Message = ProvisioningMessage
+		readyCondition.Status = v1.ConditionFalse
+		readyCondition.Reason = ProvisioningReason
+	}
 
 	// Update Node
 	conditions := []v1.NodeCondition{
-		{
-			LastHeartbeatTime:  updateTime,
-			LastTransitionTime: updateTime,
-			Message:            KubeletMessage,
-			Status:             v1.ConditionTrue,
-			Reason:             KubeletReason,
-			Type:               v1.NodeReady,
-		},
+		readyCondition,
 		{
 			LastTransitionTime: updateTime,
 			LastHeartbeatTime:  updateTime,
@@ -126,14 +153,7 @@ func (n *NodeUpdater) SyncNode(ctx context.Context, node *v1.Node) {
 			Reason:             DiskReason,
 			Type:               OutOfDiskPressure,
 		},
-		{
-			LastHeartbeatTime:  updateTime,
-			LastTransitionTime: updateTime,
-			Message:            MemoryMessage,
-			Status:             v1.ConditionFalse,
-			Reason:             MemoryReason,
-			Type:               v1.NodeMemoryPressure,
-		},
+		memoryCondition,
 		{
 			LastTransitionTime: updateTime,
 			LastHeartbeatTime:  updateTime,
@@ -184,6 +204,7 @@ func (n *NodeUpdater) SyncNode(ctx context.Context, node *v1.Node) {
 	if err != nil && apierrors.IsNotFound(err) {
 		err := n.Client.Create(ctx, newLease)
 		if err != nil {
+			RecordLeaseRenewFailure()
 			klog.Errorf("Sync Node Lease: %v Error: %v", node.GetName(), err)
 		}
 		return
@@ -197,6 +218,7 @@ func (n *NodeUpdater) SyncNode(ctx context.Context, node *v1.Node) {
 		},
 	}
 	if err := n.Client.Patch(ctx, lease, &util.Patch{PatchOps: leaseOps}); err != nil {
+		RecordLeaseRenewFailure()
 		klog.Errorf("Sync Node Lease: %v Error: %v", node.GetName(), err)
 	}
 
@@ -3,8 +3,8 @@ package node
 import (
 	"context"
 	"errors"
-	"fmt"
 
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/util"
 	scv "github.com/NJUPT-ISL/SCV/api/v1"
 
 	"k8s.io/apimachinery/pkg/types"
@@ -52,12 +52,7 @@ func (n *ResourceUtilizationUpdater) processNextItem() bool {
 	defer n.Queue.Done(key)
 
 	if node, ok := key.(*v1.Node); ok {
-		nodeName := node.GetName()
-		cardPercentage, nodePercentage := n.SyncResourceUtilization(ctx, node)
-		for index, perCardPercent := range cardPercentage {
-			fmt.Printf("node name: %s, card ID: %d, utilization percent: %f\n", nodeName, index, perCardPercent)
-		}
-		fmt.Printf("----node name: %s, node utilization percent: %f\n", nodeName, nodePercentage)
+		n.SyncResourceUtilization(ctx, node)
 	} else {
 		klog.Errorf("Key in Queue is not Node Type. ")
 	}
@@ -72,24 +67,27 @@ func (n *ResourceUtilizationUpdater) runWorker() {
 
 func (n *ResourceUtilizationUpdater) InitUpdater() {
 	for {
-		time.Sleep(30 * time.Second)
-		fmt.Printf("----Update start----\n")
+		util.Tick(30 * time.Second)
 		nodeList := &v1.NodeList{}
 		err := n.Client.List(context.TODO(), nodeList)
 		if err != nil {
 			klog.Errorf("List Node Error: %v", err)
 			continue
 		}
+		managedNodes := make([]v1.Node, 0)
 		if nodeList.Items != nil && len(nodeList.Items) > 0 {
 			for _, node := range nodeList.Items {
 				labels := node.GetLabels()
 				if labels != nil {
 					if v, ok := labels[ManageLabelKey]; ok && v == ManageLabelValue {
+						managedNodes = append(managedNodes, node)
 						n.Queue.Add(node.DeepCopy())
 					}
 				}
 			}
 		}
+		ForgetNodeMetrics(managedNodes)
+		RecordClusterMetrics(context.TODO(), n.Client, managedNodes)
 	}
 }
 
@@ -110,26 +108,55 @@ func (n *ResourceUtilizationUpdater) Run(threadiness int, stopCh chan struct{})
 	klog.Info("Stopping resource utilization updater")
 }
 
-func (n *ResourceUtilizationUpdater) SyncResourceUtilization(ctx context.Context, node *v1.Node) ([]float64, float64) {
+// Utilization is the GPU memory and compute utilization of a node, per card and in total.
+// CardLoad is the simulated load of each card, next to the share of its compute allocated in CardCore.
+type Utilization struct {
+	CardMemory []float64
+	CardCore   []float64
+	CardLoad   []float64
+	NodeMemory float64
+	NodeCore   float64
+	NodeLoad   float64
+}
+
+func (n *ResourceUtilizationUpdater) SyncResourceUtilization(ctx context.Context, node *v1.Node) *Utilization {
 	currentScv := &scv.Scv{}
 	nodeName := node.GetName()
 
 	err := n.Client.Get(ctx, types.NamespacedName{Name: nodeName}, currentScv)
 	if err != nil {
 		klog.Errorf("Node: %v Get Scv Error: %v", nodeName, err)
+		return nil
 	}
 
 	cardList := currentScv.Status.CardList
+	statusList := GetCardStatus(currentScv)
 
-	//define card and node GPU memory utilization
-	cardPercentage := make([]float64, len(cardList))
-	nodePercentage := float64(0)
+	//define card and node GPU memory and core utilization
+	utilization := &Utilization{
+		CardMemory: make([]float64, len(cardList)),
+		CardCore:   make([]float64, len(cardList)),
+		CardLoad:   make([]float64, len(cardList)),
+	}
 
+	totalCore, freeCore := uint64(0), uint64(0)
 	for index, card := range cardList {
-		cardPercentage[index] = 1 - (float64(card.FreeMemory) / float64(card.TotalMemory))
+		utilization.CardMemory[index] = 1 - (float64(card.FreeMemory) / float64(card.TotalMemory))
+		if statusList[index].TotalCore > 0 {
+			utilization.CardCore[index] = 1 - (float64(statusList[index].FreeCore) / float64(statusList[index].TotalCore))
+		}
+		utilization.CardLoad[index] = statusList[index].Utilization
+		utilization.NodeLoad += statusList[index].Utilization / float64(len(cardList))
+		totalCore += statusList[index].TotalCore
+		freeCore += statusList[index].FreeCore
+	}
+
+	utilization.NodeMemory = 1 - (float64(currentScv.Status.FreeMemorySum) / float64(currentScv.Status.TotalMemorySum))
+	if totalCore > 0 {
+		utilization.NodeCore = 1 - (float64(freeCore) / float64(totalCore))
 	}
 
-	nodePercentage = 1 - (float64(currentScv.Status.FreeMemorySum) / float64(currentScv.Status.TotalMemorySum))
+	RecordNodeMetrics(node, currentScv, utilization)
 
-	return cardPercentage, nodePercentage
+	return utilization
 }
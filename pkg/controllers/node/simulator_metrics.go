@@ -0,0 +1,100 @@
+package node
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+	"time"
+)
+
+// GPU allocation failure reasons
+const (
+	AllocationInvalidRequest = "invalid_request"
+	AllocationNoCapacity     = "no_capacity"
+)
+
+var (
+	heartbeatLag = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "node_heartbeat_lag_seconds",
+		Help:      "Time since the previous heartbeat of a node when it is renewed, the update period plus the delay of the simulator.",
+	}, []string{"node"})
+	leaseRenewFailures = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "lease_renew_failures_total",
+		Help:      "Node leases that could not be created or renewed.",
+	})
+	podStatusPatchLatency = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "pod_status_patch_duration_seconds",
+		Help:      "Latency of the pod status patches of the simulator.",
+		Buckets:   prometheus.ExponentialBuckets(0.005, 2, 12),
+	})
+	scvPatchFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "scv_patch_failures_total",
+		Help:      "Scv patches that failed, by reason: conflict when the Scv changed meanwhile, retried or not, error otherwise.",
+	}, []string{"reason"})
+	gpuAllocationFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "gpu_allocation_failures_total",
+		Help:      "Pods whose GPUs could not be allocated, by reason.",
+	}, []string{"reason"})
+	podTimeToRunning = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "pod_time_to_running_seconds",
+		Help:      "Time from the creation of a pod to the simulator reporting it Running.",
+		Buckets:   prometheus.ExponentialBuckets(0.1, 2, 14),
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		heartbeatLag,
+		leaseRenewFailures,
+		podStatusPatchLatency,
+		scvPatchFailures,
+		gpuAllocationFailures,
+		podTimeToRunning,
+	)
+}
+
+// RecordHeartbeat records the time since the previous heartbeat of a node.
+func RecordHeartbeat(nodeName string, previous, now time.Time) {
+	if previous.IsZero() {
+		return
+	}
+	heartbeatLag.WithLabelValues(nodeName).Set(now.Sub(previous).Seconds())
+
+	exported.Lock()
+	defer exported.Unlock()
+	exported.heartbeats[nodeName] = true
+}
+
+// RecordLeaseRenewFailure counts a node lease that could not be created or renewed.
+func RecordLeaseRenewFailure() {
+	leaseRenewFailures.Inc()
+}
+
+// RecordPodStatusPatch records the latency of a pod status patch started at start.
+func RecordPodStatusPatch(start time.Time) {
+	podStatusPatchLatency.Observe(time.Since(start).Seconds())
+}
+
+// RecordScvPatchError counts a failed Scv patch.
+func RecordScvPatchError(err error) {
+	reason := "error"
+	if IsScvConflict(err) {
+		reason = "conflict"
+	}
+	scvPatchFailures.WithLabelValues(reason).Inc()
+}
+
+// RecordGPUAllocationFailure counts a pod whose GPUs could not be allocated.
+func RecordGPUAllocationFailure(reason string) {
+	gpuAllocationFailures.WithLabelValues(reason).Inc()
+}
+
+// RecordPodRunning records the time a pod created at created took to be reported Running.
+func RecordPodRunning(created, now time.Time) {
+	podTimeToRunning.Observe(now.Sub(created).Seconds())
+}
@@ -0,0 +1,111 @@
+package node
+
+import (
+	"fmt"
+	simv1 "github.com/NJUPT-ISL/NodeSimulator/pkg/api/v1"
+)
+
+// Link levels between two cards, from the loosest to the tightest connection.
+const (
+	LinkCrossSocket = iota
+	LinkSameSocket
+	LinkPCIeSwitch
+	LinkNVLink
+)
+
+// ValidateGPUTopology checks that the topology only refers to existing cards.
+func ValidateGPUTopology(topology simv1.GPUTopology, number int) error {
+	for name, groups := range map[string][][]int{
+		"nvlinks":      topology.NVLinks,
+		"pcieSwitches": topology.PCIeSwitches,
+		"sockets":      topology.Sockets,
+	} {
+		for _, group := range groups {
+			for _, id := range group {
+				if id < 0 || id >= number {
+					return fmt.Errorf("topology %v refers to card %d, the node has %d cards", name, id, number)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// ApplyGPUTopology records the topology in the status of the cards.
+func ApplyGPUTopology(statusList CardStatusList, topology simv1.GPUTopology) {
+	for _, group := range topology.NVLinks {
+		for _, a := range group {
+			for _, b := range group {
+				if a != b && a < len(statusList) && !containsCard(statusList[a].NVLinks, uint(b)) {
+					statusList[a].NVLinks = append(statusList[a].NVLinks, uint(b))
+				}
+			}
+		}
+	}
+	for index, group := range topology.PCIeSwitches {
+		for _, id := range group {
+			if id < len(statusList) {
+				statusList[id].PCIeSwitch = index
+			}
+		}
+	}
+	for index, group := range topology.Sockets {
+		for _, id := range group {
+			if id < len(statusList) {
+				statusList[id].Socket = index
+			}
+		}
+	}
+}
+
+// HasTopology reports whether any topology is declared for the cards.
+func HasTopology(statusList CardStatusList) bool {
+	for _, status := range statusList {
+		if len(status.NVLinks) > 0 || status.PCIeSwitch >= 0 || status.Socket >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// LinkLevel returns how tightly two cards are connected.
+func LinkLevel(a, b CardStatus) int {
+	switch {
+	case containsCard(a.NVLinks, b.ID):
+		return LinkNVLink
+	case a.PCIeSwitch >= 0 && a.PCIeSwitch == b.PCIeSwitch:
+		return LinkPCIeSwitch
+	case a.Socket >= 0 && a.Socket == b.Socket:
+		return LinkSameSocket
+	default:
+		return LinkCrossSocket
+	}
+}
+
+// TopologyScore rates a set of cards between 0 and 1 by the mean link level of
+// every pair of them. A single card scores 1.
+func TopologyScore(statusList CardStatusList, ids []int) float64 {
+	pairs, sum := 0, 0
+	for i := 0; i < len(ids); i++ {
+		for j := i + 1; j < len(ids); j++ {
+			if ids[i] >= len(statusList) || ids[j] >= len(statusList) {
+				continue
+			}
+			sum += LinkLevel(statusList[ids[i]], statusList[ids[j]])
+			pairs++
+		}
+	}
+	if pairs == 0 {
+		return 1
+	}
+	return float64(sum) / float64(pairs*LinkNVLink)
+}
+
+func containsCard(ids []uint, id uint) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
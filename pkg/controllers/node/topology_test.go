@@ -0,0 +1,49 @@
+package node
+
+import (
+	simv1 "github.com/NJUPT-ISL/NodeSimulator/pkg/api/v1"
+	scv1 "github.com/NJUPT-ISL/SCV/api/v1"
+	"math"
+	"testing"
+)
+
+// testStatusList returns the status of number idle cards with the given topology.
+func testStatusList(number int, topology simv1.GPUTopology) CardStatusList {
+	statusList := make(CardStatusList, 0, number)
+	for i := 0; i < number; i++ {
+		statusList = append(statusList, NewCardStatus(scv1.Card{ID: uint(i)}))
+	}
+	ApplyGPUTopology(statusList, topology)
+	return statusList
+}
+
+func TestTopologyScore(t *testing.T) {
+	// Two NVLink pairs behind one PCIe switch per socket
+	topology := simv1.GPUTopology{
+		NVLinks:      [][]int{{0, 1}, {2, 3}},
+		PCIeSwitches: [][]int{{0, 1, 2, 3}, {4, 5, 6, 7}},
+		Sockets:      [][]int{{0, 1, 2, 3}, {4, 5, 6, 7}},
+	}
+	tests := []struct {
+		name       string
+		statusList CardStatusList
+		ids        []int
+		want       float64
+	}{
+		{"single card", testStatusList(8, topology), []int{5}, 1},
+		{"NVLink pair", testStatusList(8, topology), []int{0, 1}, 1},
+		{"same PCIe switch", testStatusList(8, topology), []int{0, 2}, 2.0 / 3},
+		{"no NVLink in the group", testStatusList(8, topology), []int{4, 5}, 2.0 / 3},
+		{"cross socket", testStatusList(8, topology), []int{0, 4}, 0},
+		{"two NVLink pairs", testStatusList(8, topology), []int{0, 1, 2, 3}, 14.0 / 18},
+		{"unknown card ignored", testStatusList(8, topology), []int{0, 1, 9}, 1},
+		{"no topology", testStatusList(8, simv1.GPUTopology{}), []int{0, 1}, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := TopologyScore(test.statusList, test.ids); math.Abs(got-test.want) > 1e-9 {
+				t.Errorf("TopologyScore(%v) = %v, want %v", test.ids, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,41 @@
+package node
+
+import (
+	"encoding/json"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/klog"
+)
+
+// EvictionMemoryThreshold is the available memory below which a node is under
+// memory pressure and evicts pods, like the default memory.available<100Mi of the kubelet.
+var EvictionMemoryThreshold = resource.MustParse("100Mi")
+
+// GetNodeUsage returns the usage recorded in the NodeUsageAnnotation of the node.
+func GetNodeUsage(node *v1.Node) (v1.ResourceList, bool) {
+	value, ok := node.GetAnnotations()[NodeUsageAnnotation]
+	if !ok {
+		return nil, false
+	}
+	usage := v1.ResourceList{}
+	if err := json.Unmarshal([]byte(value), &usage); err != nil {
+		klog.Errorf("Node: %v Invalid %v Annotation: %v", node.GetName(), NodeUsageAnnotation, err)
+		return nil, false
+	}
+	return usage, true
+}
+
+// MemoryAvailable returns the memory of the node its pods do not use. Like the
+// memory.available signal of the kubelet, it is measured against the capacity, of
+// which the allocatable memory already leaves out EvictionMemoryThreshold.
+func MemoryAvailable(node *v1.Node, usage v1.ResourceList) resource.Quantity {
+	available := node.Status.Capacity.Memory().DeepCopy()
+	available.Sub(*usage.Memory())
+	return available
+}
+
+// UnderMemoryPressure reports whether the available memory of the node is below EvictionMemoryThreshold.
+func UnderMemoryPressure(node *v1.Node, usage v1.ResourceList) bool {
+	available := MemoryAvailable(node, usage)
+	return available.Cmp(EvictionMemoryThreshold) < 0
+}
@@ -0,0 +1,62 @@
+package node
+
+import (
+	simv1 "github.com/NJUPT-ISL/NodeSimulator/pkg/api/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"testing"
+)
+
+func TestUnderMemoryPressure(t *testing.T) {
+	node, err := GenNode(&simv1.NodeSimulator{Spec: simv1.NodeSimulatorSpec{
+		Cpu:       "8",
+		Memory:    "8Gi",
+		PodNumber: "110",
+		Disk:      "100Gi",
+		Bandwidth: "10G",
+	}})
+	if err != nil {
+		t.Fatalf("GenNode() error: %v", err)
+	}
+	allocatable := node.Status.Allocatable.Memory()
+	if want := resource.MustParse("8092Mi"); allocatable.Cmp(want) != 0 {
+		t.Fatalf("allocatable memory = %v, want %v", allocatable.String(), want.String())
+	}
+
+	tests := []struct {
+		name  string
+		usage resource.Quantity
+		want  bool
+	}{
+		{
+			name:  "no usage",
+			usage: resource.MustParse("0"),
+		},
+		{
+			name:  "half used",
+			usage: resource.MustParse("4Gi"),
+		},
+		{
+			name:  "packed to allocatable",
+			usage: *allocatable,
+		},
+		{
+			name:  "less than the threshold left",
+			usage: resource.MustParse("8100Mi"),
+			want:  true,
+		},
+		{
+			name:  "more used than capacity",
+			usage: resource.MustParse("9Gi"),
+			want:  true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			usage := v1.ResourceList{v1.ResourceMemory: test.usage}
+			if got := UnderMemoryPressure(node, usage); got != test.want {
+				t.Errorf("UnderMemoryPressure(%v) = %v, want %v", test.usage.String(), got, test.want)
+			}
+		})
+	}
+}
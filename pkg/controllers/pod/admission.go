@@ -0,0 +1,246 @@
+package pod
+
+import (
+	"fmt"
+	nodecontroller "github.com/NJUPT-ISL/NodeSimulator/pkg/controllers/node"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+)
+
+// Admission failure reasons of the kubelet
+const (
+	nodeAffinityPredicate    = "NodeAffinity"
+	taintTolerationPredicate = "TaintToleration"
+	nodePortsPredicate       = "NodePorts"
+	outOfResourcePrefix      = "OutOf"
+)
+
+// admitPod checks the pod against the node like the kubelet does before running it:
+// the node has to satisfy its node selector and required node affinity, it has to
+// tolerate the NoExecute taints of the node, its host ports have to be free, and
+// its requests have to fit the allocatable resources left by the pods already
+// admitted. CPU, memory and pods are always checked, other resources when the node
+// advertises them, nvidia.com/gpu counting as gpu/number. It returns the reason and
+// message the kubelet rejects the pod with.
+func admitPod(pod *v1.Pod, node *v1.Node, admitted []v1.Pod) (string, string, bool) {
+	if !matchesNodeSelector(pod, node) {
+		return nodeAffinityPredicate, predicateFailure(nodeAffinityPredicate), false
+	}
+	for i := range node.Spec.Taints {
+		taint := &node.Spec.Taints[i]
+		if taint.Effect == v1.TaintEffectNoExecute && !toleratesTaint(pod.Spec.Tolerations, taint) {
+			return taintTolerationPredicate, predicateFailure(taintTolerationPredicate), false
+		}
+	}
+
+	usedPorts := make([]v1.ContainerPort, 0)
+	for i := range admitted {
+		usedPorts = append(usedPorts, hostPorts(&admitted[i])...)
+	}
+	for _, port := range hostPorts(pod) {
+		for _, used := range usedPorts {
+			if portsConflict(port, used) {
+				return nodePortsPredicate, predicateFailure(nodePortsPredicate), false
+			}
+		}
+	}
+
+	allocatable := node.Status.Allocatable
+	if podNumber, ok := allocatable[v1.ResourcePods]; ok && int64(len(admitted))+1 > podNumber.Value() {
+		return outOfResourcePrefix + string(v1.ResourcePods), insufficientResource(v1.ResourcePods, 1, int64(len(admitted)), podNumber.Value()), false
+	}
+
+	requested := podRequests(pod)
+	used := v1.ResourceList{}
+	for i := range admitted {
+		addResources(used, podRequests(&admitted[i]))
+	}
+	for _, name := range admissionResources(node, requested) {
+		request := requested[name]
+		if request.IsZero() {
+			continue
+		}
+		capacity, inUse := allocatable[name], used[name]
+		left := capacity.DeepCopy()
+		left.Sub(inUse)
+		if request.Cmp(left) > 0 {
+			return outOfResourcePrefix + string(name), insufficientResource(name, quantityValue(name, request), quantityValue(name, inUse), quantityValue(name, capacity)), false
+		}
+	}
+	return "", "", true
+}
+
+// admissionResources returns the requested resources to check against the node.
+func admissionResources(node *v1.Node, requested v1.ResourceList) []v1.ResourceName {
+	names := []v1.ResourceName{v1.ResourceCPU, v1.ResourceMemory}
+	for name := range requested {
+		if name == v1.ResourceCPU || name == v1.ResourceMemory {
+			continue
+		}
+		if _, ok := node.Status.Capacity[name]; ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// podRequests returns the effective requests of the pod, with its overhead.
+func podRequests(pod *v1.Pod) v1.ResourceList {
+	names := make(map[v1.ResourceName]bool)
+	for _, containers := range [][]v1.Container{pod.Spec.Containers, pod.Spec.InitContainers} {
+		for _, container := range containers {
+			for _, list := range []v1.ResourceList{container.Resources.Requests, container.Resources.Limits} {
+				for name := range list {
+					names[name] = true
+				}
+			}
+		}
+	}
+
+	requests := v1.ResourceList{}
+	for name := range names {
+		q, err := nodecontroller.EffectiveRequest(pod, name)
+		if err != nil || q == nil {
+			continue
+		}
+		if name == ResourceNvidiaGPU {
+			name = ResourceGPUNumber
+			if number, ok := requests[name]; ok && number.Cmp(*q) >= 0 {
+				continue
+			}
+		}
+		requests[name] = q.DeepCopy()
+	}
+	addResources(requests, pod.Spec.Overhead)
+	return requests
+}
+
+// quantityValue returns the value the kubelet reports for a resource: millicores for CPU.
+func quantityValue(name v1.ResourceName, q resource.Quantity) int64 {
+	if name == v1.ResourceCPU {
+		return q.MilliValue()
+	}
+	return q.Value()
+}
+
+func predicateFailure(predicate string) string {
+	return fmt.Sprintf("Predicate %s failed", predicate)
+}
+
+func insufficientResource(name v1.ResourceName, requested, used, capacity int64) string {
+	return fmt.Sprintf("Node didn't have enough resource: %s, requested: %d, used: %d, capacity: %d", name, requested, used, capacity)
+}
+
+// matchesNodeSelector reports whether the node satisfies the node selector and the
+// required node affinity of the pod.
+func matchesNodeSelector(pod *v1.Pod, node *v1.Node) bool {
+	if !labels.SelectorFromSet(pod.Spec.NodeSelector).Matches(labels.Set(node.GetLabels())) {
+		return false
+	}
+	affinity := pod.Spec.Affinity
+	if affinity == nil || affinity.NodeAffinity == nil || affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		return true
+	}
+	// The terms are ORed, an empty list matches no node.
+	for _, term := range affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms {
+		if matchesNodeSelectorTerm(term, node) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesNodeSelectorTerm reports whether the node satisfies all requirements of a
+// term, which has to have at least one.
+func matchesNodeSelectorTerm(term v1.NodeSelectorTerm, node *v1.Node) bool {
+	if len(term.MatchExpressions) == 0 && len(term.MatchFields) == 0 {
+		return false
+	}
+	for _, expression := range term.MatchExpressions {
+		requirement, err := nodeSelectorRequirement(expression)
+		if err != nil || !requirement.Matches(labels.Set(node.GetLabels())) {
+			return false
+		}
+	}
+	for _, field := range term.MatchFields {
+		if field.Key != "metadata.name" {
+			return false
+		}
+		requirement, err := nodeSelectorRequirement(field)
+		if err != nil || !requirement.Matches(labels.Set{field.Key: node.GetName()}) {
+			return false
+		}
+	}
+	return true
+}
+
+func nodeSelectorRequirement(expression v1.NodeSelectorRequirement) (*labels.Requirement, error) {
+	var op selection.Operator
+	switch expression.Operator {
+	case v1.NodeSelectorOpIn:
+		op = selection.In
+	case v1.NodeSelectorOpNotIn:
+		op = selection.NotIn
+	case v1.NodeSelectorOpExists:
+		op = selection.Exists
+	case v1.NodeSelectorOpDoesNotExist:
+		op = selection.DoesNotExist
+	case v1.NodeSelectorOpGt:
+		op = selection.GreaterThan
+	case v1.NodeSelectorOpLt:
+		op = selection.LessThan
+	default:
+		return nil, fmt.Errorf("invalid node selector operator %q", expression.Operator)
+	}
+	return labels.NewRequirement(expression.Key, op, expression.Values)
+}
+
+func toleratesTaint(tolerations []v1.Toleration, taint *v1.Taint) bool {
+	for i := range tolerations {
+		if tolerations[i].ToleratesTaint(taint) {
+			return true
+		}
+	}
+	return false
+}
+
+// hostPorts returns the host ports the pod binds, with the default protocol and IP.
+func hostPorts(pod *v1.Pod) []v1.ContainerPort {
+	ports := make([]v1.ContainerPort, 0)
+	for _, container := range pod.Spec.Containers {
+		for _, port := range container.Ports {
+			if port.HostPort <= 0 {
+				continue
+			}
+			if port.Protocol == "" {
+				port.Protocol = v1.ProtocolTCP
+			}
+			if port.HostIP == "" {
+				port.HostIP = "0.0.0.0"
+			}
+			ports = append(ports, port)
+		}
+	}
+	return ports
+}
+
+// portsConflict reports whether two host ports cannot be bound together.
+func portsConflict(a, b v1.ContainerPort) bool {
+	if a.HostPort != b.HostPort || a.Protocol != b.Protocol {
+		return false
+	}
+	return a.HostIP == b.HostIP || a.HostIP == "0.0.0.0" || b.HostIP == "0.0.0.0"
+}
+
+// admittedPods returns the pods of the node the simulator already started and that still run.
+func admittedPods(pod *v1.Pod, pods []v1.Pod) []v1.Pod {
+	admitted := make([]v1.Pod, 0)
+	for _, item := range pods {
+		if item.Spec.NodeName == pod.Spec.NodeName && item.GetUID() != pod.GetUID() && item.Status.StartTime != nil && !isTerminated(&item) {
+			admitted = append(admitted, item)
+		}
+	}
+	return admitted
+}
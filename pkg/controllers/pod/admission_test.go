@@ -0,0 +1,165 @@
+package pod
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+// testPod returns a pod with one container requesting the resources.
+func testPod(requests v1.ResourceList, ports ...v1.ContainerPort) v1.Pod {
+	container := testContainer(requests, nil)
+	container.Ports = ports
+	return v1.Pod{Spec: v1.PodSpec{Containers: []v1.Container{container}}}
+}
+
+func TestAdmitPod(t *testing.T) {
+	resources := v1.ResourceList{
+		v1.ResourceCPU:    resource.MustParse("4"),
+		v1.ResourceMemory: resource.MustParse("8Gi"),
+		v1.ResourcePods:   resource.MustParse("3"),
+		ResourceGPUNumber: resource.MustParse("2"),
+	}
+	node := &v1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-0", Labels: map[string]string{"zone": "a"}},
+		Status:     v1.NodeStatus{Capacity: resources, Allocatable: resources},
+	}
+	tainted := node.DeepCopy()
+	tainted.Spec.Taints = []v1.Taint{
+		{Key: "dedicated", Value: "gpu", Effect: v1.TaintEffectNoExecute},
+		{Key: "maintenance", Effect: v1.TaintEffectNoSchedule},
+	}
+	web := v1.ContainerPort{ContainerPort: 80, HostPort: 8080}
+
+	tests := []struct {
+		name        string
+		pod         v1.Pod
+		node        *v1.Node
+		admitted    []v1.Pod
+		wantReason  string
+		wantMessage string
+	}{
+		{
+			name:     "fits",
+			pod:      testPod(testResources("2", "4Gi")),
+			node:     node,
+			admitted: []v1.Pod{testPod(testResources("2", "4Gi"))},
+		},
+		{
+			name: "node selector not matched",
+			pod: func() v1.Pod {
+				pod := testPod(nil)
+				pod.Spec.NodeSelector = map[string]string{"zone": "b"}
+				return pod
+			}(),
+			node:        node,
+			wantReason:  "NodeAffinity",
+			wantMessage: "Predicate NodeAffinity failed",
+		},
+		{
+			name: "required node affinity matched by name",
+			pod: func() v1.Pod {
+				pod := testPod(nil)
+				pod.Spec.Affinity = &v1.Affinity{NodeAffinity: &v1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{NodeSelectorTerms: []v1.NodeSelectorTerm{
+						{MatchExpressions: []v1.NodeSelectorRequirement{{Key: "zone", Operator: v1.NodeSelectorOpIn, Values: []string{"b"}}}},
+						{MatchFields: []v1.NodeSelectorRequirement{{Key: "metadata.name", Operator: v1.NodeSelectorOpIn, Values: []string{"node-0"}}}},
+					}},
+				}}
+				return pod
+			}(),
+			node: node,
+		},
+		{
+			name: "required node affinity not matched",
+			pod: func() v1.Pod {
+				pod := testPod(nil)
+				pod.Spec.Affinity = &v1.Affinity{NodeAffinity: &v1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &v1.NodeSelector{NodeSelectorTerms: []v1.NodeSelectorTerm{
+						{MatchExpressions: []v1.NodeSelectorRequirement{{Key: "zone", Operator: v1.NodeSelectorOpNotIn, Values: []string{"a"}}}},
+					}},
+				}}
+				return pod
+			}(),
+			node:        node,
+			wantReason:  "NodeAffinity",
+			wantMessage: "Predicate NodeAffinity failed",
+		},
+		{
+			name:        "NoExecute taint not tolerated",
+			pod:         testPod(nil),
+			node:        tainted,
+			wantReason:  "TaintToleration",
+			wantMessage: "Predicate TaintToleration failed",
+		},
+		{
+			name: "NoExecute taint tolerated",
+			pod: func() v1.Pod {
+				pod := testPod(nil)
+				pod.Spec.Tolerations = []v1.Toleration{{Key: "dedicated", Operator: v1.TolerationOpExists}}
+				return pod
+			}(),
+			node: tainted,
+		},
+		{
+			name:        "host port in use",
+			pod:         testPod(nil, web),
+			node:        node,
+			admitted:    []v1.Pod{testPod(nil, v1.ContainerPort{ContainerPort: 80, HostPort: 8080, HostIP: "10.0.0.1"})},
+			wantReason:  "NodePorts",
+			wantMessage: "Predicate NodePorts failed",
+		},
+		{
+			name:     "host port of another protocol",
+			pod:      testPod(nil, web),
+			node:     node,
+			admitted: []v1.Pod{testPod(nil, v1.ContainerPort{ContainerPort: 80, HostPort: 8080, Protocol: v1.ProtocolUDP})},
+		},
+		{
+			name:        "too many pods",
+			pod:         testPod(nil),
+			node:        node,
+			admitted:    []v1.Pod{testPod(nil), testPod(nil), testPod(nil)},
+			wantReason:  "OutOfpods",
+			wantMessage: "Node didn't have enough resource: pods, requested: 1, used: 3, capacity: 3",
+		},
+		{
+			name:        "out of CPU",
+			pod:         testPod(testResources("2500m", "")),
+			node:        node,
+			admitted:    []v1.Pod{testPod(testResources("2", "1Gi"))},
+			wantReason:  "OutOfcpu",
+			wantMessage: "Node didn't have enough resource: cpu, requested: 2500, used: 2000, capacity: 4000",
+		},
+		{
+			name:        "out of memory",
+			pod:         testPod(testResources("", "5Gi")),
+			node:        node,
+			admitted:    []v1.Pod{testPod(testResources("", "4Gi"))},
+			wantReason:  "OutOfmemory",
+			wantMessage: "Node didn't have enough resource: memory, requested: 5368709120, used: 4294967296, capacity: 8589934592",
+		},
+		{
+			name:        "nvidia.com/gpu counted as gpu/number",
+			pod:         testPod(v1.ResourceList{ResourceNvidiaGPU: resource.MustParse("2")}),
+			node:        node,
+			admitted:    []v1.Pod{testPod(v1.ResourceList{ResourceGPUNumber: resource.MustParse("1")})},
+			wantReason:  "OutOfgpu/number",
+			wantMessage: "Node didn't have enough resource: gpu/number, requested: 2, used: 1, capacity: 2",
+		},
+		{
+			name: "resource the node does not advertise",
+			pod:  testPod(v1.ResourceList{"example.com/fpga": resource.MustParse("1")}),
+			node: node,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reason, message, ok := admitPod(&test.pod, test.node, test.admitted)
+			if ok != (test.wantReason == "") || reason != test.wantReason || message != test.wantMessage {
+				t.Errorf("admitPod() = %q, %q, %v, want %q, %q", reason, message, ok, test.wantReason, test.wantMessage)
+			}
+		})
+	}
+}
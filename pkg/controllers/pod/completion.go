@@ -0,0 +1,87 @@
+package pod
+
+import (
+	"encoding/json"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+	"strconv"
+	"time"
+)
+
+// jobCompletionIndexAnnotation is set by the Job controller on the pods of indexed Jobs.
+const jobCompletionIndexAnnotation = "batch.kubernetes.io/job-completion-index"
+
+// runDuration returns how long the containers of the pod run before they exit: the
+// duration of its completion index in the IndexDurationsAnnotation, else its
+// DurationAnnotation. Pods without one run until deleted.
+func runDuration(pod *v1.Pod) (time.Duration, bool) {
+	annotations := pod.GetAnnotations()
+	value, ok := annotations[DurationAnnotation]
+	if durations, found := annotations[IndexDurationsAnnotation]; found {
+		if index, err := strconv.Atoi(annotations[jobCompletionIndexAnnotation]); err == nil && index >= 0 {
+			list := make([]string, 0)
+			if err := json.Unmarshal([]byte(durations), &list); err != nil {
+				klog.Errorf("Pod: %v/%v Invalid %v Annotation: %v", pod.GetNamespace(), pod.GetName(), IndexDurationsAnnotation, err)
+			} else if index < len(list) {
+				value, ok = list[index], true
+			}
+		}
+	}
+	if !ok {
+		return 0, false
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		klog.Errorf("Pod: %v/%v Invalid Run Duration: %v", pod.GetNamespace(), pod.GetName(), value)
+		return 0, false
+	}
+	return duration, true
+}
+
+// failureProbability returns the probability in the FailureProbabilityAnnotation of the pod.
+func failureProbability(pod *v1.Pod) float64 {
+	value, ok := pod.GetAnnotations()[FailureProbabilityAnnotation]
+	if !ok {
+		return 0
+	}
+	probability, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		klog.Errorf("Pod: %v/%v Invalid %v Annotation: %v", pod.GetNamespace(), pod.GetName(), FailureProbabilityAnnotation, value)
+		return 0
+	}
+	return probability
+}
+
+// completeContainers terminates the containers of the pod that ran for its run
+// duration: all of them succeed, or with the failure probability of the pod, all
+// of them fail. The restart policy then decides whether the run is retried.
+func completeContainers(pod *v1.Pod, status *v1.PodStatus, now metav1.Time) {
+	duration, ok := runDuration(pod)
+	if !ok {
+		return
+	}
+	done := make([]*v1.ContainerStatus, 0)
+	for i := range status.ContainerStatuses {
+		running := status.ContainerStatuses[i].State.Running
+		if running != nil && util.Elapsed(running.StartedAt.Time, now.Time) >= duration {
+			done = append(done, &status.ContainerStatuses[i])
+		}
+	}
+	if len(done) == 0 {
+		return
+	}
+
+	failed := false
+	if probability := failureProbability(pod); probability > 0 {
+		failed = chance(pod, probability, "completion", done[0].Name, strconv.Itoa(int(done[0].RestartCount)))
+	}
+	for _, containerStatus := range done {
+		if failed {
+			terminateContainer(containerStatus, ErrorReason, crashExitCode, "", now)
+		} else {
+			terminateContainer(containerStatus, CompletedReason, 0, "", now)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package pod
+
+import (
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+	"time"
+)
+
+func TestRunDuration(t *testing.T) {
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		want        time.Duration
+		wantOK      bool
+	}{
+		{
+			name: "runs until deleted",
+		},
+		{
+			name:        "duration",
+			annotations: map[string]string{DurationAnnotation: "30m"},
+			want:        30 * time.Minute,
+			wantOK:      true,
+		},
+		{
+			name:        "invalid duration",
+			annotations: map[string]string{DurationAnnotation: "long"},
+		},
+		{
+			name:        "negative duration",
+			annotations: map[string]string{DurationAnnotation: "-1m"},
+		},
+		{
+			name: "duration of the completion index",
+			annotations: map[string]string{
+				DurationAnnotation:           "30m",
+				IndexDurationsAnnotation:     `["1m", "2m", "3m"]`,
+				jobCompletionIndexAnnotation: "1",
+			},
+			want:   2 * time.Minute,
+			wantOK: true,
+		},
+		{
+			name: "index beyond the durations",
+			annotations: map[string]string{
+				DurationAnnotation:           "30m",
+				IndexDurationsAnnotation:     `["1m"]`,
+				jobCompletionIndexAnnotation: "3",
+			},
+			want:   30 * time.Minute,
+			wantOK: true,
+		},
+		{
+			name: "not an indexed pod",
+			annotations: map[string]string{
+				IndexDurationsAnnotation: `["1m"]`,
+			},
+		},
+		{
+			name: "invalid durations",
+			annotations: map[string]string{
+				DurationAnnotation:           "30m",
+				IndexDurationsAnnotation:     `1m`,
+				jobCompletionIndexAnnotation: "0",
+			},
+			want:   30 * time.Minute,
+			wantOK: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: test.annotations}}
+			got, ok := runDuration(pod)
+			if got != test.want || ok != test.wantOK {
+				t.Errorf("runDuration() = %v, %v, want %v, %v", got, ok, test.want, test.wantOK)
+			}
+		})
+	}
+}
+
+func TestCompleteContainersFastClock(t *testing.T) {
+	epoch := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	util.SetClock(util.NewVirtualClockAt(epoch, epoch, 60))
+	defer util.SetClock(util.NewVirtualClock(1))
+
+	started := metav1.NewTime(epoch.Add(time.Minute))
+	tests := []struct {
+		name string
+		now  time.Time
+		done bool
+	}{
+		{
+			name: "29 simulated minutes",
+			now:  started.Add(29 * time.Second),
+		},
+		{
+			name: "30 simulated minutes",
+			now:  started.Add(30 * time.Second),
+			done: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pod := &v1.Pod{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{DurationAnnotation: "30m"}}}
+			status := &v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{{
+				Name:  "app",
+				State: v1.ContainerState{Running: &v1.ContainerStateRunning{StartedAt: started}},
+			}}}
+			now := metav1.NewTime(test.now)
+			completeContainers(pod, status, now)
+
+			terminated := status.ContainerStatuses[0].State.Terminated
+			if (terminated != nil) != test.done {
+				t.Fatalf("container terminated = %v, want %v", terminated != nil, test.done)
+			}
+			// The status keeps the wall clock times
+			if terminated != nil && (!terminated.StartedAt.Equal(&started) || !terminated.FinishedAt.Equal(&now)) {
+				t.Errorf("terminated at %v-%v, want %v-%v", terminated.StartedAt, terminated.FinishedAt, started, now)
+			}
+		})
+	}
+}
@@ -1,3 +1,83 @@
 package pod
 
-const scheduleGPUID = "scheduleGPUID"
+import nodecontroller "github.com/NJUPT-ISL/NodeSimulator/pkg/controllers/node"
+
+const (
+	scheduleGPUID   = nodecontroller.ScheduleGPUID
+	scheduleMIGID   = nodecontroller.ScheduleMIGID
+	nativeScheduler = "native-scheduler"
+
+	// GPU request labels
+	scvMemory = nodecontroller.ScvMemory
+	scvNumber = nodecontroller.ScvNumber
+	scvCore   = nodecontroller.ScvCore
+
+	// GPU extended resources
+	ResourceGPUNumber  = nodecontroller.ResourceGPUNumber
+	ResourceGPUMemory  = nodecontroller.ResourceGPUMemory
+	ResourceGPUCore    = nodecontroller.ResourceGPUCore
+	ResourceGPUCompute = nodecontroller.ResourceGPUCompute
+	ResourceNvidiaGPU  = nodecontroller.ResourceNvidiaGPU
+
+	// TopologyScoreAnnotation reports the topology score of the cards of a pod
+	TopologyScoreAnnotation = "sim.k8s.io/gpu-topology-score"
+
+	// GPULoadAnnotation holds the JSON load profile of the GPU work of a pod,
+	// e.g. {"type": "periodic", "level": 0.5, "amplitude": 0.3, "period": "10m"}
+	GPULoadAnnotation = "sim.k8s.io/gpu-load"
+
+	// CPUUsageAnnotation and MemoryUsageAnnotation hold the JSON load profiles of the
+	// CPU and memory usage of a pod, as a fraction of the requests of its containers,
+	// e.g. {"type": "periodic", "level": 0.5, "amplitude": 0.3, "period": "10m"}
+	CPUUsageAnnotation    = "sim.k8s.io/cpu-usage"
+	MemoryUsageAnnotation = "sim.k8s.io/memory-usage"
+
+	// CrashProbabilityAnnotation holds the probability that a running container crashes
+	// within a minute, by container name or "*" for all, e.g. {"app": 0.05}
+	CrashProbabilityAnnotation = "sim.k8s.io/crash-probability"
+
+	// InitContainersAnnotation holds how long each init container runs and the probability
+	// that a run fails, by container name or "*" for all,
+	// e.g. {"migrate": {"duration": "30s", "failureProbability": 0.1}}
+	InitContainersAnnotation = "sim.k8s.io/init-containers"
+
+	// SidecarsAnnotation lists the init containers to run as sidecars, e.g. "istio-proxy,log".
+	// Init containers with restartPolicy Always are sidecars without it on Kubernetes 1.28+.
+	SidecarsAnnotation = nodecontroller.SidecarsAnnotation
+
+	// ProbesAnnotation holds how the probes of each container respond, by container name
+	// or "*" for all, e.g. {"app": {"startupDuration": "45s", "readinessFailureProbability": 0.05}}
+	ProbesAnnotation = "sim.k8s.io/probes"
+
+	// ShutdownDurationAnnotation is how long the containers of a pod take to exit once
+	// told to stop, and PreStopDurationAnnotation how long their preStop hooks run
+	// before, e.g. "20s". Both are bounded by the termination grace period.
+	ShutdownDurationAnnotation = "sim.k8s.io/shutdown-duration"
+	PreStopDurationAnnotation  = "sim.k8s.io/prestop-duration"
+
+	// DurationAnnotation is how long the containers of a pod run before they exit, e.g.
+	// "5m" in the pod template of a Job, and FailureProbabilityAnnotation the probability
+	// that a run fails, e.g. "0.1". IndexDurationsAnnotation lists the durations of the
+	// pods of an indexed Job by completion index, e.g. ["5m", "10m", "2m"].
+	DurationAnnotation           = "sim.k8s.io/duration"
+	FailureProbabilityAnnotation = "sim.k8s.io/failure-probability"
+	IndexDurationsAnnotation     = "sim.k8s.io/index-durations"
+
+	// Container termination and waiting reasons
+	OOMKilledReason        = "OOMKilled"
+	ErrorReason            = "Error"
+	CompletedReason        = "Completed"
+	CrashLoopBackOffReason = "CrashLoopBackOff"
+	PodInitializingReason  = "PodInitializing"
+
+	// Event reasons
+	InvalidGPURequestReason = "InvalidGPURequest"
+	GPUUnhealthyReason      = "GPUUnhealthy"
+	EvictedReason           = "Evicted"
+	BackOffReason           = "BackOff"
+	UnhealthyReason         = "Unhealthy"
+	KillingReason           = "Killing"
+
+	gpuIDSeparator = nodecontroller.GPUIDSeparator
+	migIDSeparator = nodecontroller.MIGIDSeparator
+)
@@ -0,0 +1,173 @@
+package pod
+
+import (
+	"fmt"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"time"
+)
+
+const (
+	// Exit codes of a container killed for running out of memory, of one killed by
+	// the kubelet, e.g. after its grace period, and of a crashed one
+	oomExitCode    = 137
+	killedExitCode = 137
+	crashExitCode  = 1
+
+	// Restart back-off of the kubelet: doubling from 10s up to 5m, reset after the
+	// container ran for twice the maximum.
+	initialBackOff = 10 * time.Second
+	maxBackOff     = 300 * time.Second
+	backOffReset   = 2 * maxBackOff
+
+	containersNotReadyReason = "ContainersNotReady"
+)
+
+// terminateContainer marks a running container terminated at now.
+func terminateContainer(status *v1.ContainerStatus, reason string, exitCode int32, message string, now metav1.Time) {
+	terminated := &v1.ContainerStateTerminated{
+		ExitCode:    exitCode,
+		Reason:      reason,
+		Message:     message,
+		FinishedAt:  now,
+		ContainerID: status.ContainerID,
+	}
+	if status.State.Running != nil {
+		terminated.StartedAt = status.State.Running.StartedAt
+	}
+	started := false
+	status.State = v1.ContainerState{Terminated: terminated}
+	status.Ready = false
+	status.Started = &started
+}
+
+// shouldRestart reports whether the restart policy restarts a container that exited with exitCode.
+func shouldRestart(policy v1.RestartPolicy, exitCode int32) bool {
+	switch policy {
+	case v1.RestartPolicyNever:
+		return false
+	case v1.RestartPolicyOnFailure:
+		return exitCode != 0
+	default:
+		return true
+	}
+}
+
+// restartDelay returns the back-off before restarting a container that terminated after running for ran.
+func restartDelay(restartCount int32, ran time.Duration) time.Duration {
+	if ran >= backOffReset {
+		return initialBackOff
+	}
+	delay := initialBackOff
+	for i := int32(0); i < restartCount && delay < maxBackOff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackOff {
+		delay = maxBackOff
+	}
+	return delay
+}
+
+// syncContainerRestarts moves terminated containers the restart policy restarts into
+// CrashLoopBackOff, and restarts the containers whose back-off is over. Failed init
+// containers are retried unless the policy is Never, sidecars always restart. It
+// returns the names of the containers that entered back-off.
+func syncContainerRestarts(pod *v1.Pod, status *v1.PodStatus, sidecars map[string]bool, now metav1.Time) []string {
+	backOff := restartContainers(pod, status.InitContainerStatuses, func(name string, exitCode int32) bool {
+		return sidecars[name] || (exitCode != 0 && pod.Spec.RestartPolicy != v1.RestartPolicyNever)
+	}, now)
+	for i := range status.InitContainerStatuses {
+		// Regular init containers are only ready once completed.
+		if !sidecars[status.InitContainerStatuses[i].Name] && status.InitContainerStatuses[i].State.Running != nil {
+			status.InitContainerStatuses[i].Ready = false
+		}
+	}
+	return append(backOff, restartContainers(pod, status.ContainerStatuses, func(name string, exitCode int32) bool {
+		return shouldRestart(pod.Spec.RestartPolicy, exitCode)
+	}, now)...)
+}
+
+func restartContainers(pod *v1.Pod, statuses []v1.ContainerStatus, restarts func(name string, exitCode int32) bool, now metav1.Time) []string {
+	backOff := make([]string, 0)
+	for i := range statuses {
+		containerStatus := &statuses[i]
+
+		if terminated := containerStatus.State.Terminated; terminated != nil {
+			if !restarts(containerStatus.Name, terminated.ExitCode) {
+				continue
+			}
+			delay := restartDelay(containerStatus.RestartCount, util.Elapsed(terminated.StartedAt.Time, terminated.FinishedAt.Time))
+			containerStatus.LastTerminationState = v1.ContainerState{Terminated: terminated}
+			containerStatus.State = v1.ContainerState{
+				Waiting: &v1.ContainerStateWaiting{
+					Reason: CrashLoopBackOffReason,
+					Message: fmt.Sprintf("back-off %v restarting failed container=%v pod=%v_%v(%v)",
+						delay, containerStatus.Name, pod.GetName(), pod.GetNamespace(), pod.GetUID()),
+				},
+			}
+			backOff = append(backOff, containerStatus.Name)
+			continue
+		}
+
+		if waiting := containerStatus.State.Waiting; waiting != nil && waiting.Reason == CrashLoopBackOffReason {
+			last := containerStatus.LastTerminationState.Terminated
+			if last != nil && util.Elapsed(last.FinishedAt.Time, now.Time) < restartDelay(containerStatus.RestartCount, util.Elapsed(last.StartedAt.Time, last.FinishedAt.Time)) {
+				continue
+			}
+			startContainer(containerStatus, containerOf(pod, containerStatus.Name), now)
+			containerStatus.RestartCount++
+		}
+	}
+	return backOff
+}
+
+// syncPodPhase derives the phase and readiness of the pod from its containers: the
+// pod is ready when it is initialized and all containers and sidecars run, and done
+// once none of its containers runs or will restart, which stops its sidecars. A
+// regular init container failing for good fails the pod.
+func syncPodPhase(status *v1.PodStatus, sidecars map[string]bool, now metav1.Time) {
+	initialized := isInitialized(status)
+	ready, done, failed := initialized, initialized, false
+	for _, containerStatus := range status.ContainerStatuses {
+		if !containerStatus.Ready {
+			ready = false
+		}
+		if terminated := containerStatus.State.Terminated; terminated != nil {
+			if terminated.ExitCode != 0 {
+				failed = true
+			}
+		} else {
+			done = false
+		}
+	}
+	for _, containerStatus := range status.InitContainerStatuses {
+		if sidecars[containerStatus.Name] && !containerStatus.Ready {
+			ready = false
+		}
+	}
+
+	if initFailed(status, sidecars) {
+		status.Phase = v1.PodFailed
+		ready = false
+	} else if done && len(status.ContainerStatuses) > 0 {
+		stopSidecars(status, sidecars, now)
+		status.Phase = v1.PodSucceeded
+		if failed {
+			status.Phase = v1.PodFailed
+		}
+		ready = false
+	}
+
+	conditionStatus, reason := v1.ConditionFalse, containersNotReadyReason
+	if ready {
+		conditionStatus, reason = v1.ConditionTrue, ""
+	}
+	for i, condition := range status.Conditions {
+		if (condition.Type == v1.PodReady || condition.Type == v1.ContainersReady) && condition.Status != conditionStatus {
+			status.Conditions[i].Status = conditionStatus
+			status.Conditions[i].Reason = reason
+			status.Conditions[i].LastTransitionTime = now
+		}
+	}
+}
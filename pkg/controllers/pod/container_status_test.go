@@ -0,0 +1,60 @@
+package pod
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRestartDelay(t *testing.T) {
+	tests := []struct {
+		name         string
+		restartCount int32
+		ran          time.Duration
+		want         time.Duration
+	}{
+		{
+			name: "first restart",
+			want: 10 * time.Second,
+		},
+		{
+			name:         "doubles per restart",
+			restartCount: 1,
+			want:         20 * time.Second,
+		},
+		{
+			name:         "fourth restart",
+			restartCount: 4,
+			ran:          time.Minute,
+			want:         160 * time.Second,
+		},
+		{
+			name:         "capped",
+			restartCount: 5,
+			want:         300 * time.Second,
+		},
+		{
+			name:         "capped after many restarts",
+			restartCount: 1000,
+			want:         300 * time.Second,
+		},
+		{
+			name:         "reset after running long enough",
+			restartCount: 8,
+			ran:          10 * time.Minute,
+			want:         10 * time.Second,
+		},
+		{
+			name:         "not reset just before",
+			restartCount: 8,
+			ran:          10*time.Minute - time.Second,
+			want:         300 * time.Second,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := restartDelay(test.restartCount, test.ran); got != test.want {
+				t.Errorf("restartDelay(%v, %v) = %v, want %v", test.restartCount, test.ran, got, test.want)
+			}
+		})
+	}
+}
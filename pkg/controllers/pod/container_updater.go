@@ -0,0 +1,294 @@
+package pod
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	nodecontroller "github.com/NJUPT-ISL/NodeSimulator/pkg/controllers/node"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+	"math"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// containerUpdatePeriod is how often the containers of every pod are checked.
+const containerUpdatePeriod = 10 * time.Second
+
+// ContainerUpdater simulates the containers of running pods: init containers run
+// in order, startup and readiness probes decide when containers start and are ready,
+// containers with a run duration exit once it is over, a container whose memory
+// usage exceeds its limit is OOMKilled, and one with a crash probability may
+// crash. Failed containers are restarted according to the restart policy of the
+// pod with the exponential back-off of the kubelet, and the pod completes once
+// none runs.
+type ContainerUpdater struct {
+	Client   client.Client
+	Recorder record.EventRecorder
+	Tracker  *UsageTracker
+	Queue    workqueue.RateLimitingInterface
+	StopChan chan struct{}
+
+	// lock guards probes and rolls.
+	lock   sync.Mutex
+	probes map[string]*probeState
+	rolls  map[string]*rollState
+}
+
+// rollState is when the crash of a running container was last rolled for.
+type rollState struct {
+	node      string
+	startedAt time.Time
+	at        time.Time
+}
+
+func NewContainerUpdater(updaterClient client.Client, recorder record.EventRecorder, tracker *UsageTracker, queue workqueue.RateLimitingInterface, stopChan chan struct{}) (*ContainerUpdater, error) {
+	if updaterClient == nil || recorder == nil || tracker == nil || queue == nil || stopChan == nil {
+		return nil, errors.New("New ContainerUpdater Error, parameters contains nil ")
+	}
+	return &ContainerUpdater{
+		Client:   updaterClient,
+		Recorder: recorder,
+		Tracker:  tracker,
+		Queue:    queue,
+		StopChan: stopChan,
+		probes:   make(map[string]*probeState),
+		rolls:    make(map[string]*rollState),
+	}, nil
+}
+
+func (c *ContainerUpdater) processNextItem() bool {
+	ctx := context.TODO()
+	key, quit := c.Queue.Get()
+	if quit {
+		return false
+	}
+	defer c.Queue.Done(key)
+
+	if node, ok := key.(*v1.Node); ok {
+		c.SyncContainers(ctx, node)
+	} else {
+		klog.Errorf("Key in Queue is not Node Type. ")
+	}
+	return true
+}
+
+func (c *ContainerUpdater) runWorker() {
+	for c.processNextItem() {
+	}
+}
+
+func (c *ContainerUpdater) InitUpdater() {
+	for {
+		util.Tick(containerUpdatePeriod)
+		nodeList := &v1.NodeList{}
+		err := c.Client.List(context.TODO(), nodeList, &client.MatchingLabels{
+			nodecontroller.ManageLabelKey: nodecontroller.ManageLabelValue,
+		})
+		if err != nil {
+			klog.Errorf("List Node Error: %v", err)
+			continue
+		}
+		for _, node := range nodeList.Items {
+			c.Queue.Add(node.DeepCopy())
+		}
+	}
+}
+
+func (c *ContainerUpdater) Run(threadiness int, stopCh chan struct{}) {
+	defer runtime.HandleCrash()
+
+	defer c.Queue.ShutDown()
+	klog.Info("Starting container updater")
+
+	go c.InitUpdater()
+
+	for i := 0; i < threadiness; i++ {
+		go wait.Until(c.runWorker, time.Second, stopCh)
+	}
+
+	<-stopCh
+	klog.Info("Stopping container updater")
+}
+
+func (c *ContainerUpdater) SyncContainers(ctx context.Context, node *v1.Node) {
+	nodeName := node.GetName()
+	podList := &v1.PodList{}
+	err := c.Client.List(ctx, podList, &client.MatchingLabels{
+		nodecontroller.ManageLabelKey: nodecontroller.ManageLabelValue,
+	})
+	if err != nil {
+		klog.Errorf("List Pod Error: %v", err)
+		return
+	}
+
+	podListWithNode := make([]v1.Pod, 0)
+	for _, pod := range podList.Items {
+		if pod.Spec.NodeName == nodeName {
+			podListWithNode = append(podListWithNode, pod)
+		}
+	}
+
+	seen := make(map[string]bool)
+	defer c.forgetProbes(nodeName, seen)
+
+	for i := range podListWithNode {
+		pod := &podListWithNode[i]
+		// Pending pods the simulator started are still initializing.
+		started := pod.Status.Phase == v1.PodRunning || (pod.Status.Phase == v1.PodPending && pod.Status.StartTime != nil)
+		if !started || pod.GetDeletionTimestamp() != nil {
+			continue
+		}
+
+		now := metav1.Now()
+		status := pod.Status.DeepCopy()
+		sidecars := sidecarNames(ctx, c.Client, pod)
+		c.failContainers(pod, status, sidecars, seen, now)
+		unhealthy := c.syncProbes(pod, status, sidecars, seen, now)
+		completeInitContainers(pod, status, sidecars, now)
+		completeContainers(pod, status, now)
+		backOff := syncContainerRestarts(pod, status, sidecars, now)
+		running := startInitContainers(pod, status, sidecars, now)
+		syncPodPhase(status, sidecars, now)
+		if equality.Semantic.DeepEqual(*status, pod.Status) {
+			continue
+		}
+
+		ops := []util.Ops{
+			{
+				Op:    "replace",
+				Path:  "/status",
+				Value: status,
+			},
+		}
+		start := time.Now()
+		err := c.Client.Status().Patch(ctx, pod, &util.Patch{PatchOps: ops})
+		nodecontroller.RecordPodStatusPatch(start)
+		if err != nil {
+			klog.Errorf("Pod: %v/%v Patch Status Error: %v", pod.GetNamespace(), pod.GetName(), err)
+			continue
+		}
+		pod.Status = *status
+		if running {
+			nodecontroller.RecordPodRunning(util.Simulated(pod.GetCreationTimestamp().Time), util.Simulated(now.Time))
+		}
+
+		for _, event := range unhealthy {
+			c.Recorder.Eventf(pod, v1.EventTypeWarning, UnhealthyReason, "%v for container %v", event.message, event.container)
+		}
+		for _, name := range backOff {
+			c.Recorder.Eventf(pod, v1.EventTypeWarning, BackOffReason, "Back-off restarting failed container %v", name)
+		}
+		if isTerminated(pod) {
+			klog.Infof("Pod: %v/%v %v", pod.GetNamespace(), pod.GetName(), pod.Status.Phase)
+			if _, ok := nodecontroller.GetScheduledGPUIDs(pod.GetLabels()); ok {
+				releaseGPUs(ctx, c.Client, nodeName, podListWithNode)
+			}
+		}
+	}
+}
+
+// failContainers terminates the running containers of the pod that ran out of
+// memory in the last usage sample, or that crash by chance. Sidecars may crash too.
+func (c *ContainerUpdater) failContainers(pod *v1.Pod, status *v1.PodStatus, sidecars, seen map[string]bool, now metav1.Time) {
+	usage, sampled := c.Tracker.PodUsage(pod.GetUID())
+	probabilities := crashProbabilities(pod)
+
+	for i := range status.InitContainerStatuses {
+		containerStatus := &status.InitContainerStatuses[i]
+		if containerStatus.State.Running == nil || !sidecars[containerStatus.Name] {
+			continue
+		}
+		probability, ok := probabilities[containerStatus.Name]
+		if !ok {
+			probability = probabilities["*"]
+		}
+		if probability > 0 && c.roll(pod, containerStatus, probability, seen, now) {
+			terminateContainer(containerStatus, ErrorReason, crashExitCode, "", now)
+		}
+	}
+
+	for i := range status.ContainerStatuses {
+		containerStatus := &status.ContainerStatuses[i]
+		if containerStatus.State.Running == nil {
+			continue
+		}
+		container := containerOf(pod, containerStatus.Name)
+
+		if limit, ok := container.Resources.Limits[v1.ResourceMemory]; ok && sampled && usage.Timestamp.After(util.Simulated(containerStatus.State.Running.StartedAt.Time)) {
+			for _, containerUsage := range usage.Containers {
+				if containerUsage.Name == container.Name && containerUsage.Usage.Memory().Cmp(limit) > 0 {
+					message := fmt.Sprintf("Container %v was using %v, which exceeds its limit of %v.", container.Name, containerUsage.Usage.Memory().String(), limit.String())
+					terminateContainer(containerStatus, OOMKilledReason, oomExitCode, message, now)
+				}
+			}
+			if containerStatus.State.Running == nil {
+				continue
+			}
+		}
+
+		probability, ok := probabilities[container.Name]
+		if !ok {
+			probability = probabilities["*"]
+		}
+		if probability > 0 && c.roll(pod, containerStatus, probability, seen, now) {
+			terminateContainer(containerStatus, ErrorReason, crashExitCode, "", now)
+		}
+	}
+}
+
+// roll reports whether a running container crashes with the given probability per
+// minute within the simulated time elapsed since its last roll, or since it started.
+func (c *ContainerUpdater) roll(pod *v1.Pod, containerStatus *v1.ContainerStatus, probability float64, seen map[string]bool, now metav1.Time) bool {
+	startedAt := containerStatus.State.Running.StartedAt.Time
+	key := "crash/" + string(pod.GetUID()) + "/" + containerStatus.Name
+	seen[key] = true
+	c.lock.Lock()
+	state, ok := c.rolls[key]
+	if !ok || !state.startedAt.Equal(startedAt) {
+		state = &rollState{node: pod.Spec.NodeName, startedAt: startedAt, at: startedAt}
+		c.rolls[key] = state
+	}
+	elapsed := util.Elapsed(state.at, now.Time)
+	if elapsed > 0 {
+		state.at = now.Time
+	}
+	c.lock.Unlock()
+
+	if elapsed <= 0 {
+		return false
+	}
+	return chance(pod, 1-math.Pow(1-math.Min(1, probability), elapsed.Minutes()),
+		"crash", containerStatus.Name, strconv.Itoa(int(containerStatus.RestartCount)), strconv.FormatInt(int64(now.Sub(startedAt)/time.Second), 10))
+}
+
+// chance reports whether an event of the pod with the given probability happens. The
+// event is named by keys, so that it is decided the same way by every run with the
+// same seed.
+func chance(pod *v1.Pod, probability float64, keys ...string) bool {
+	rnd := util.NewRand(append([]string{pod.GetNamespace(), pod.GetName()}, keys...)...)
+	return rnd.Float64() < probability
+}
+
+// crashProbabilities returns the crash probabilities in the CrashProbabilityAnnotation of the pod.
+func crashProbabilities(pod *v1.Pod) map[string]float64 {
+	probabilities := make(map[string]float64)
+	value, ok := pod.GetAnnotations()[CrashProbabilityAnnotation]
+	if !ok {
+		return probabilities
+	}
+	if err := json.Unmarshal([]byte(value), &probabilities); err != nil {
+		klog.Errorf("Pod: %v/%v Invalid %v Annotation: %v", pod.GetNamespace(), pod.GetName(), CrashProbabilityAnnotation, err)
+	}
+	return probabilities
+}
@@ -0,0 +1,203 @@
+package pod
+
+import (
+	"context"
+	nodecontroller "github.com/NJUPT-ISL/NodeSimulator/pkg/controllers/node"
+	scv1 "github.com/NJUPT-ISL/SCV/api/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/klog"
+	"math/rand"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func formatGPUIDs(ids []int) string {
+	s := make([]string, 0, len(ids))
+	for _, id := range ids {
+		s = append(s, strconv.Itoa(id))
+	}
+	return strings.Join(s, gpuIDSeparator)
+}
+
+func containsGPUID(ids []int, id int) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
+}
+
+// filterCardsByTags drops the cards the pod may not share because of its
+// anti-affinity or exclusion tag.
+func filterCardsByTags(cardList scv1.CardList, labels map[string]string) scv1.CardList {
+	antiAffinityTag, hasAntiAffinity := labels[nodecontroller.AntiAffinity]
+	exclusionTag, hasExclusion := labels[nodecontroller.Exclusion]
+
+	filterCardList := make(scv1.CardList, 0)
+	for _, card := range cardList {
+		if hasAntiAffinity && containsParam(card.AntiAffinityTag, antiAffinityTag) {
+			continue
+		}
+		if hasExclusion {
+			excluded := false
+			for _, tag := range card.ExclusionTag {
+				if exclusionTag != tag {
+					excluded = true
+					break
+				}
+			}
+			if excluded {
+				continue
+			}
+		}
+		filterCardList = append(filterCardList, card)
+	}
+	return filterCardList
+}
+
+// pickCards chooses demand.Number distinct cards from candidates, preferring the
+// cards with the most free memory. It returns nil if not enough cards fit.
+func pickCards(candidates scv1.CardList, statusList nodecontroller.CardStatusList, demand nodecontroller.GPUDemand) []int {
+	fit := make(scv1.CardList, 0)
+	for _, card := range candidates {
+		if int(card.ID) < len(statusList) && demand.Fits(card, statusList[card.ID]) {
+			fit = append(fit, card)
+		}
+	}
+	if len(fit) < demand.Number {
+		return nil
+	}
+	sort.SliceStable(fit, func(i, j int) bool {
+		return fit[i].FreeMemory > fit[j].FreeMemory
+	})
+	if demand.Number > 1 && nodecontroller.HasTopology(statusList) {
+		return pickTopologyCards(fit, statusList, demand.Number)
+	}
+	ids := make([]int, 0, demand.Number)
+	for _, card := range fit[:demand.Number] {
+		ids = append(ids, int(card.ID))
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// maxTopologyCombinations bounds the exhaustive search of pickTopologyCards.
+const maxTopologyCombinations = 20000
+
+// pickTopologyCards chooses number cards from fit, which is sorted by free memory,
+// with the best topology score. Among equally connected sets the one found first,
+// i.e. with the most free memory, wins. Too many candidates are placed greedily.
+func pickTopologyCards(fit scv1.CardList, statusList nodecontroller.CardStatusList, number int) []int {
+	var best []int
+	bestScore := -1.0
+
+	consider := func(ids []int) {
+		sorted := append([]int(nil), ids...)
+		sort.Ints(sorted)
+		if score := nodecontroller.TopologyScore(statusList, sorted); score > bestScore {
+			best, bestScore = sorted, score
+		}
+	}
+
+	if combinations(len(fit), number) <= maxTopologyCombinations {
+		chosen := make([]int, 0, number)
+		var search func(from int)
+		search = func(from int) {
+			if len(chosen) == number {
+				consider(chosen)
+				return
+			}
+			for i := from; i <= len(fit)-(number-len(chosen)); i++ {
+				chosen = append(chosen, int(fit[i].ID))
+				search(i + 1)
+				chosen = chosen[:len(chosen)-1]
+			}
+		}
+		search(0)
+		return best
+	}
+
+	// Grow a set from every card, each time adding the card best linked to the set.
+	for _, seed := range fit {
+		chosen := []int{int(seed.ID)}
+		for len(chosen) < number {
+			next, nextLevel := -1, -1
+			for _, card := range fit {
+				if containsGPUID(chosen, int(card.ID)) {
+					continue
+				}
+				level := 0
+				for _, id := range chosen {
+					level += nodecontroller.LinkLevel(statusList[id], statusList[card.ID])
+				}
+				if level > nextLevel {
+					next, nextLevel = int(card.ID), level
+				}
+			}
+			chosen = append(chosen, next)
+		}
+		consider(chosen)
+	}
+	return best
+}
+
+func combinations(n, k int) int {
+	c := 1
+	for i := 1; i <= k; i++ {
+		c = c * (n - k + i) / i
+		if c > maxTopologyCombinations {
+			return c
+		}
+	}
+	return c
+}
+
+// randomCards chooses demand.Number distinct healthy cards at random, regardless of
+// free memory. The choice is drawn from rnd.
+func randomCards(cardList scv1.CardList, statusList nodecontroller.CardStatusList, demand nodecontroller.GPUDemand, rnd *rand.Rand) []int {
+	candidates := make(scv1.CardList, 0)
+	for _, card := range cardList {
+		if int(card.ID) < len(statusList) && len(statusList[card.ID].MIGDevices) == 0 && card.Health != nodecontroller.CardUnhealthy {
+			candidates = append(candidates, card)
+		}
+	}
+	if len(candidates) < demand.Number {
+		return nil
+	}
+	ids := make([]int, 0, demand.Number)
+	for _, i := range rnd.Perm(len(candidates))[:demand.Number] {
+		ids = append(ids, int(candidates[i].ID))
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// isTerminated reports whether all containers of the pod have terminated for good.
+func isTerminated(pod *v1.Pod) bool {
+	return pod.Status.Phase == v1.PodFailed || pod.Status.Phase == v1.PodSucceeded
+}
+
+// releaseGPUs rebuilds the cards of the Scv of the node from the pods still holding them.
+func releaseGPUs(ctx context.Context, c client.Client, nodeName string, pods []v1.Pod) {
+	err := nodecontroller.UpdateScv(ctx, c, nodeName, func(scv *scv1.Scv) bool {
+		cardList := scv.Status.CardList
+		statusList := nodecontroller.GetCardStatus(scv)
+		nodecontroller.RebuildCards(cardList, statusList, pods)
+
+		freeSum := uint64(0)
+		for _, card := range cardList {
+			freeSum += card.FreeMemory
+		}
+		scv.Status.FreeMemorySum = freeSum
+		scv.Status.CardList = cardList
+		nodecontroller.SetCardStatus(scv, statusList)
+		return true
+	})
+	if err != nil && !apierrors.IsNotFound(err) {
+		klog.Errorf("Node: %v Update Scv Error: %v", nodeName, err)
+	}
+}
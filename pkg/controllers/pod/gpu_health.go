@@ -0,0 +1,271 @@
+package pod
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	simv1 "github.com/NJUPT-ISL/NodeSimulator/pkg/api/v1"
+	nodecontroller "github.com/NJUPT-ISL/NodeSimulator/pkg/controllers/node"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/util"
+	scv1 "github.com/NJUPT-ISL/SCV/api/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GPUHealthUpdater injects GPU card faults. A card is unhealthy while it is listed
+// in the unhealthy-gpus annotation of its node or one of the faults scheduled in
+// the NodeSimulator is active. The GPU allocatable of the node shrinks by the
+// unhealthy cards, and the pods running on them fail.
+type GPUHealthUpdater struct {
+	Client   client.Client
+	Recorder record.EventRecorder
+	Queue    workqueue.RateLimitingInterface
+	StopChan chan struct{}
+}
+
+func NewGPUHealthUpdater(updaterClient client.Client, recorder record.EventRecorder, queue workqueue.RateLimitingInterface, stopChan chan struct{}) (*GPUHealthUpdater, error) {
+	if updaterClient == nil || recorder == nil || queue == nil || stopChan == nil {
+		return nil, errors.New("New GPUHealthUpdater Error, parameters contains nil ")
+	}
+	return &GPUHealthUpdater{
+		Client:   updaterClient,
+		Recorder: recorder,
+		Queue:    queue,
+		StopChan: stopChan,
+	}, nil
+}
+
+func (g *GPUHealthUpdater) processNextItem() bool {
+	ctx := context.TODO()
+	key, quit := g.Queue.Get()
+	if quit {
+		return false
+	}
+	defer g.Queue.Done(key)
+
+	if node, ok := key.(*v1.Node); ok {
+		g.SyncGPUHealth(ctx, node)
+	} else {
+		klog.Errorf("Key in Queue is not Node Type. ")
+	}
+	return true
+}
+
+func (g *GPUHealthUpdater) runWorker() {
+	for g.processNextItem() {
+	}
+}
+
+func (g *GPUHealthUpdater) InitUpdater() {
+	for {
+		util.Tick(10 * time.Second)
+		nodeList := &v1.NodeList{}
+		err := g.Client.List(context.TODO(), nodeList, &client.MatchingLabels{
+			nodecontroller.ManageLabelKey: nodecontroller.ManageLabelValue,
+		})
+		if err != nil {
+			klog.Errorf("List Node Error: %v", err)
+			continue
+		}
+		for _, node := range nodeList.Items {
+			g.Queue.Add(node.DeepCopy())
+		}
+	}
+}
+
+func (g *GPUHealthUpdater) Run(threadiness int, stopCh chan struct{}) {
+	defer runtime.HandleCrash()
+
+	defer g.Queue.ShutDown()
+	klog.Info("Starting GPU health updater")
+
+	go g.InitUpdater()
+
+	for i := 0; i < threadiness; i++ {
+		go wait.Until(g.runWorker, time.Second, stopCh)
+	}
+
+	<-stopCh
+	klog.Info("Stopping GPU health updater")
+}
+
+func (g *GPUHealthUpdater) SyncGPUHealth(ctx context.Context, node *v1.Node) {
+	nodeName := node.GetName()
+	unhealthy := g.unhealthyCards(ctx, node)
+
+	podList := &v1.PodList{}
+	err := g.Client.List(ctx, podList, &client.MatchingLabels{
+		nodecontroller.ManageLabelKey: nodecontroller.ManageLabelValue,
+	})
+	if err != nil {
+		klog.Errorf("List Pod Error: %v", err)
+		return
+	}
+
+	podListWithNode := make([]v1.Pod, 0)
+	for _, pod := range podList.Items {
+		if pod.Spec.NodeName == nodeName {
+			podListWithNode = append(podListWithNode, pod)
+		}
+	}
+
+	failed := false
+	for i := range podListWithNode {
+		pod := &podListWithNode[i]
+		ids, ok := nodecontroller.GetScheduledGPUIDs(pod.GetLabels())
+		if !ok || !nodecontroller.HoldsGPU(pod) {
+			continue
+		}
+		for _, id := range ids {
+			if !unhealthy[id] {
+				continue
+			}
+			message := fmt.Sprintf("GPU card %d on node %v became unhealthy", id, nodeName)
+			status := failedPodStatus(pod, GPUUnhealthyReason, message)
+			ops := []util.Ops{
+				{
+					Op:    "replace",
+					Path:  "/status",
+					Value: status,
+				},
+			}
+			start := time.Now()
+			err := g.Client.Status().Patch(ctx, pod, &util.Patch{PatchOps: ops})
+			nodecontroller.RecordPodStatusPatch(start)
+			if err != nil {
+				klog.Errorf("Pod: %v/%v Patch Status Error: %v", pod.GetNamespace(), pod.GetName(), err)
+				break
+			}
+			pod.Status = status
+			g.Recorder.Event(pod, v1.EventTypeWarning, GPUUnhealthyReason, message)
+			failed = true
+			break
+		}
+	}
+
+	var cardList scv1.CardList
+	var statusList nodecontroller.CardStatusList
+	err = nodecontroller.UpdateScv(ctx, g.Client, nodeName, func(scv *scv1.Scv) bool {
+		cardList = scv.Status.CardList
+		statusList = nodecontroller.GetCardStatus(scv)
+
+		changed := failed
+		for i, card := range cardList {
+			health := nodecontroller.CardHealthy
+			if unhealthy[int(card.ID)] {
+				health = nodecontroller.CardUnhealthy
+			}
+			if card.Health != health {
+				klog.Infof("Node: %v Card: %v is %v", nodeName, card.ID, health)
+				cardList[i].Health = health
+				changed = true
+			}
+		}
+		if !changed {
+			return false
+		}
+
+		nodecontroller.RebuildCards(cardList, statusList, podListWithNode)
+
+		freeSum := uint64(0)
+		for _, card := range cardList {
+			freeSum += card.FreeMemory
+		}
+		scv.Status.FreeMemorySum = freeSum
+		scv.Status.CardList = cardList
+		nodecontroller.SetCardStatus(scv, statusList)
+		return true
+	})
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			klog.Errorf("Node: %v Update Scv Error: %v", nodeName, err)
+		}
+		return
+	}
+
+	allocatable := nodecontroller.GPUAllocatable(node, cardList, statusList)
+	if !equality.Semantic.DeepEqual(allocatable, node.Status.Allocatable) {
+		ops := []util.Ops{
+			{
+				Op:    "replace",
+				Path:  "/status/allocatable",
+				Value: allocatable,
+			},
+		}
+		if err := g.Client.Status().Patch(ctx, node, &util.Patch{PatchOps: ops}); err != nil {
+			klog.Errorf("Node: %v Patch Allocatable Error: %v", nodeName, err)
+		}
+	}
+}
+
+// unhealthyCards returns the IDs of the cards of the node that are unhealthy now.
+func (g *GPUHealthUpdater) unhealthyCards(ctx context.Context, node *v1.Node) map[int]bool {
+	unhealthy := make(map[int]bool)
+
+	if value, ok := node.GetAnnotations()[nodecontroller.UnhealthyGPUAnnotation]; ok {
+		for _, s := range strings.Split(value, ",") {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+			id, err := strconv.Atoi(s)
+			if err != nil {
+				klog.Errorf("Node: %v Invalid %v Annotation: %q", node.GetName(), nodecontroller.UnhealthyGPUAnnotation, value)
+				continue
+			}
+			unhealthy[id] = true
+		}
+	}
+
+	nodeSimList := &simv1.NodeSimulatorList{}
+	if err := g.Client.List(ctx, nodeSimList); err != nil {
+		klog.Errorf("List NodeSim Error: %v", err)
+		return unhealthy
+	}
+
+	now := util.Now()
+	for _, nodeSim := range nodeSimList.Items {
+		prefix := nodeSim.GetNamespace() + "-" + nodeSim.GetName()
+		if node.GetLabels()[nodecontroller.UniqueLabelKey] != prefix {
+			continue
+		}
+		for _, fault := range nodeSim.Spec.GPU.Faults {
+			if node.GetName() != prefix+"-"+strconv.Itoa(fault.Node) {
+				continue
+			}
+			start := util.Simulated(nodeSim.GetCreationTimestamp().Time)
+			if fault.After != "" {
+				after, err := time.ParseDuration(fault.After)
+				if err != nil {
+					klog.Errorf("NodeSim: %v/%v Invalid Fault After: %q", nodeSim.GetNamespace(), nodeSim.GetName(), fault.After)
+					continue
+				}
+				start = start.Add(after)
+			}
+			if now.Before(start) {
+				continue
+			}
+			if fault.Duration != "" {
+				duration, err := time.ParseDuration(fault.Duration)
+				if err != nil {
+					klog.Errorf("NodeSim: %v/%v Invalid Fault Duration: %q", nodeSim.GetNamespace(), nodeSim.GetName(), fault.Duration)
+					continue
+				}
+				if !now.Before(start.Add(duration)) {
+					continue
+				}
+			}
+			unhealthy[fault.Card] = true
+		}
+	}
+	return unhealthy
+}
@@ -0,0 +1,250 @@
+package pod
+
+import (
+	"context"
+	"errors"
+	nodecontroller "github.com/NJUPT-ISL/NodeSimulator/pkg/controllers/node"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/util"
+	scv1 "github.com/NJUPT-ISL/SCV/api/v1"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+	"math"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// gpuLoadUpdatePeriod is how often the load of every card is updated.
+const gpuLoadUpdatePeriod = 10 * time.Second
+
+// GPULoadUpdater evolves the utilization, power, temperature and clock of the
+// simulated cards from the load profiles of the pods running on them. A pod
+// without a GPULoadAnnotation keeps its share of the cards fully busy.
+type GPULoadUpdater struct {
+	Client   client.Client
+	Queue    workqueue.RateLimitingInterface
+	StopChan chan struct{}
+
+	// lock guards walks, the last level of the random walk of each pod.
+	lock  sync.Mutex
+	walks map[types.UID]float64
+}
+
+func NewGPULoadUpdater(updaterClient client.Client, queue workqueue.RateLimitingInterface, stopChan chan struct{}) (*GPULoadUpdater, error) {
+	if updaterClient == nil || queue == nil || stopChan == nil {
+		return nil, errors.New("New GPULoadUpdater Error, parameters contains nil ")
+	}
+	return &GPULoadUpdater{
+		Client:   updaterClient,
+		Queue:    queue,
+		StopChan: stopChan,
+		walks:    make(map[types.UID]float64),
+	}, nil
+}
+
+func (g *GPULoadUpdater) processNextItem() bool {
+	ctx := context.TODO()
+	key, quit := g.Queue.Get()
+	if quit {
+		return false
+	}
+	defer g.Queue.Done(key)
+
+	if node, ok := key.(*v1.Node); ok {
+		g.SyncGPULoad(ctx, node)
+	} else {
+		klog.Errorf("Key in Queue is not Node Type. ")
+	}
+	return true
+}
+
+func (g *GPULoadUpdater) runWorker() {
+	for g.processNextItem() {
+	}
+}
+
+func (g *GPULoadUpdater) InitUpdater() {
+	for {
+		util.Tick(gpuLoadUpdatePeriod)
+		nodeList := &v1.NodeList{}
+		err := g.Client.List(context.TODO(), nodeList, &client.MatchingLabels{
+			nodecontroller.ManageLabelKey: nodecontroller.ManageLabelValue,
+		})
+		if err != nil {
+			klog.Errorf("List Node Error: %v", err)
+			continue
+		}
+		for _, node := range nodeList.Items {
+			g.Queue.Add(node.DeepCopy())
+		}
+	}
+}
+
+func (g *GPULoadUpdater) Run(threadiness int, stopCh chan struct{}) {
+	defer runtime.HandleCrash()
+
+	defer g.Queue.ShutDown()
+	klog.Info("Starting GPU load updater")
+
+	go g.InitUpdater()
+
+	for i := 0; i < threadiness; i++ {
+		go wait.Until(g.runWorker, time.Second, stopCh)
+	}
+
+	<-stopCh
+	klog.Info("Stopping GPU load updater")
+}
+
+func (g *GPULoadUpdater) SyncGPULoad(ctx context.Context, node *v1.Node) {
+	nodeName := node.GetName()
+	scv := &scv1.Scv{}
+	if err := g.Client.Get(ctx, types.NamespacedName{Name: nodeName}, scv); err != nil {
+		if !apierrors.IsNotFound(err) {
+			klog.Errorf("Node: %v Get Scv Error: %v", nodeName, err)
+		}
+		return
+	}
+
+	podList := &v1.PodList{}
+	err := g.Client.List(ctx, podList, &client.MatchingLabels{
+		nodecontroller.ManageLabelKey: nodecontroller.ManageLabelValue,
+	})
+	if err != nil {
+		klog.Errorf("List Pod Error: %v", err)
+		return
+	}
+
+	cardList := scv.Status.CardList
+	statusList := nodecontroller.GetCardStatus(scv)
+	load := make([]float64, len(cardList))
+
+	now := util.Now()
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if pod.Spec.NodeName != nodeName || !nodecontroller.HoldsGPU(pod) {
+			continue
+		}
+		if _, ok := nodecontroller.GetScheduledGPUIDs(pod.GetLabels()); !ok {
+			continue
+		}
+		demand, err := nodecontroller.GetGPUDemand(pod)
+		if err != nil {
+			continue
+		}
+		level := g.podLevel(pod, now)
+
+		if demand.MIGProfile != "" {
+			refs, _ := nodecontroller.GetScheduledMIGIDs(pod.GetLabels())
+			for _, ref := range refs {
+				if ref.Card < 0 || ref.Card >= len(statusList) {
+					continue
+				}
+				status := statusList[ref.Card]
+				for _, device := range status.MIGDevices {
+					if device.Start == ref.Start && status.TotalCore > 0 {
+						load[ref.Card] += level * float64(device.Core) / float64(status.TotalCore)
+					}
+				}
+			}
+			continue
+		}
+
+		ids, _ := nodecontroller.GetScheduledGPUIDs(pod.GetLabels())
+		for _, id := range ids {
+			if id < 0 || id >= len(cardList) || id >= len(statusList) {
+				continue
+			}
+			load[id] += level * cardShare(demand, cardList[id], statusList[id])
+		}
+	}
+
+	for i := range cardList {
+		if i >= len(statusList) {
+			break
+		}
+		utilization := math.Min(1, load[i])
+		if cardList[i].Health == nodecontroller.CardUnhealthy {
+			utilization = 0
+		}
+		nodecontroller.UpdateCardTelemetry(&cardList[i], &statusList[i], utilization)
+	}
+	scv.Status.CardList = cardList
+	nodecontroller.SetCardStatus(scv, statusList)
+
+	// The telemetry is only written back if no allocation changed the Scv meanwhile,
+	// otherwise it is retried on the next tick.
+	if err := g.Client.Patch(ctx, scv, &util.Patch{PatchOps: nodecontroller.ScvPatchOps(scv)}); err != nil {
+		nodecontroller.RecordScvPatchError(err)
+		if !nodecontroller.IsScvConflict(err) {
+			klog.Errorf("Scv: %v Patch Status Error: %v", scv.GetName(), err)
+		}
+	}
+	g.forgetPods(podList.Items)
+}
+
+// podLevel returns the current load level of the pod, 1 if it has no load profile.
+func (g *GPULoadUpdater) podLevel(pod *v1.Pod, now time.Time) float64 {
+	value, ok := pod.GetAnnotations()[GPULoadAnnotation]
+	if !ok {
+		return 1
+	}
+	profile, err := util.ParseLoadProfile(value)
+	if err != nil {
+		klog.Errorf("Pod: %v/%v Invalid %v Annotation: %v", pod.GetNamespace(), pod.GetName(), GPULoadAnnotation, err)
+		return 1
+	}
+
+	start := util.Simulated(pod.GetCreationTimestamp().Time)
+	if pod.Status.StartTime != nil {
+		start = util.Simulated(pod.Status.StartTime.Time)
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	last, ok := g.walks[pod.GetUID()]
+	if !ok {
+		last = profile.Level
+	}
+	rnd := util.NewRand(pod.GetNamespace(), pod.GetName(), "gpu-load", strconv.FormatInt(int64(now.Sub(start)/gpuLoadUpdatePeriod), 10))
+	level := math.Min(1, profile.LevelAt(now.Sub(start), last, rnd))
+	if profile.Type == util.RandomWalkLoad {
+		g.walks[pod.GetUID()] = level
+	}
+	return level
+}
+
+// forgetPods drops the random walks of the pods that are gone.
+func (g *GPULoadUpdater) forgetPods(pods []v1.Pod) {
+	exist := make(map[types.UID]bool, len(pods))
+	for _, pod := range pods {
+		exist[pod.GetUID()] = true
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	for uid := range g.walks {
+		if !exist[uid] {
+			delete(g.walks, uid)
+		}
+	}
+}
+
+// cardShare returns the fraction of the compute of the card the demand takes.
+func cardShare(demand nodecontroller.GPUDemand, card scv1.Card, status nodecontroller.CardStatus) float64 {
+	if demand.Whole {
+		return 1
+	}
+	if demand.Core > 0 && status.TotalCore > 0 {
+		return math.Min(1, float64(demand.Core)/float64(status.TotalCore))
+	}
+	if demand.Memory > 0 && card.TotalMemory > 0 {
+		return math.Min(1, float64(demand.Memory)/float64(card.TotalMemory))
+	}
+	return 0
+}
@@ -0,0 +1,102 @@
+package pod
+
+import (
+	simv1 "github.com/NJUPT-ISL/NodeSimulator/pkg/api/v1"
+	nodecontroller "github.com/NJUPT-ISL/NodeSimulator/pkg/controllers/node"
+	scv1 "github.com/NJUPT-ISL/SCV/api/v1"
+	"reflect"
+	"testing"
+)
+
+// testCards returns number idle cards with the given topology.
+func testCards(number int, topology simv1.GPUTopology) (scv1.CardList, nodecontroller.CardStatusList) {
+	cardList := make(scv1.CardList, 0, number)
+	statusList := make(nodecontroller.CardStatusList, 0, number)
+	for i := 0; i < number; i++ {
+		card := scv1.Card{ID: uint(i), TotalMemory: 16000, FreeMemory: 16000}
+		cardList = append(cardList, card)
+		statusList = append(statusList, nodecontroller.NewCardStatus(card))
+	}
+	nodecontroller.ApplyGPUTopology(statusList, topology)
+	return cardList, statusList
+}
+
+// fitCards returns the cards with the given IDs, in that order.
+func fitCards(cardList scv1.CardList, ids ...int) scv1.CardList {
+	fit := make(scv1.CardList, 0, len(ids))
+	for _, id := range ids {
+		fit = append(fit, cardList[id])
+	}
+	return fit
+}
+
+func TestPickTopologyCards(t *testing.T) {
+	topology := simv1.GPUTopology{
+		NVLinks:      [][]int{{0, 1}, {2, 3}},
+		PCIeSwitches: [][]int{{0, 1, 2, 3}, {4, 5, 6, 7}},
+		Sockets:      [][]int{{0, 1, 2, 3}, {4, 5, 6, 7}},
+	}
+	cardList, statusList := testCards(8, topology)
+
+	// 10 NVLink groups of 4 cards, too many sets of 4 to search them all
+	large := simv1.GPUTopology{}
+	for i := 0; i < 40; i += 4 {
+		large.NVLinks = append(large.NVLinks, []int{i, i + 1, i + 2, i + 3})
+	}
+	largeCards, largeStatus := testCards(40, large)
+	largeFit := make([]int, 0, 40)
+	for i := 39; i >= 0; i-- {
+		largeFit = append(largeFit, i)
+	}
+
+	tests := []struct {
+		name       string
+		fit        scv1.CardList
+		statusList nodecontroller.CardStatusList
+		number     int
+		want       []int
+	}{
+		{
+			name:       "NVLink pair over freer cards",
+			fit:        fitCards(cardList, 4, 0, 6, 1),
+			statusList: statusList,
+			number:     2,
+			want:       []int{0, 1},
+		},
+		{
+			name:       "same switch over cross socket",
+			fit:        fitCards(cardList, 0, 4, 2),
+			statusList: statusList,
+			number:     2,
+			want:       []int{0, 2},
+		},
+		{
+			name:       "freer pair wins a tie",
+			fit:        fitCards(cardList, 2, 3, 0, 1),
+			statusList: statusList,
+			number:     2,
+			want:       []int{2, 3},
+		},
+		{
+			name:       "two NVLink pairs",
+			fit:        fitCards(cardList, 0, 4, 1, 5, 2, 6, 3, 7),
+			statusList: statusList,
+			number:     4,
+			want:       []int{0, 1, 2, 3},
+		},
+		{
+			name:       "greedy placement of many candidates",
+			fit:        fitCards(largeCards, largeFit...),
+			statusList: largeStatus,
+			number:     4,
+			want:       []int{36, 37, 38, 39},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := pickTopologyCards(test.fit, test.statusList, test.number); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("pickTopologyCards() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
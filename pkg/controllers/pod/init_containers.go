@@ -0,0 +1,247 @@
+package pod
+
+import (
+	"context"
+	"encoding/json"
+	nodecontroller "github.com/NJUPT-ISL/NodeSimulator/pkg/controllers/node"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const containersNotInitializedReason = "ContainersNotInitialized"
+
+// InitContainerBehavior is how an init container runs.
+type InitContainerBehavior struct {
+	// Duration is how long a run takes, by default it completes at the next update.
+	Duration string `json:"duration,omitempty"`
+	// FailureProbability is the probability that a run fails instead of completing.
+	FailureProbability float64 `json:"failureProbability,omitempty"`
+
+	duration time.Duration
+}
+
+// initContainerBehaviors returns the behaviors in the InitContainersAnnotation of the pod.
+func initContainerBehaviors(pod *v1.Pod) map[string]InitContainerBehavior {
+	behaviors := make(map[string]InitContainerBehavior)
+	value, ok := pod.GetAnnotations()[InitContainersAnnotation]
+	if !ok {
+		return behaviors
+	}
+	if err := json.Unmarshal([]byte(value), &behaviors); err != nil {
+		klog.Errorf("Pod: %v/%v Invalid %v Annotation: %v", pod.GetNamespace(), pod.GetName(), InitContainersAnnotation, err)
+		return behaviors
+	}
+	for name, behavior := range behaviors {
+		if behavior.Duration == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(behavior.Duration)
+		if err != nil {
+			klog.Errorf("Pod: %v/%v Invalid Duration of Init Container %v: %v", pod.GetNamespace(), pod.GetName(), name, err)
+		}
+		behavior.duration = duration
+		behaviors[name] = behavior
+	}
+	return behaviors
+}
+
+func behaviorOf(behaviors map[string]InitContainerBehavior, name string) InitContainerBehavior {
+	if behavior, ok := behaviors[name]; ok {
+		return behavior
+	}
+	return behaviors["*"]
+}
+
+// sidecarNames returns the init containers of the pod that run as sidecars: those
+// with restartPolicy Always, and those listed in the SidecarsAnnotation. The API
+// types of the simulator predate the field, so it is read from the raw pod.
+func sidecarNames(ctx context.Context, c client.Client, pod *v1.Pod) map[string]bool {
+	if len(pod.Spec.InitContainers) == 0 {
+		return make(map[string]bool)
+	}
+	sidecars := nodecontroller.AnnotatedSidecars(pod)
+
+	raw := &unstructured.Unstructured{}
+	raw.SetAPIVersion("v1")
+	raw.SetKind("Pod")
+	if err := c.Get(ctx, types.NamespacedName{Namespace: pod.GetNamespace(), Name: pod.GetName()}, raw); err != nil {
+		klog.Errorf("Pod: %v/%v Get Error: %v", pod.GetNamespace(), pod.GetName(), err)
+		return sidecars
+	}
+	initContainers, _, _ := unstructured.NestedSlice(raw.Object, "spec", "initContainers")
+	for _, item := range initContainers {
+		container, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if policy, _ := container["restartPolicy"].(string); policy == string(v1.RestartPolicyAlways) {
+			name, _ := container["name"].(string)
+			sidecars[name] = true
+		}
+	}
+	return sidecars
+}
+
+// recordSidecars lists all sidecars of the pod in its SidecarsAnnotation before it
+// starts, so that its effective requests count them wherever the pod is read.
+func (r *PodSimReconciler) recordSidecars(ctx context.Context, pod *v1.Pod) {
+	sidecars := sidecarNames(ctx, r.Client, pod)
+	if len(sidecars) == len(nodecontroller.AnnotatedSidecars(pod)) {
+		return
+	}
+	names := make([]string, 0, len(sidecars))
+	for name := range sidecars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	annotations := make(map[string]string)
+	for k, v := range pod.GetAnnotations() {
+		annotations[k] = v
+	}
+	annotations[SidecarsAnnotation] = strings.Join(names, ",")
+	ops := []util.Ops{
+		{
+			Op:    "add",
+			Path:  "/metadata/annotations",
+			Value: annotations,
+		},
+	}
+	if err := r.Client.Patch(ctx, pod, &util.Patch{PatchOps: ops}); err != nil {
+		klog.Errorf("Pod: %v/%v Patch Sidecars Error: %v", pod.GetNamespace(), pod.GetName(), err)
+		return
+	}
+	pod.SetAnnotations(annotations)
+}
+
+// waitingContainerStatus is the status of a container that has not started yet.
+func waitingContainerStatus(container v1.Container) v1.ContainerStatus {
+	started := false
+	return v1.ContainerStatus{
+		Name: container.Name,
+		State: v1.ContainerState{
+			Waiting: &v1.ContainerStateWaiting{Reason: PodInitializingReason},
+		},
+		Image:   container.Image,
+		ImageID: "docker://sim.k8s.io/podSim/image/" + container.Image,
+		Started: &started,
+	}
+}
+
+// startContainer runs a container that has not started yet. A container with a
+// startup probe has not started until the probe succeeds, and one with probes is
+// not ready until they succeed.
+func startContainer(status *v1.ContainerStatus, container v1.Container, now metav1.Time) {
+	started := container.StartupProbe == nil
+	status.State = v1.ContainerState{Running: &v1.ContainerStateRunning{StartedAt: now}}
+	status.Ready = started && container.ReadinessProbe == nil
+	status.Started = &started
+}
+
+// isInitialized reports whether the Initialized condition of the pod is true.
+func isInitialized(status *v1.PodStatus) bool {
+	for _, condition := range status.Conditions {
+		if condition.Type == v1.PodInitialized {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// completeInitContainers terminates the running init containers whose run is over,
+// failing them with their failure probability. Sidecars keep running.
+func completeInitContainers(pod *v1.Pod, status *v1.PodStatus, sidecars map[string]bool, now metav1.Time) {
+	behaviors := initContainerBehaviors(pod)
+	for i := range status.InitContainerStatuses {
+		containerStatus := &status.InitContainerStatuses[i]
+		running := containerStatus.State.Running
+		if running == nil || sidecars[containerStatus.Name] {
+			continue
+		}
+		behavior := behaviorOf(behaviors, containerStatus.Name)
+		if util.Elapsed(running.StartedAt.Time, now.Time) < behavior.duration {
+			continue
+		}
+		if behavior.FailureProbability > 0 && chance(pod, behavior.FailureProbability, "init", containerStatus.Name, strconv.Itoa(int(containerStatus.RestartCount))) {
+			terminateContainer(containerStatus, ErrorReason, crashExitCode, "", now)
+		} else {
+			terminateContainer(containerStatus, CompletedReason, 0, "", now)
+			containerStatus.Ready = true
+		}
+	}
+}
+
+// startInitContainers starts the init containers in order like the kubelet: a sidecar
+// has to start and is left running, a regular init container has to complete before
+// the next one starts. Once all have, the pod is initialized and its containers start.
+// It reports whether the pod started running.
+func startInitContainers(pod *v1.Pod, status *v1.PodStatus, sidecars map[string]bool, now metav1.Time) bool {
+	if isInitialized(status) {
+		return false
+	}
+	for i := range status.InitContainerStatuses {
+		containerStatus := &status.InitContainerStatuses[i]
+		waiting := containerStatus.State.Waiting
+		if waiting != nil && waiting.Reason == PodInitializingReason {
+			startContainer(containerStatus, containerOf(pod, containerStatus.Name), now)
+			if !sidecars[containerStatus.Name] {
+				containerStatus.Ready = false
+				return false
+			}
+		}
+		if sidecars[containerStatus.Name] {
+			// The next init container waits for the sidecar to have started.
+			if containerStatus.Started == nil || !*containerStatus.Started {
+				return false
+			}
+			continue
+		}
+		if terminated := containerStatus.State.Terminated; terminated == nil || terminated.ExitCode != 0 {
+			return false
+		}
+	}
+
+	for i := range status.ContainerStatuses {
+		if waiting := status.ContainerStatuses[i].State.Waiting; waiting != nil && waiting.Reason == PodInitializingReason {
+			startContainer(&status.ContainerStatuses[i], containerOf(pod, status.ContainerStatuses[i].Name), now)
+		}
+	}
+	for i, condition := range status.Conditions {
+		if condition.Type == v1.PodInitialized {
+			status.Conditions[i].Status = v1.ConditionTrue
+			status.Conditions[i].Reason = ""
+			status.Conditions[i].LastTransitionTime = now
+		}
+	}
+	status.Phase = v1.PodRunning
+	return true
+}
+
+// initFailed reports whether a regular init container failed for good, which fails the pod.
+func initFailed(status *v1.PodStatus, sidecars map[string]bool) bool {
+	for _, containerStatus := range status.InitContainerStatuses {
+		if terminated := containerStatus.State.Terminated; terminated != nil && terminated.ExitCode != 0 && !sidecars[containerStatus.Name] {
+			return true
+		}
+	}
+	return false
+}
+
+// stopSidecars terminates the sidecars still running once the containers of the pod are done.
+func stopSidecars(status *v1.PodStatus, sidecars map[string]bool, now metav1.Time) {
+	for i := range status.InitContainerStatuses {
+		containerStatus := &status.InitContainerStatuses[i]
+		if sidecars[containerStatus.Name] && containerStatus.State.Terminated == nil {
+			terminateContainer(containerStatus, CompletedReason, 0, "", now)
+		}
+	}
+}
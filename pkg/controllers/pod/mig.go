@@ -0,0 +1,51 @@
+package pod
+
+import (
+	nodecontroller "github.com/NJUPT-ISL/NodeSimulator/pkg/controllers/node"
+	scv1 "github.com/NJUPT-ISL/SCV/api/v1"
+	"strconv"
+	"strings"
+)
+
+func formatMIGIDs(refs []nodecontroller.MIGRef) string {
+	s := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		s = append(s, strconv.Itoa(ref.Card)+migIDSeparator+strconv.Itoa(ref.Start))
+	}
+	return strings.Join(s, gpuIDSeparator)
+}
+
+// migCardIDs returns the distinct cards of the MIG instances.
+func migCardIDs(refs []nodecontroller.MIGRef) []int {
+	if refs == nil {
+		return nil
+	}
+	ids := make([]int, 0)
+	for _, ref := range refs {
+		if !containsGPUID(ids, ref.Card) {
+			ids = append(ids, ref.Card)
+		}
+	}
+	return ids
+}
+
+// pickMIGDevices chooses demand.Number free instances of the requested MIG profile
+// among the candidate cards, filling the cards in order. It returns nil if there
+// are not enough free instances.
+func pickMIGDevices(candidates scv1.CardList, statusList nodecontroller.CardStatusList, demand nodecontroller.GPUDemand) []nodecontroller.MIGRef {
+	refs := make([]nodecontroller.MIGRef, 0, demand.Number)
+	for _, card := range candidates {
+		if int(card.ID) >= len(statusList) || card.Health == nodecontroller.CardUnhealthy {
+			continue
+		}
+		for _, device := range statusList[card.ID].MIGDevices {
+			if device.Profile == demand.MIGProfile && device.Pod == "" {
+				refs = append(refs, nodecontroller.MIGRef{Card: int(card.ID), Start: device.Start})
+				if len(refs) == demand.Number {
+					return refs
+				}
+			}
+		}
+	}
+	return nil
+}
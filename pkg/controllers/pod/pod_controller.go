@@ -2,7 +2,6 @@ package pod
 
 import (
 	"context"
-	"fmt"
 	nodecontroller "github.com/NJUPT-ISL/NodeSimulator/pkg/controllers/node"
 	"github.com/NJUPT-ISL/NodeSimulator/pkg/util"
 	scv1 "github.com/NJUPT-ISL/SCV/api/v1"
@@ -12,11 +11,12 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/klog"
-	"math/rand"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"strconv"
+	"sync"
 	"time"
 )
 
@@ -24,8 +24,14 @@ type PodSimReconciler struct {
 	Client    client.Client
 	ClientSet *kubernetes.Clientset
 	Scheme    *runtime.Scheme
+	Recorder  record.EventRecorder
+
+	// terminating holds the UIDs of the deleted pods whose containers are stopping.
+	terminating sync.Map
 }
 
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
 func (r *PodSimReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1.Pod{}).
@@ -58,11 +64,11 @@ func (r *PodSimReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 		}
 
 		if pod.GetDeletionTimestamp() != nil {
-			scv := &scv1.Scv{}
-			err := r.Client.Get(ctx, types.NamespacedName{Name: nodeName}, scv)
-			if err != nil {
-				klog.Errorf("Node: %v Get Scv Error: %v", nodeName, err)
-				return ctrl.Result{}, err
+			// The pod keeps its resources until its containers stopped.
+			if pod.Status.StartTime != nil && !isTerminated(pod) {
+				if wait, stopped := r.stopContainers(ctx, pod); !stopped {
+					return ctrl.Result{RequeueAfter: wait}, nil
+				}
 			}
 
 			podListWithNode := make([]v1.Pod, 0)
@@ -76,62 +82,14 @@ func (r *PodSimReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 				return ctrl.Result{}, err
 			}
 
-			for _, pod := range podList.Items {
-				if pod.Spec.NodeName == nodeName {
-					if pod.GetDeletionTimestamp() == nil {
-						podListWithNode = append(podListWithNode, pod)
-					}
-				}
-			}
-
-			cardList := scv.Status.CardList
-
-			for i, card := range cardList {
-				cardList[i].FreeMemory = card.TotalMemory
-			}
-
-			for _, pod := range podListWithNode {
-				labels := pod.GetLabels()
-				mem := StrToUint64(labels["scv/memory"])
-				//minSub := ^uint64(0)
-				GPUID := 0
-
-				//for index, card := range cardList {
-				//	sub := card.FreeMemory - mem
-				//	if sub >= 0 && sub < minSub {
-				//		minSub = sub
-				//		GPUID = index
-				//	}
-				//}
-				if gpuID, ok := labels[scheduleGPUID]; ok {
-					GPUID, err = strconv.Atoi(gpuID)
-					if err != nil {
-						cardList[GPUID].FreeMemory -= mem
-					}
+			for _, item := range podList.Items {
+				if item.Spec.NodeName == nodeName && item.GetUID() != pod.GetUID() {
+					podListWithNode = append(podListWithNode, item)
 				}
 			}
 
-			freeSum := uint64(0)
-			for _, card := range cardList {
-				freeSum += card.FreeMemory
-			}
-
-			scv.Status.FreeMemorySum = freeSum
-			scv.Status.CardList = cardList
-
-			ops := []util.Ops{
-				{
-					Op:    "replace",
-					Path:  "/status",
-					Value: scv.Status,
-				},
-			}
+			releaseGPUs(ctx, r.Client, nodeName, podListWithNode)
 
-			err = r.Client.Patch(context.TODO(), scv, &util.Patch{PatchOps: ops})
-
-			if err != nil {
-				klog.Errorf("Scv: %v Patch Status Error: %v", scv.GetName(), err)
-			}
 			_, hasAffinity := labels[nodecontroller.Affinity]
 			_, hasAntiAffinity := labels[nodecontroller.AntiAffinity]
 			_, hasExclusion := labels[nodecontroller.Exclusion]
@@ -147,71 +105,86 @@ func (r *PodSimReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctr
 			}
 			return ctrl.Result{}, nil
 		}
-		r.SyncFakePod(pod.DeepCopy())
+		if isTerminated(pod) {
+			return ctrl.Result{}, nil
+		}
+		if pod.Status.StartTime == nil {
+			r.recordSidecars(ctx, pod)
+		}
+		if !r.SyncFakePod(pod.DeepCopy()) {
+			return ctrl.Result{}, nil
+		}
 		r.SyncGPUPod(ctx, *pod)
 	}
 
 	return ctrl.Result{}, nil
 }
 
-func (r *PodSimReconciler) SyncFakePod(pod *v1.Pod) {
-	updateTime := metav1.Time{Time: time.Now()}
+// SyncFakePod admits the pod and starts its containers. It reports false when the
+// node rejected the pod.
+func (r *PodSimReconciler) SyncFakePod(pod *v1.Pod) bool {
+	// Once started, the containers are driven by the ContainerUpdater
+	if pod.Status.Phase == v1.PodRunning || pod.Status.StartTime != nil {
+		return true
+	}
+	if !r.admit(context.TODO(), pod) {
+		return false
+	}
+	updateTime := metav1.Now()
+	initContainerStatusList := make([]v1.ContainerStatus, 0)
+	for _, container := range pod.Spec.InitContainers {
+		initContainerStatusList = append(initContainerStatusList, waitingContainerStatus(container))
+	}
 	containerStatusList := make([]v1.ContainerStatus, 0)
 	for _, container := range pod.Spec.Containers {
-		runningState := &v1.ContainerStateRunning{
-			StartedAt: updateTime,
-		}
-		started := true
-		containerStatus := v1.ContainerStatus{
-			Name: container.Name,
-			State: v1.ContainerState{
-				Running: runningState,
-			},
-			Ready:        true,
-			Image:        container.Image,
-			Started:      &started,
-			RestartCount: 0,
-			ImageID:      "docker://sim.k8s.io/podSim/image/" + container.Image,
-		}
-		containerStatusList = append(containerStatusList, containerStatus)
+		containerStatusList = append(containerStatusList, waitingContainerStatus(container))
 	}
 	conditions := []v1.PodCondition{
 		{
 			LastProbeTime:      updateTime,
 			LastTransitionTime: updateTime,
-			Status:             v1.ConditionTrue,
+			Status:             v1.ConditionFalse,
 			Type:               v1.PodInitialized,
+			Reason:             containersNotInitializedReason,
 		},
 		{
 			LastProbeTime:      updateTime,
 			LastTransitionTime: updateTime,
-			Status:             v1.ConditionTrue,
+			Status:             v1.ConditionFalse,
 			Type:               v1.PodReady,
+			Reason:             containersNotReadyReason,
 		},
 		{
 			LastProbeTime:      updateTime,
 			LastTransitionTime: updateTime,
-			Status:             v1.ConditionTrue,
+			Status:             v1.ConditionFalse,
 			Type:               v1.ContainersReady,
+			Reason:             containersNotReadyReason,
 		},
 		{
 			LastProbeTime:      updateTime,
-			LastTransitionTime: updateTime,
+			LastTransitionTime: scheduledTime(pod),
 			Status:             v1.ConditionTrue,
 			Type:               v1.PodScheduled,
 		},
 	}
 
 	podStatus := v1.PodStatus{
-		HostIP:            "10.0.0.1",
-		Phase:             v1.PodRunning,
-		PodIP:             "10.224.0.1",
-		QOSClass:          v1.PodQOSBurstable,
-		StartTime:         &updateTime,
-		Conditions:        conditions,
-		ContainerStatuses: containerStatusList,
+		HostIP:                "10.0.0.1",
+		Phase:                 v1.PodPending,
+		PodIP:                 "10.224.0.1",
+		QOSClass:              GetPodQOS(pod),
+		StartTime:             &updateTime,
+		Conditions:            conditions,
+		InitContainerStatuses: initContainerStatusList,
+		ContainerStatuses:     containerStatusList,
 	}
 
+	// Pods without init containers start running right away.
+	sidecars := sidecarNames(context.TODO(), r.Client, pod)
+	running := startInitContainers(pod, &podStatus, sidecars, updateTime)
+	syncPodPhase(&podStatus, sidecars, updateTime)
+
 	ops := []util.Ops{
 		{
 			Op:    "replace",
@@ -219,13 +192,80 @@ func (r *PodSimReconciler) SyncFakePod(pod *v1.Pod) {
 			Value: podStatus,
 		},
 	}
+	start := time.Now()
 	err := r.Client.Status().Patch(context.TODO(), pod, &util.Patch{PatchOps: ops})
+	nodecontroller.RecordPodStatusPatch(start)
 	if err != nil {
 		klog.Errorf("Pod: %v/%v Patch Status Error: %v", pod.GetNamespace(), pod.GetName(), err)
+		return true
+	}
+	if running {
+		nodecontroller.RecordPodRunning(util.Simulated(pod.GetCreationTimestamp().Time), util.Simulated(updateTime.Time))
+	}
+	return true
+}
+
+// scheduledTime returns when the scheduler bound the pod, now if it did not record
+// it. Like the other timestamps of the control plane it is on the wall clock.
+func scheduledTime(pod *v1.Pod) metav1.Time {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodScheduled && condition.Status == v1.ConditionTrue && !condition.LastTransitionTime.IsZero() {
+			return condition.LastTransitionTime
+		}
 	}
-} //TODO: CPU,memory的allocatable数值的更新
+	return metav1.Now()
+}
+
+// admit runs the admission checks of the kubelet on the pod, and fails the pod
+// with the reason of the kubelet if its node rejects it.
+func (r *PodSimReconciler) admit(ctx context.Context, pod *v1.Pod) bool {
+	node := &v1.Node{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Name: pod.Spec.NodeName}, node); err != nil {
+		klog.Errorf("Node: %v Get Error: %v", pod.Spec.NodeName, err)
+		return true
+	}
+	podList := &v1.PodList{}
+	if err := r.Client.List(ctx, podList, &client.MatchingLabels{
+		nodecontroller.ManageLabelKey: nodecontroller.ManageLabelValue,
+	}); err != nil {
+		klog.Errorf("List Pod Error: %v", err)
+		return true
+	}
+
+	reason, message, ok := admitPod(pod, node, admittedPods(pod, podList.Items))
+	if ok {
+		return true
+	}
+	status := failedPodStatus(pod, reason, "Pod "+message)
+	ops := []util.Ops{
+		{
+			Op:    "replace",
+			Path:  "/status",
+			Value: status,
+		},
+	}
+	start := time.Now()
+	err := r.Client.Status().Patch(ctx, pod, &util.Patch{PatchOps: ops})
+	nodecontroller.RecordPodStatusPatch(start)
+	if err != nil {
+		klog.Errorf("Pod: %v/%v Patch Status Error: %v", pod.GetNamespace(), pod.GetName(), err)
+		return false
+	}
+	klog.Infof("Node: %v Rejected Pod: %v/%v: %v", pod.Spec.NodeName, pod.GetNamespace(), pod.GetName(), message)
+	if r.Recorder != nil {
+		r.Recorder.Event(pod, v1.EventTypeWarning, reason, message)
+	}
+	return false
+}
 
 func (r *PodSimReconciler) SyncGPUPod(ctx context.Context, pod v1.Pod) {
+	if _, err := nodecontroller.GetGPUDemand(&pod); err != nil {
+		nodecontroller.RecordGPUAllocationFailure(nodecontroller.AllocationInvalidRequest)
+		if r.Recorder != nil {
+			r.Recorder.Eventf(&pod, v1.EventTypeWarning, InvalidGPURequestReason, "Invalid GPU request: %v", err)
+		}
+	}
+
 	scv := &scv1.Scv{}
 	err := r.Client.Get(ctx, types.NamespacedName{Name: pod.Spec.NodeName}, scv)
 	if err != nil {
@@ -245,300 +285,181 @@ func (r *PodSimReconciler) SyncGPUPod(ctx context.Context, pod v1.Pod) {
 	}
 
 	for _, item := range podList.Items {
-		if item.Spec.NodeName == pod.Spec.NodeName {
-			podListWithNode = append(podListWithNode, item)
+		if item.Spec.NodeName != pod.Spec.NodeName {
+			continue
+		}
+		// The cache may not have seen the sidecars recorded in the pod yet
+		if value, ok := pod.GetAnnotations()[SidecarsAnnotation]; ok && item.GetUID() == pod.GetUID() {
+			annotations := make(map[string]string)
+			for k, v := range item.GetAnnotations() {
+				annotations[k] = v
+			}
+			annotations[SidecarsAnnotation] = value
+			item.SetAnnotations(annotations)
 		}
+		podListWithNode = append(podListWithNode, item)
 	}
 
+	// Pick from the cards left by the pods that hold GPUs already
 	cardList := scv.Status.CardList
+	statusList := nodecontroller.GetCardStatus(scv)
+	nodecontroller.RebuildCards(cardList, statusList, podListWithNode)
 
-	labels := pod.GetLabels()
-	_, ok := labels[scheduleGPUID]
+	for i := range podListWithNode {
+		pod := podListWithNode[i]
+		labels := pod.GetLabels()
+		if _, ok := labels[scheduleGPUID]; ok || !nodecontroller.HoldsGPU(&pod) || pod.GetDeletionTimestamp() != nil {
+			continue
+		}
 
-	if len(podListWithNode) == 1 && !ok {
-		fmt.Println("start to initialize cardlist")
-		for i, card := range cardList {
-			cardList[i].FreeMemory = card.TotalMemory
+		demand, err := nodecontroller.GetGPUDemand(&pod)
+		if err != nil {
+			klog.Errorf("Pod: %v/%v GPU Request Error: %v", pod.GetNamespace(), pod.GetName(), err)
+			continue
 		}
+		_, hasAffinity := labels[nodecontroller.Affinity]
+		_, hasAntiAffinity := labels[nodecontroller.AntiAffinity]
+		_, hasExclusion := labels[nodecontroller.Exclusion]
 
-		freeSum := uint64(0)
-		for _, card := range cardList {
-			freeSum += card.FreeMemory
+		var GPUIDs []int
+		var MIGIDs []nodecontroller.MIGRef
+		if demand.MIGProfile != "" {
+			candidates := cardList
+			if hasAffinity || hasAntiAffinity || hasExclusion {
+				candidates = filterCardsByTags(cardList, labels)
+			}
+			MIGIDs = pickMIGDevices(candidates, statusList, demand)
+			GPUIDs = migCardIDs(MIGIDs)
+		} else if hasAffinity || hasAntiAffinity || hasExclusion {
+			GPUIDs = pickCards(filterCardsByTags(cardList, labels), statusList, demand)
+		} else if pod.Spec.SchedulerName == nativeScheduler {
+			GPUIDs = randomCards(cardList, statusList, demand, util.NewRand(pod.GetNamespace(), pod.GetName(), "cards"))
+		} else {
+			GPUIDs = pickCards(cardList, statusList, demand)
 		}
 
-		scv.Status.FreeMemorySum = freeSum
-		scv.Status.CardList = cardList
+		if GPUIDs == nil {
+			nodecontroller.RecordGPUAllocationFailure(nodecontroller.AllocationNoCapacity)
+			klog.Errorf("Pod: %v/%v No %d GPU(s) available on Node: %v", pod.GetNamespace(), pod.GetName(), demand.Number, pod.Spec.NodeName)
+			continue
+		}
+
+		klog.V(4).Infof("Pod: %v/%v Set GPUs: %v", pod.GetNamespace(), pod.GetName(), GPUIDs)
+		labels[scheduleGPUID] = formatGPUIDs(GPUIDs)
+		if MIGIDs != nil {
+			labels[scheduleMIGID] = formatMIGIDs(MIGIDs)
+		}
+		updatePod := pod.DeepCopy()
+		updatePod.SetLabels(labels)
 
 		ops := []util.Ops{
 			{
 				Op:    "replace",
-				Path:  "/status",
-				Value: scv.Status,
+				Path:  "/metadata/labels",
+				Value: updatePod.GetLabels(),
 			},
 		}
 
-		err = r.Client.Patch(context.TODO(), scv, &util.Patch{PatchOps: ops})
-		if err != nil {
-			klog.Errorf("Scv: %v Patch Status Error: %v", scv.GetName(), err)
-		}
-	}
-
-	for _, pod := range podListWithNode {
-		labels := pod.GetLabels()
-		_, hasAffinity := labels[nodecontroller.Affinity]
-		antiAffinityTag, hasAntiAffinity := labels[nodecontroller.AntiAffinity]
-		exclusionTag, hasExclusion := labels[nodecontroller.Exclusion]
-		if hasAffinity || hasAntiAffinity || hasExclusion {
-			mem := StrToUint64(labels["scv/memory"])
-			minSub := uint64(0)
-			GPUID := 0
-			filterCardList := cardList.DeepCopy()
-			if hasAntiAffinity {
-				for _, card := range filterCardList {
-					for _, tag := range card.AntiAffinityTag {
-						if antiAffinityTag == tag {
-							filterCardList = RemoveCard(filterCardList, card)
-							break
-						}
-					}
-				}
+		if nodecontroller.HasTopology(statusList) {
+			score := nodecontroller.TopologyScore(statusList, GPUIDs)
+			klog.Infof("Pod: %v/%v GPUs: %v Topology Score: %.2f", pod.GetNamespace(), pod.GetName(), GPUIDs, score)
+			annotations := updatePod.GetAnnotations()
+			if annotations == nil {
+				annotations = make(map[string]string)
 			}
+			annotations[TopologyScoreAnnotation] = strconv.FormatFloat(score, 'f', 2, 64)
+			updatePod.SetAnnotations(annotations)
+			ops = append(ops, util.Ops{
+				Op:    "add",
+				Path:  "/metadata/annotations",
+				Value: updatePod.GetAnnotations(),
+			})
+		}
 
-			if hasExclusion {
-				for _, card := range filterCardList {
-					if len(card.ExclusionTag) != 0 {
-						for _, tag := range card.ExclusionTag {
-							if exclusionTag != tag {
-								filterCardList = RemoveCard(filterCardList, card)
-								break
-							}
-						}
-					}
-				}
-			}
-
-			for _, card := range filterCardList {
-				sub := card.FreeMemory - mem
-				if sub >= 0 && sub > minSub {
-					minSub = sub
-					GPUID = int(card.ID)
-				}
-			}
-
-			if _, ok := labels[scheduleGPUID]; !ok {
-				fmt.Println("start to set GPUID label")
-				labels[scheduleGPUID] = strconv.Itoa(GPUID)
-				updatePod := pod.DeepCopy()
-				updatePod.SetLabels(labels)
-
-				ops := []util.Ops{
-					{
-						Op:    "replace",
-						Path:  "/metadata/labels",
-						Value: updatePod.GetLabels(),
-					},
-				}
-
-				err = r.Client.Patch(context.TODO(), updatePod, &util.Patch{PatchOps: ops})
-
-				if err != nil {
-					klog.Errorf("Pod: %v/%v Patch Label Error: %v", updatePod.GetNamespace(), updatePod.GetName(), err)
-				}
-
-				cardList[GPUID].FreeMemory -= mem
-			}
-		} else if pod.Spec.SchedulerName == "native-scheduler" {
-			if _, ok := labels[scheduleGPUID]; !ok {
-				mem := int64(0)
-				for _, container := range pod.Spec.Containers {
-					quantity := container.Resources.Requests["gpu/memory"]
-					q := quantity.DeepCopy()
-					mem += q.Value()
-				}
-				rand.Seed(time.Now().UnixNano())
-				cardID := rand.Intn(4)
-				fmt.Println("start to set GPUID label--native scheduler")
-				labels[scheduleGPUID] = strconv.Itoa(cardID)
-				updatePod := pod.DeepCopy()
-				updatePod.SetLabels(labels)
-
-				ops := []util.Ops{
-					{
-						Op:    "replace",
-						Path:  "/metadata/labels",
-						Value: updatePod.GetLabels(),
-					},
-				}
-
-				err = r.Client.Patch(context.TODO(), updatePod, &util.Patch{PatchOps: ops})
-
-				if err != nil {
-					klog.Errorf("Pod: %v/%v Patch Label Error: %v", updatePod.GetNamespace(), updatePod.GetName(), err)
-				}
-
-				cardList[cardID].FreeMemory -= uint64(mem)
-			}
-		} else {
-			mem, _ := strconv.Atoi(labels["scv/memory"])
-			maxCard := 0
-			maxMemory := uint64(0)
-			for i, card := range cardList {
-				if maxMemory < card.FreeMemory {
-					maxCard = i
-					maxMemory = card.FreeMemory
-				}
-			}
-
-			if _, ok := labels[scheduleGPUID]; !ok {
-				fmt.Println("start to set GPUID label")
-				labels[scheduleGPUID] = strconv.Itoa(maxCard)
-				updatePod := pod.DeepCopy()
-				updatePod.SetLabels(labels)
-
-				ops := []util.Ops{
-					{
-						Op:    "replace",
-						Path:  "/metadata/labels",
-						Value: updatePod.GetLabels(),
-					},
-				}
-
-				err = r.Client.Patch(context.TODO(), updatePod, &util.Patch{PatchOps: ops})
-
-				if err != nil {
-					klog.Errorf("Pod: %v/%v Patch Label Error: %v", updatePod.GetNamespace(), updatePod.GetName(), err)
-				}
+		err = r.Client.Patch(context.TODO(), updatePod, &util.Patch{PatchOps: ops})
 
-				cardList[maxCard].FreeMemory -= uint64(mem)
-			}
+		if err != nil {
+			klog.Errorf("Pod: %v/%v Patch Label Error: %v", updatePod.GetNamespace(), updatePod.GetName(), err)
+			continue
 		}
-	}
 
-	freeSum := uint64(0)
-	for _, card := range cardList {
-		freeSum += card.FreeMemory
+		nodecontroller.AllocatePod(cardList, statusList, updatePod, demand)
+		podListWithNode[i] = *updatePod
 	}
 
-	scv.Status.FreeMemorySum = freeSum
-	scv.Status.CardList = cardList
+	util.Sleep(10 * time.Second)
 
-	ops := []util.Ops{
-		{
-			Op:    "replace",
-			Path:  "/status",
-			Value: scv.Status,
-		},
-	}
+	// The Scv is rebuilt from the pods, so that allocations and releases made
+	// meanwhile by other writers are kept.
+	err = nodecontroller.UpdateScv(ctx, r.Client, pod.Spec.NodeName, func(scv *scv1.Scv) bool {
+		cardList := scv.Status.CardList
+		statusList := nodecontroller.GetCardStatus(scv)
+		nodecontroller.RebuildCards(cardList, statusList, podListWithNode)
+		addAffinityTags(cardList, podListWithNode)
 
-	time.Sleep(10 * time.Second)
-
-	err = r.Client.Patch(context.TODO(), scv, &util.Patch{PatchOps: ops})
+		freeSum := uint64(0)
+		for _, card := range cardList {
+			freeSum += card.FreeMemory
+		}
+		scv.Status.FreeMemorySum = freeSum
+		scv.Status.CardList = cardList
+		nodecontroller.SetCardStatus(scv, statusList)
+		return true
+	})
 	if err != nil {
-		klog.Errorf("Scv: %v Patch Status Error: %v", scv.GetName(), err)
+		klog.Errorf("Node: %v Update Scv Error: %v", pod.Spec.NodeName, err)
 	}
+}
 
-	for _, pod := range podListWithNode {
+// addAffinityTags adds the affinity, anti-affinity and exclusion tags of the pods to
+// the cards they hold.
+func addAffinityTags(cardList scv1.CardList, pods []v1.Pod) {
+	for _, pod := range pods {
 		labels := pod.GetLabels()
-		isFound := false
+		GPUIDs, ok := nodecontroller.GetScheduledGPUIDs(labels)
+		if !ok {
+			continue
+		}
 
-		if value, ok := labels[nodecontroller.Affinity]; ok {
-			if id, ok := labels[scheduleGPUID]; ok {
-				GPUID, err := strconv.Atoi(id)
-				if err != nil {
-					fmt.Println("convert GPUID failed")
-				}
-				for _, tag := range cardList[GPUID].AffinityTag {
-					if value == tag {
-						isFound = true
-					}
-				}
+		for _, GPUID := range GPUIDs {
+			if GPUID >= len(cardList) {
+				klog.Errorf("Pod: %v/%v GPUID %d out of range", pod.GetNamespace(), pod.GetName(), GPUID)
+				continue
 			}
 
-			if !isFound {
-				if id, ok := labels[scheduleGPUID]; ok {
-					GPUID, err := strconv.Atoi(id)
-					if err != nil {
-						fmt.Println("convert GPUID failed")
-					}
+			if value, ok := labels[nodecontroller.Affinity]; ok {
+				if !containsParam(cardList[GPUID].AffinityTag, value) {
 					cardList[GPUID].AffinityTag = append(cardList[GPUID].AffinityTag, value)
-					fmt.Println(cardList[GPUID].AffinityTag)
 				}
-
 			}
-		}
 
-		if value, ok := labels[nodecontroller.AntiAffinity]; ok {
-			isFound = false
-			if id, ok := labels[scheduleGPUID]; ok {
-				GPUID, err := strconv.Atoi(id)
-				if err != nil {
-					fmt.Println("convert GPUID failed")
-				}
-				for _, tag := range cardList[GPUID].AntiAffinityTag {
-					if value == tag {
-						isFound = true
-					}
-				}
-			}
-			if !isFound {
-				if id, ok := labels[scheduleGPUID]; ok {
-					GPUID, err := strconv.Atoi(id)
-					if err != nil {
-						fmt.Println("convert GPUID failed")
-					}
+			if value, ok := labels[nodecontroller.AntiAffinity]; ok {
+				if !containsParam(cardList[GPUID].AntiAffinityTag, value) {
 					cardList[GPUID].AntiAffinityTag = append(cardList[GPUID].AntiAffinityTag, value)
 				}
-
 			}
-		}
 
-		if value, ok := labels[nodecontroller.Exclusion]; ok {
-			isFound = false
-			if id, ok := labels[scheduleGPUID]; ok {
-				GPUID, _ := strconv.Atoi(id)
+			if value, ok := labels[nodecontroller.Exclusion]; ok {
 				if len(cardList[GPUID].ExclusionTag) == 0 {
 					cardList[GPUID].ExclusionTag = append(cardList[GPUID].ExclusionTag, value)
-				} else {
-					for _, tag := range cardList[GPUID].ExclusionTag {
-						if value != tag {
-							fmt.Println("Pod exclusion tag mismatch with node")
-						}
-					}
+				} else if !containsParam(cardList[GPUID].ExclusionTag, value) {
+					klog.Warningf("Pod: %v/%v Exclusion Tag: %v Mismatch with Card: %v", pod.GetNamespace(), pod.GetName(), value, GPUID)
 				}
 			}
 		}
-
-		scv.Status.CardList = cardList
-
-		ops := []util.Ops{
-			{
-				Op:    "replace",
-				Path:  "/status",
-				Value: scv.Status,
-			},
-		}
-		err = r.Client.Patch(context.TODO(), scv, &util.Patch{PatchOps: ops})
-		if err != nil {
-			klog.Errorf("Scv: %v Patch Status Error: %v", scv.GetName(), err)
-		}
 	}
 }
 
 func (r *PodSimReconciler) cleanAffinityTags(labels map[string]string, ctx context.Context, pod *v1.Pod, nodeName string) {
-	fmt.Println("ready to clean affinity tags")
-	scv := &scv1.Scv{}
-	err := r.Client.Get(ctx, types.NamespacedName{Name: nodeName}, scv)
-	if err != nil {
-		klog.Errorf("Node: %v Get Scv Error: %v", nodeName, err)
+	klog.V(4).Infof("Pod: %v/%v Clean Affinity Tags", pod.GetNamespace(), pod.GetName())
+	GPUIDs, ok := nodecontroller.GetScheduledGPUIDs(labels)
+	if !ok {
 		return
 	}
 
-	cardList := scv.Status.CardList
-
-	podListWithGPU := make([]v1.Pod, 0)
 	podList := &v1.PodList{}
 
-	err = r.Client.List(ctx, podList, &client.MatchingLabels{
+	err := r.Client.List(ctx, podList, &client.MatchingLabels{
 		nodecontroller.ManageLabelKey: nodecontroller.ManageLabelValue,
 	})
 	if err != nil {
@@ -546,97 +467,49 @@ func (r *PodSimReconciler) cleanAffinityTags(labels map[string]string, ctx conte
 		return
 	}
 
-	for _, podItem := range podList.Items {
-		if pod.Spec.NodeName == podItem.Spec.NodeName && pod.GetName() != podItem.GetName() {
-			existLabels := podItem.GetLabels()
-			if existLabels[scheduleGPUID] == labels[scheduleGPUID] {
-				podListWithGPU = append(podListWithGPU, podItem)
+	err = nodecontroller.UpdateScv(ctx, r.Client, nodeName, func(scv *scv1.Scv) bool {
+		cardList := scv.Status.CardList
+		for _, GPUID := range GPUIDs {
+			if GPUID >= len(cardList) {
+				continue
 			}
-		}
-	}
-
-	fmt.Println("get pod list success")
 
-	alreadyExist := false
-	if value, ok := labels[nodecontroller.Affinity]; ok {
-		fmt.Println("get pod affinity success")
-		for _, podItem := range podListWithGPU {
-			existLabels := podItem.GetLabels()
-			if v, ok := existLabels[nodecontroller.Affinity]; ok {
-				fmt.Println("get  existing pod affinity success")
-				if value == v && pod.GetName() != podItem.GetName() {
-					alreadyExist = true
+			podListWithGPU := make([]v1.Pod, 0)
+			for _, podItem := range podList.Items {
+				if pod.Spec.NodeName == podItem.Spec.NodeName && pod.GetName() != podItem.GetName() {
+					if ids, ok := nodecontroller.GetScheduledGPUIDs(podItem.GetLabels()); ok && containsGPUID(ids, GPUID) {
+						podListWithGPU = append(podListWithGPU, podItem)
+					}
 				}
 			}
-		}
-		if !alreadyExist {
-			fmt.Println("start to delete affinity tag")
-			if gpuID, ok := labels[scheduleGPUID]; ok {
-				GPUID, err := strconv.Atoi(gpuID)
-				if err != nil {
-					fmt.Println("convert failed")
+
+			// A tag stays on the card as long as another pod on it still carries the tag.
+			alreadyExist := func(key, value string) bool {
+				for _, podItem := range podListWithGPU {
+					if v, ok := podItem.GetLabels()[key]; ok && v == value {
+						return true
+					}
 				}
-				cardList[GPUID].AffinityTag = RemoveParam(cardList[GPUID].AffinityTag, value)
-				fmt.Println(cardList[GPUID].AffinityTag)
+				return false
 			}
-		}
-	}
 
-	if value, ok := labels[nodecontroller.AntiAffinity]; ok {
-		alreadyExist = false
-		for _, podItem := range podListWithGPU {
-			existLabels := podItem.GetLabels()
-			if v, ok := existLabels[nodecontroller.AntiAffinity]; ok {
-				if value == v && pod.GetName() != podItem.GetName() {
-					alreadyExist = true
-				}
+			if value, ok := labels[nodecontroller.Affinity]; ok && !alreadyExist(nodecontroller.Affinity, value) {
+				cardList[GPUID].AffinityTag = RemoveParam(cardList[GPUID].AffinityTag, value)
 			}
-		}
-		if !alreadyExist {
-			if gpuID, ok := labels[scheduleGPUID]; ok {
-				GPUID, err := strconv.Atoi(gpuID)
-				if err != nil {
-					fmt.Println("convert failed")
-				}
+
+			if value, ok := labels[nodecontroller.AntiAffinity]; ok && !alreadyExist(nodecontroller.AntiAffinity, value) {
 				cardList[GPUID].AntiAffinityTag = RemoveParam(cardList[GPUID].AntiAffinityTag, value)
 			}
-		}
-	}
 
-	if value, ok := labels[nodecontroller.Exclusion]; ok {
-		alreadyExist = false
-		for _, podItem := range podListWithGPU {
-			existLabels := podItem.GetLabels()
-			if v, ok := existLabels[nodecontroller.Exclusion]; ok {
-				if value == v && pod.GetName() != podItem.GetName() {
-					alreadyExist = true
-				}
-			}
-		}
-		if !alreadyExist {
-			if gpuID, ok := labels[scheduleGPUID]; ok {
-				GPUID, err := strconv.Atoi(gpuID)
-				if err != nil {
-					fmt.Println("convert failed")
-				}
+			if value, ok := labels[nodecontroller.Exclusion]; ok && !alreadyExist(nodecontroller.Exclusion, value) {
 				cardList[GPUID].ExclusionTag = RemoveParam(cardList[GPUID].ExclusionTag, value)
 			}
 		}
-	}
-
-	scv.Status.CardList = cardList
-
-	ops := []util.Ops{
-		{
-			Op:    "replace",
-			Path:  "/status",
-			Value: scv.Status,
-		},
-	}
-
-	err = r.Client.Patch(context.TODO(), scv, &util.Patch{PatchOps: ops})
+		scv.Status.CardList = cardList
+		return true
+	})
 	if err != nil {
-		klog.Errorf("Scv: %v Patch Status Error: %v", scv.GetName(), err)
+		klog.Errorf("Node: %v Update Scv Error: %v", nodeName, err)
 	}
 }
 
@@ -656,6 +529,15 @@ func RemoveParam(sli []string, n string) []string {
 	return sli
 }
 
+func containsParam(sli []string, n string) bool {
+	for _, s := range sli {
+		if s == n {
+			return true
+		}
+	}
+	return false
+}
+
 func StrToUint64(str string) uint64 {
 	if i, e := strconv.Atoi(str); e != nil {
 		return 0
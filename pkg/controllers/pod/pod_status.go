@@ -0,0 +1,44 @@
+package pod
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// failedPodStatus returns the status of the pod after it failed for the given reason:
+// its running containers are killed with an Error and it is no longer ready.
+func failedPodStatus(pod *v1.Pod, reason, message string) v1.PodStatus {
+	updateTime := metav1.Now()
+	status := pod.Status.DeepCopy()
+	status.Phase = v1.PodFailed
+	status.Reason = reason
+	status.Message = message
+
+	for i, containerStatus := range status.ContainerStatuses {
+		if containerStatus.State.Terminated != nil {
+			continue
+		}
+		terminated := &v1.ContainerStateTerminated{
+			ExitCode:   killedExitCode,
+			Reason:     ErrorReason,
+			Message:    message,
+			FinishedAt: updateTime,
+		}
+		if containerStatus.State.Running != nil {
+			terminated.StartedAt = containerStatus.State.Running.StartedAt
+		}
+		started := false
+		status.ContainerStatuses[i].State = v1.ContainerState{Terminated: terminated}
+		status.ContainerStatuses[i].Ready = false
+		status.ContainerStatuses[i].Started = &started
+	}
+
+	for i, condition := range status.Conditions {
+		if condition.Type == v1.PodReady || condition.Type == v1.ContainersReady {
+			status.Conditions[i].Status = v1.ConditionFalse
+			status.Conditions[i].Reason = reason
+			status.Conditions[i].LastTransitionTime = updateTime
+		}
+	}
+	return *status
+}
@@ -0,0 +1,30 @@
+package pod
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+)
+
+func TestFailedPodStatus(t *testing.T) {
+	started := metav1.Now()
+	pod := &v1.Pod{Status: v1.PodStatus{
+		Phase: v1.PodRunning,
+		ContainerStatuses: []v1.ContainerStatus{
+			{Name: "app", State: v1.ContainerState{Running: &v1.ContainerStateRunning{StartedAt: started}}},
+			{Name: "done", State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{Reason: CompletedReason}}},
+		},
+	}}
+
+	status := failedPodStatus(pod, "Evicted", "The node was low on resource: memory.")
+	if status.Phase != v1.PodFailed || status.Reason != "Evicted" {
+		t.Errorf("pod %v with reason %q, want %v with reason Evicted", status.Phase, status.Reason, v1.PodFailed)
+	}
+	killed := status.ContainerStatuses[0].State.Terminated
+	if killed == nil || killed.ExitCode != killedExitCode || killed.Reason != ErrorReason || !killed.StartedAt.Equal(&started) {
+		t.Errorf("running container terminated as %+v, want exit code %v with reason %v", killed, killedExitCode, ErrorReason)
+	}
+	if done := status.ContainerStatuses[1].State.Terminated; done == nil || done.Reason != CompletedReason {
+		t.Errorf("completed container terminated as %+v, want it kept", done)
+	}
+}
@@ -0,0 +1,230 @@
+package pod
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+	"strconv"
+	"time"
+)
+
+const (
+	// Exit code of a container the kubelet stopped because its startup probe failed
+	probeFailedExitCode = 143
+
+	// Probe defaults of the API server
+	defaultProbePeriod           = 10 * time.Second
+	defaultProbeFailureThreshold = 3
+	defaultProbeSuccessThreshold = 1
+)
+
+// ProbeBehavior is how the probes of a container respond.
+type ProbeBehavior struct {
+	// StartupDuration is how long the container takes to start, its startup and
+	// readiness probes fail until then.
+	StartupDuration string `json:"startupDuration,omitempty"`
+	// ReadinessFailureProbability is the probability that a readiness probe fails once started.
+	ReadinessFailureProbability float64 `json:"readinessFailureProbability,omitempty"`
+
+	startupDuration time.Duration
+}
+
+// probeState is the readiness of a container between two updates.
+type probeState struct {
+	node      string
+	startedAt time.Time
+	// probes is the number of readiness probes run since the container started.
+	probes    int
+	failures  int
+	successes int
+	ready     bool
+}
+
+// probeEvent is a probe failure to report.
+type probeEvent struct {
+	container string
+	message   string
+}
+
+// probeBehaviors returns the behaviors in the ProbesAnnotation of the pod.
+func probeBehaviors(pod *v1.Pod) map[string]ProbeBehavior {
+	behaviors := make(map[string]ProbeBehavior)
+	value, ok := pod.GetAnnotations()[ProbesAnnotation]
+	if !ok {
+		return behaviors
+	}
+	if err := json.Unmarshal([]byte(value), &behaviors); err != nil {
+		klog.Errorf("Pod: %v/%v Invalid %v Annotation: %v", pod.GetNamespace(), pod.GetName(), ProbesAnnotation, err)
+		return behaviors
+	}
+	for name, behavior := range behaviors {
+		if behavior.StartupDuration == "" {
+			continue
+		}
+		duration, err := time.ParseDuration(behavior.StartupDuration)
+		if err != nil {
+			klog.Errorf("Pod: %v/%v Invalid Startup Duration of Container %v: %v", pod.GetNamespace(), pod.GetName(), name, err)
+		}
+		behavior.startupDuration = duration
+		behaviors[name] = behavior
+	}
+	return behaviors
+}
+
+func probeBehaviorOf(behaviors map[string]ProbeBehavior, name string) ProbeBehavior {
+	if behavior, ok := behaviors[name]; ok {
+		return behavior
+	}
+	return behaviors["*"]
+}
+
+// probeTimings returns the initial delay, period and thresholds of a probe with the defaults of the API server.
+func probeTimings(probe *v1.Probe) (delay, period time.Duration, failureThreshold, successThreshold int) {
+	delay = time.Duration(probe.InitialDelaySeconds) * time.Second
+	period = time.Duration(probe.PeriodSeconds) * time.Second
+	if period <= 0 {
+		period = defaultProbePeriod
+	}
+	failureThreshold = int(probe.FailureThreshold)
+	if failureThreshold <= 0 {
+		failureThreshold = defaultProbeFailureThreshold
+	}
+	successThreshold = int(probe.SuccessThreshold)
+	if successThreshold <= 0 {
+		successThreshold = defaultProbeSuccessThreshold
+	}
+	return delay, period, failureThreshold, successThreshold
+}
+
+// startupOutcome returns when, after the container started, its startup probe first
+// succeeds for a container taking startup to start, and whether the probe fails
+// failureThreshold times before that, in which case the kubelet kills it at killAt.
+func startupOutcome(probe *v1.Probe, startup time.Duration) (successAt time.Duration, killed bool, killAt time.Duration) {
+	delay, period, failureThreshold, _ := probeTimings(probe)
+	k := 0
+	if startup > delay {
+		k = int((startup - delay + period - 1) / period)
+	}
+	if k >= failureThreshold {
+		return 0, true, delay + time.Duration(failureThreshold-1)*period
+	}
+	return delay + time.Duration(k)*period, false, 0
+}
+
+// syncProbes runs the startup and readiness probes of the running containers and
+// sidecars of the pod that are due since the last update. A container whose startup
+// probe fails failureThreshold times is killed and restarts like a failed one. It
+// returns the probe failures that changed a container.
+func (c *ContainerUpdater) syncProbes(pod *v1.Pod, status *v1.PodStatus, sidecars map[string]bool, seen map[string]bool, now metav1.Time) []probeEvent {
+	behaviors := probeBehaviors(pod)
+	events := make([]probeEvent, 0)
+
+	sync := func(containerStatus *v1.ContainerStatus) {
+		running := containerStatus.State.Running
+		if running == nil {
+			return
+		}
+		container := containerOf(pod, containerStatus.Name)
+		behavior := probeBehaviorOf(behaviors, container.Name)
+		elapsed := util.Elapsed(running.StartedAt.Time, now.Time)
+
+		startedAfter := time.Duration(0)
+		if probe := container.StartupProbe; probe != nil {
+			successAt, killed, killAt := startupOutcome(probe, behavior.startupDuration)
+			if killed && elapsed >= killAt {
+				message := fmt.Sprintf("Container %v failed startup probe, will be restarted", container.Name)
+				terminateContainer(containerStatus, ErrorReason, probeFailedExitCode, message, now)
+				events = append(events, probeEvent{container: container.Name, message: "Startup probe failed"})
+				return
+			}
+			started := !killed && elapsed >= successAt
+			containerStatus.Started = &started
+			if !started {
+				containerStatus.Ready = false
+				return
+			}
+			startedAfter = successAt
+		}
+
+		probe := container.ReadinessProbe
+		if probe == nil {
+			containerStatus.Ready = true
+			return
+		}
+
+		key := string(pod.GetUID()) + "/" + container.Name
+		seen[key] = true
+		// The state is shared with forgetProbes, which may run for another node meanwhile.
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		state, ok := c.probes[key]
+		if !ok || !state.startedAt.Equal(running.StartedAt.Time) {
+			state = &probeState{node: pod.Spec.NodeName, startedAt: running.StartedAt.Time}
+			c.probes[key] = state
+		}
+
+		delay, period, failureThreshold, successThreshold := probeTimings(probe)
+		due := 0
+		if elapsed >= delay {
+			due = int((elapsed-delay)/period) + 1
+		}
+		// Only the last probes can change the readiness.
+		if skip := due - failureThreshold - successThreshold; skip > state.probes {
+			state.probes = skip
+		}
+		wasReady := state.ready
+		for ; state.probes < due; state.probes++ {
+			at := delay + time.Duration(state.probes)*period
+			if at < startedAfter {
+				continue
+			}
+			if at < behavior.startupDuration || (behavior.ReadinessFailureProbability > 0 && chance(pod, behavior.ReadinessFailureProbability,
+				"readiness", container.Name, strconv.Itoa(int(containerStatus.RestartCount)), strconv.Itoa(state.probes))) {
+				state.failures++
+				state.successes = 0
+				if state.failures >= failureThreshold {
+					state.ready = false
+				}
+			} else {
+				state.successes++
+				state.failures = 0
+				if state.successes >= successThreshold {
+					state.ready = true
+				}
+			}
+		}
+		if wasReady && !state.ready {
+			events = append(events, probeEvent{container: container.Name, message: "Readiness probe failed"})
+		}
+		containerStatus.Ready = state.ready
+	}
+
+	for i := range status.InitContainerStatuses {
+		if sidecars[status.InitContainerStatuses[i].Name] {
+			sync(&status.InitContainerStatuses[i])
+		}
+	}
+	for i := range status.ContainerStatuses {
+		sync(&status.ContainerStatuses[i])
+	}
+	return events
+}
+
+// forgetProbes drops the probe and crash state of the containers of the node that no longer run.
+func (c *ContainerUpdater) forgetProbes(nodeName string, seen map[string]bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for key, state := range c.probes {
+		if state.node == nodeName && !seen[key] {
+			delete(c.probes, key)
+		}
+	}
+	for key, state := range c.rolls {
+		if state.node == nodeName && !seen[key] {
+			delete(c.rolls, key)
+		}
+	}
+}
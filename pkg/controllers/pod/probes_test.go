@@ -0,0 +1,103 @@
+package pod
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStartupOutcome(t *testing.T) {
+	probe := &v1.Probe{InitialDelaySeconds: 5, PeriodSeconds: 2, FailureThreshold: 10}
+	tests := []struct {
+		name          string
+		probe         *v1.Probe
+		startup       time.Duration
+		wantSuccessAt time.Duration
+		wantKilled    bool
+		wantKillAt    time.Duration
+	}{
+		{
+			name:  "started at once",
+			probe: &v1.Probe{},
+		},
+		{
+			name:          "succeeds at the next period",
+			probe:         &v1.Probe{},
+			startup:       15 * time.Second,
+			wantSuccessAt: 20 * time.Second,
+		},
+		{
+			name:          "succeeds on the last probe",
+			probe:         &v1.Probe{},
+			startup:       20 * time.Second,
+			wantSuccessAt: 20 * time.Second,
+		},
+		{
+			name:       "killed after the failure threshold",
+			probe:      &v1.Probe{},
+			startup:    21 * time.Second,
+			wantKilled: true,
+			wantKillAt: 20 * time.Second,
+		},
+		{
+			name:          "started before the initial delay",
+			probe:         probe,
+			startup:       4 * time.Second,
+			wantSuccessAt: 5 * time.Second,
+		},
+		{
+			name:          "succeeds after the initial delay",
+			probe:         probe,
+			startup:       10 * time.Second,
+			wantSuccessAt: 11 * time.Second,
+		},
+		{
+			name:       "killed after the initial delay",
+			probe:      probe,
+			startup:    time.Minute,
+			wantKilled: true,
+			wantKillAt: 23 * time.Second,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			successAt, killed, killAt := startupOutcome(test.probe, test.startup)
+			if successAt != test.wantSuccessAt || killed != test.wantKilled || killAt != test.wantKillAt {
+				t.Errorf("startupOutcome(%v) = %v, %v, %v, want %v, %v, %v", test.startup,
+					successAt, killed, killAt, test.wantSuccessAt, test.wantKilled, test.wantKillAt)
+			}
+		})
+	}
+}
+
+// Run with -race: the readiness of a container is shared by the updates of its node.
+func TestSyncProbesConcurrent(t *testing.T) {
+	c := &ContainerUpdater{probes: make(map[string]*probeState), rolls: make(map[string]*rollState)}
+	started := metav1.NewTime(time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC))
+	pod := &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "app", UID: "uid"},
+		Spec: v1.PodSpec{
+			NodeName:   "node-0",
+			Containers: []v1.Container{{Name: "app", ReadinessProbe: &v1.Probe{PeriodSeconds: 10}}},
+		},
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			status := &v1.PodStatus{ContainerStatuses: []v1.ContainerStatus{{
+				Name:  "app",
+				State: v1.ContainerState{Running: &v1.ContainerStateRunning{StartedAt: started}},
+			}}}
+			c.syncProbes(pod, status, nil, make(map[string]bool), metav1.NewTime(started.Add(time.Duration(i)*time.Minute)))
+			if !status.ContainerStatuses[0].Ready {
+				t.Errorf("container not ready after its readiness probe succeeded")
+			}
+		}(i)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,79 @@
+package pod
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// GetPodQOS returns the QoS class of the pod as Kubernetes computes it from the CPU
+// and memory requests and limits of its containers and init containers:
+// BestEffort without any, Guaranteed when every container limits both and all
+// requests equal the limits, else Burstable.
+func GetPodQOS(pod *v1.Pod) v1.PodQOSClass {
+	requests := v1.ResourceList{}
+	limits := v1.ResourceList{}
+	zero := resource.MustParse("0")
+	guaranteed := true
+
+	containers := append(append([]v1.Container{}, pod.Spec.Containers...), pod.Spec.InitContainers...)
+	for _, container := range containers {
+		for name, quantity := range container.Resources.Requests {
+			if !isQOSResource(name) || quantity.Cmp(zero) <= 0 {
+				continue
+			}
+			sum := quantity.DeepCopy()
+			if total, ok := requests[name]; ok {
+				sum.Add(total)
+			}
+			requests[name] = sum
+		}
+
+		limited := make(map[v1.ResourceName]bool)
+		for name, quantity := range container.Resources.Limits {
+			if !isQOSResource(name) || quantity.Cmp(zero) <= 0 {
+				continue
+			}
+			limited[name] = true
+			sum := quantity.DeepCopy()
+			if total, ok := limits[name]; ok {
+				sum.Add(total)
+			}
+			limits[name] = sum
+		}
+		if !limited[v1.ResourceCPU] || !limited[v1.ResourceMemory] {
+			guaranteed = false
+		}
+	}
+
+	if len(requests) == 0 && len(limits) == 0 {
+		return v1.PodQOSBestEffort
+	}
+	if guaranteed {
+		for name, request := range requests {
+			if limit, ok := limits[name]; !ok || limit.Cmp(request) != 0 {
+				guaranteed = false
+				break
+			}
+		}
+	}
+	if guaranteed && len(requests) == len(limits) {
+		return v1.PodQOSGuaranteed
+	}
+	return v1.PodQOSBurstable
+}
+
+func isQOSResource(name v1.ResourceName) bool {
+	return name == v1.ResourceCPU || name == v1.ResourceMemory
+}
+
+// qosRank orders the QoS classes from the first to the last evicted.
+func qosRank(class v1.PodQOSClass) int {
+	switch class {
+	case v1.PodQOSBestEffort:
+		return 0
+	case v1.PodQOSBurstable:
+		return 1
+	default:
+		return 2
+	}
+}
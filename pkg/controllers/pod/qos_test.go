@@ -0,0 +1,112 @@
+package pod
+
+import (
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"testing"
+)
+
+// testResources returns the CPU and memory resources, skipping the empty ones.
+func testResources(cpu, memory string) v1.ResourceList {
+	list := v1.ResourceList{}
+	if cpu != "" {
+		list[v1.ResourceCPU] = resource.MustParse(cpu)
+	}
+	if memory != "" {
+		list[v1.ResourceMemory] = resource.MustParse(memory)
+	}
+	return list
+}
+
+func testContainer(requests, limits v1.ResourceList) v1.Container {
+	return v1.Container{Resources: v1.ResourceRequirements{Requests: requests, Limits: limits}}
+}
+
+func TestGetPodQOS(t *testing.T) {
+	tests := []struct {
+		name           string
+		containers     []v1.Container
+		initContainers []v1.Container
+		want           v1.PodQOSClass
+	}{
+		{
+			name:       "no resources",
+			containers: []v1.Container{{}},
+			want:       v1.PodQOSBestEffort,
+		},
+		{
+			name: "only other resources",
+			containers: []v1.Container{testContainer(
+				v1.ResourceList{ResourceGPUNumber: resource.MustParse("1")},
+				v1.ResourceList{ResourceGPUNumber: resource.MustParse("1")},
+			)},
+			want: v1.PodQOSBestEffort,
+		},
+		{
+			name:       "zero requests",
+			containers: []v1.Container{testContainer(testResources("0", "0"), nil)},
+			want:       v1.PodQOSBestEffort,
+		},
+		{
+			name:       "requests equal limits",
+			containers: []v1.Container{testContainer(testResources("1", "1Gi"), testResources("1", "1Gi"))},
+			want:       v1.PodQOSGuaranteed,
+		},
+		{
+			name:       "equal quantities in other units",
+			containers: []v1.Container{testContainer(testResources("1", "1Gi"), testResources("1000m", "1024Mi"))},
+			want:       v1.PodQOSGuaranteed,
+		},
+		{
+			name: "every container guaranteed",
+			containers: []v1.Container{
+				testContainer(testResources("1", "1Gi"), testResources("1", "1Gi")),
+				testContainer(testResources("500m", "256Mi"), testResources("500m", "256Mi")),
+			},
+			want: v1.PodQOSGuaranteed,
+		},
+		{
+			name:       "requests below limits",
+			containers: []v1.Container{testContainer(testResources("500m", "1Gi"), testResources("1", "1Gi"))},
+			want:       v1.PodQOSBurstable,
+		},
+		{
+			name:       "only requests",
+			containers: []v1.Container{testContainer(testResources("1", "1Gi"), nil)},
+			want:       v1.PodQOSBurstable,
+		},
+		{
+			name:       "memory not limited",
+			containers: []v1.Container{testContainer(testResources("1", "1Gi"), testResources("1", ""))},
+			want:       v1.PodQOSBurstable,
+		},
+		{
+			name: "one container without limits",
+			containers: []v1.Container{
+				testContainer(testResources("1", "1Gi"), testResources("1", "1Gi")),
+				{},
+			},
+			want: v1.PodQOSBurstable,
+		},
+		{
+			name:           "init container without limits",
+			containers:     []v1.Container{testContainer(testResources("1", "1Gi"), testResources("1", "1Gi"))},
+			initContainers: []v1.Container{testContainer(testResources("100m", ""), nil)},
+			want:           v1.PodQOSBurstable,
+		},
+		{
+			name:           "guaranteed init container",
+			containers:     []v1.Container{testContainer(testResources("1", "1Gi"), testResources("1", "1Gi"))},
+			initContainers: []v1.Container{testContainer(testResources("2", "2Gi"), testResources("2", "2Gi"))},
+			want:           v1.PodQOSGuaranteed,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pod := &v1.Pod{Spec: v1.PodSpec{Containers: test.containers, InitContainers: test.initContainers}}
+			if got := GetPodQOS(pod); got != test.want {
+				t.Errorf("GetPodQOS() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package pod
+
+import (
+	"context"
+	"fmt"
+	nodecontroller "github.com/NJUPT-ISL/NodeSimulator/pkg/controllers/node"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+	"time"
+)
+
+// defaultGracePeriod is the termination grace period of a pod that declares none.
+const defaultGracePeriod = 30 * time.Second
+
+// gracePeriod returns the grace period the pod was deleted with.
+func gracePeriod(pod *v1.Pod) time.Duration {
+	if pod.DeletionGracePeriodSeconds != nil {
+		return time.Duration(*pod.DeletionGracePeriodSeconds) * time.Second
+	}
+	if pod.Spec.TerminationGracePeriodSeconds != nil {
+		return time.Duration(*pod.Spec.TerminationGracePeriodSeconds) * time.Second
+	}
+	return defaultGracePeriod
+}
+
+// annotationDuration returns the duration in the given annotation of the pod, 0 without one.
+func annotationDuration(pod *v1.Pod, annotation string) time.Duration {
+	value, ok := pod.GetAnnotations()[annotation]
+	if !ok {
+		return 0
+	}
+	duration, err := time.ParseDuration(value)
+	if err != nil || duration < 0 {
+		klog.Errorf("Pod: %v/%v Invalid %v Annotation: %v", pod.GetNamespace(), pod.GetName(), annotation, value)
+		return 0
+	}
+	return duration
+}
+
+// shutdownTime returns when the containers of a deleted pod exit: after the preStop
+// hooks, if it has any, and the shutdown of the containers, unless the grace period
+// ends first and they are killed.
+func shutdownTime(pod *v1.Pod) (time.Time, bool) {
+	grace := gracePeriod(pod)
+	requested := util.Simulated(pod.GetDeletionTimestamp().Add(-grace))
+
+	shutdown := annotationDuration(pod, ShutdownDurationAnnotation)
+	for _, container := range pod.Spec.Containers {
+		if container.Lifecycle != nil && container.Lifecycle.PreStop != nil {
+			shutdown += annotationDuration(pod, PreStopDurationAnnotation)
+			break
+		}
+	}
+	if shutdown > grace {
+		return requested.Add(grace), true
+	}
+	return requested.Add(shutdown), false
+}
+
+// stopContainers shuts the containers of a deleted pod down like the kubelet. Until
+// they exit it returns how long to wait, then it records their termination and
+// reports the pod ready to be removed.
+func (r *PodSimReconciler) stopContainers(ctx context.Context, pod *v1.Pod) (time.Duration, bool) {
+	exitAt, killed := shutdownTime(pod)
+	if wait := util.Until(exitAt); wait > 0 {
+		if _, announced := r.terminating.LoadOrStore(pod.GetUID(), true); !announced && r.Recorder != nil {
+			for _, container := range pod.Spec.Containers {
+				r.Recorder.Eventf(pod, v1.EventTypeNormal, KillingReason, "Stopping container %v", container.Name)
+			}
+		}
+		return util.Wall(wait), false
+	}
+	r.terminating.Delete(pod.GetUID())
+
+	now := metav1.Now()
+	status := pod.Status.DeepCopy()
+	stop := func(statuses []v1.ContainerStatus) {
+		for i := range statuses {
+			if statuses[i].State.Running == nil {
+				continue
+			}
+			if killed {
+				message := fmt.Sprintf("Container %v was killed after the grace period of %v", statuses[i].Name, gracePeriod(pod))
+				terminateContainer(&statuses[i], ErrorReason, killedExitCode, message, now)
+			} else {
+				terminateContainer(&statuses[i], CompletedReason, 0, "", now)
+			}
+		}
+	}
+	stop(status.InitContainerStatuses)
+	stop(status.ContainerStatuses)
+	syncPodPhase(status, sidecarNames(ctx, r.Client, pod), now)
+
+	ops := []util.Ops{
+		{
+			Op:    "replace",
+			Path:  "/status",
+			Value: status,
+		},
+	}
+	start := time.Now()
+	err := r.Client.Status().Patch(ctx, pod, &util.Patch{PatchOps: ops})
+	nodecontroller.RecordPodStatusPatch(start)
+	if err != nil {
+		klog.Errorf("Pod: %v/%v Patch Status Error: %v", pod.GetNamespace(), pod.GetName(), err)
+	}
+	return 0, true
+}
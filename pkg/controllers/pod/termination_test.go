@@ -0,0 +1,107 @@
+package pod
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"testing"
+	"time"
+)
+
+func TestGracePeriod(t *testing.T) {
+	seconds := func(s int64) *int64 {
+		return &s
+	}
+	tests := []struct {
+		name     string
+		deletion *int64
+		spec     *int64
+		want     time.Duration
+	}{
+		{
+			name: "default",
+			want: 30 * time.Second,
+		},
+		{
+			name: "from the spec",
+			spec: seconds(60),
+			want: time.Minute,
+		},
+		{
+			name:     "from the deletion",
+			deletion: seconds(5),
+			spec:     seconds(60),
+			want:     5 * time.Second,
+		},
+		{
+			name:     "forced deletion",
+			deletion: seconds(0),
+			want:     0,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{DeletionGracePeriodSeconds: test.deletion},
+				Spec:       v1.PodSpec{TerminationGracePeriodSeconds: test.spec},
+			}
+			if got := gracePeriod(pod); got != test.want {
+				t.Errorf("gracePeriod() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestShutdownTime(t *testing.T) {
+	requested := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	preStop := &v1.Lifecycle{PreStop: &v1.Handler{Exec: &v1.ExecAction{Command: []string{"sleep", "5"}}}}
+	tests := []struct {
+		name        string
+		annotations map[string]string
+		lifecycle   *v1.Lifecycle
+		want        time.Duration
+		wantKilled  bool
+	}{
+		{
+			name: "exits at once",
+		},
+		{
+			name:        "shutdown duration",
+			annotations: map[string]string{ShutdownDurationAnnotation: "10s"},
+			want:        10 * time.Second,
+		},
+		{
+			name:        "preStop hook runs first",
+			annotations: map[string]string{ShutdownDurationAnnotation: "10s", PreStopDurationAnnotation: "5s"},
+			lifecycle:   preStop,
+			want:        15 * time.Second,
+		},
+		{
+			name:        "preStop duration without a hook",
+			annotations: map[string]string{PreStopDurationAnnotation: "5s"},
+		},
+		{
+			name:        "killed at the end of the grace period",
+			annotations: map[string]string{ShutdownDurationAnnotation: "20s", PreStopDurationAnnotation: "15s"},
+			lifecycle:   preStop,
+			want:        30 * time.Second,
+			wantKilled:  true,
+		},
+		{
+			name:        "invalid duration",
+			annotations: map[string]string{ShutdownDurationAnnotation: "soon"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			deletion := metav1.NewTime(requested.Add(defaultGracePeriod))
+			pod := &v1.Pod{
+				ObjectMeta: metav1.ObjectMeta{Annotations: test.annotations, DeletionTimestamp: &deletion},
+				Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "app", Lifecycle: test.lifecycle}}},
+			}
+			exitAt, killed := shutdownTime(pod)
+			if got := exitAt.Sub(requested); got != test.want || killed != test.wantKilled {
+				t.Errorf("shutdownTime() = %v, %v after the request, want %v, %v", got, killed, test.want, test.wantKilled)
+			}
+		})
+	}
+}
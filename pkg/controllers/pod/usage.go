@@ -0,0 +1,243 @@
+package pod
+
+import (
+	simv1 "github.com/NJUPT-ISL/NodeSimulator/pkg/api/v1"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// ContainerUsage is the simulated usage of a container.
+type ContainerUsage struct {
+	Name  string
+	Usage v1.ResourceList
+}
+
+// PodUsage is the simulated usage of a pod, sampled at Timestamp.
+type PodUsage struct {
+	Node       string
+	Timestamp  time.Time
+	Containers []ContainerUsage
+}
+
+// Total returns the usage of all containers of the pod.
+func (p PodUsage) Total() v1.ResourceList {
+	total := v1.ResourceList{}
+	for _, container := range p.Containers {
+		addResources(total, container.Usage)
+	}
+	return total
+}
+
+// nodeUsage is the simulated usage of the pods of a node, sampled at timestamp.
+type nodeUsage struct {
+	usage     v1.ResourceList
+	timestamp time.Time
+}
+
+// UsageTracker samples the CPU and memory usage of pods from their usage profiles
+// and keeps the last sample of every pod and node. A pod without a profile, in its
+// annotations or in its NodeSimulator, uses exactly what its containers request.
+type UsageTracker struct {
+	lock  sync.RWMutex
+	walks map[types.UID]map[string]float64
+	pods  map[types.UID]PodUsage
+	nodes map[string]nodeUsage
+}
+
+func NewUsageTracker() *UsageTracker {
+	return &UsageTracker{
+		walks: make(map[types.UID]map[string]float64),
+		pods:  make(map[types.UID]PodUsage),
+		nodes: make(map[string]nodeUsage),
+	}
+}
+
+// Sample returns the usage of the pod at now, defaults are the profiles of its NodeSimulator.
+// A container uses nothing while it is not running, and its profile starts over
+// when it restarts. CPU usage is throttled at the limit, memory usage is not.
+func (u *UsageTracker) Sample(pod *v1.Pod, defaults simv1.PodUsage, now time.Time) PodUsage {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	podStart := util.Simulated(pod.GetCreationTimestamp().Time)
+	if pod.Status.StartTime != nil {
+		podStart = util.Simulated(pod.Status.StartTime.Time)
+	}
+
+	usage := PodUsage{
+		Node:       pod.Spec.NodeName,
+		Timestamp:  now,
+		Containers: make([]ContainerUsage, 0, len(pod.Spec.Containers)),
+	}
+	for _, container := range pod.Spec.Containers {
+		start := podStart
+		if status, ok := containerStatusOf(pod.Status.ContainerStatuses, container.Name); ok {
+			if status.State.Running == nil {
+				usage.Containers = append(usage.Containers, ContainerUsage{
+					Name: container.Name,
+					Usage: v1.ResourceList{
+						v1.ResourceCPU:    *resource.NewMilliQuantity(0, resource.DecimalSI),
+						v1.ResourceMemory: *resource.NewQuantity(0, resource.BinarySI),
+					},
+				})
+				continue
+			}
+			start = util.Simulated(status.State.Running.StartedAt.Time)
+		}
+
+		cpuLevel := u.level(pod, container.Name, v1.ResourceCPU, CPUUsageAnnotation, defaults.CPU, now.Sub(start))
+		memoryLevel := u.level(pod, container.Name, v1.ResourceMemory, MemoryUsageAnnotation, defaults.Memory, now.Sub(start))
+		cpu := scaleQuantity(containerBase(container, v1.ResourceCPU), cpuLevel, true)
+		if limit, ok := container.Resources.Limits[v1.ResourceCPU]; ok && cpu.Cmp(limit) > 0 {
+			cpu = limit.DeepCopy()
+		}
+		usage.Containers = append(usage.Containers, ContainerUsage{
+			Name: container.Name,
+			Usage: v1.ResourceList{
+				v1.ResourceCPU:    cpu,
+				v1.ResourceMemory: scaleQuantity(containerBase(container, v1.ResourceMemory), memoryLevel, false),
+			},
+		})
+	}
+	return usage
+}
+
+// level returns the usage level of one resource of a container running for elapsed, 1 without a profile.
+func (u *UsageTracker) level(pod *v1.Pod, container string, name v1.ResourceName, annotation, defaultProfile string, elapsed time.Duration) float64 {
+	value, ok := pod.GetAnnotations()[annotation]
+	if !ok {
+		value = defaultProfile
+	}
+	if value == "" {
+		return 1
+	}
+	profile, err := util.ParseLoadProfile(value)
+	if err != nil {
+		klog.Errorf("Pod: %v/%v Invalid %v Usage Profile: %v", pod.GetNamespace(), pod.GetName(), name, err)
+		return 1
+	}
+
+	walks, ok := u.walks[pod.GetUID()]
+	if !ok {
+		walks = make(map[string]float64)
+		u.walks[pod.GetUID()] = walks
+	}
+	key := container + "/" + string(name)
+	last, ok := walks[key]
+	if !ok {
+		last = profile.Level
+	}
+	rnd := util.NewRand(pod.GetNamespace(), pod.GetName(), "usage", key, strconv.FormatInt(int64(elapsed/usageUpdatePeriod), 10))
+	level := profile.LevelAt(elapsed, last, rnd)
+	if profile.Type == util.RandomWalkLoad {
+		walks[key] = level
+	}
+	return level
+}
+
+// SetNodeUsage records the samples of the pods of a node and returns the usage of the node.
+// Pods of the node that are not sampled any more are forgotten.
+func (u *UsageTracker) SetNodeUsage(nodeName string, samples map[types.UID]PodUsage, now time.Time) v1.ResourceList {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	for uid, usage := range u.pods {
+		if _, ok := samples[uid]; !ok && usage.Node == nodeName {
+			u.forgetPod(uid)
+		}
+	}
+
+	total := v1.ResourceList{
+		v1.ResourceCPU:    *resource.NewMilliQuantity(0, resource.DecimalSI),
+		v1.ResourceMemory: *resource.NewQuantity(0, resource.BinarySI),
+	}
+	for uid, usage := range samples {
+		u.pods[uid] = usage
+		addResources(total, usage.Total())
+	}
+	u.nodes[nodeName] = nodeUsage{usage: total, timestamp: now}
+	return total
+}
+
+// ForgetNodes drops the samples of the nodes that are not in the list.
+func (u *UsageTracker) ForgetNodes(nodeList []v1.Node) {
+	exist := make(map[string]bool, len(nodeList))
+	for _, node := range nodeList {
+		exist[node.GetName()] = true
+	}
+
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	for nodeName := range u.nodes {
+		if !exist[nodeName] {
+			delete(u.nodes, nodeName)
+		}
+	}
+	for uid, usage := range u.pods {
+		if !exist[usage.Node] {
+			u.forgetPod(uid)
+		}
+	}
+}
+
+func (u *UsageTracker) forgetPod(uid types.UID) {
+	delete(u.pods, uid)
+	delete(u.walks, uid)
+}
+
+// PodUsage returns the last sample of the pod.
+func (u *UsageTracker) PodUsage(uid types.UID) (PodUsage, bool) {
+	u.lock.RLock()
+	defer u.lock.RUnlock()
+	usage, ok := u.pods[uid]
+	return usage, ok
+}
+
+// NodeUsage returns the last sample of the usage of the pods of the node.
+func (u *UsageTracker) NodeUsage(nodeName string) (v1.ResourceList, time.Time, bool) {
+	u.lock.RLock()
+	defer u.lock.RUnlock()
+	usage, ok := u.nodes[nodeName]
+	return usage.usage, usage.timestamp, ok
+}
+
+// containerBase returns what the usage of a container is relative to: its request, else its limit.
+func containerBase(container v1.Container, name v1.ResourceName) resource.Quantity {
+	if quantity, ok := container.Resources.Requests[name]; ok {
+		return quantity
+	}
+	if quantity, ok := container.Resources.Limits[name]; ok {
+		return quantity
+	}
+	return resource.Quantity{}
+}
+
+func scaleQuantity(base resource.Quantity, level float64, milli bool) resource.Quantity {
+	if milli {
+		return *resource.NewMilliQuantity(int64(float64(base.MilliValue())*level), resource.DecimalSI)
+	}
+	return *resource.NewQuantity(int64(float64(base.Value())*level), resource.BinarySI)
+}
+
+func containerStatusOf(statuses []v1.ContainerStatus, name string) (v1.ContainerStatus, bool) {
+	for _, status := range statuses {
+		if status.Name == name {
+			return status, true
+		}
+	}
+	return v1.ContainerStatus{}, false
+}
+
+func addResources(total, list v1.ResourceList) {
+	for name, quantity := range list {
+		sum := total[name]
+		sum.Add(quantity)
+		total[name] = sum
+	}
+}
@@ -0,0 +1,77 @@
+package pod
+
+import (
+	simv1 "github.com/NJUPT-ISL/NodeSimulator/pkg/api/v1"
+	nodecontroller "github.com/NJUPT-ISL/NodeSimulator/pkg/controllers/node"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"testing"
+	"time"
+)
+
+func TestPackedNodeMemoryPressure(t *testing.T) {
+	node, err := nodecontroller.GenNode(&simv1.NodeSimulator{Spec: simv1.NodeSimulatorSpec{
+		Cpu:       "8",
+		Memory:    "8Gi",
+		PodNumber: "110",
+		Disk:      "100Gi",
+		Bandwidth: "10G",
+	}})
+	if err != nil {
+		t.Fatalf("GenNode() error: %v", err)
+	}
+	node.SetName("node-0")
+
+	// Two pods requesting all the allocatable memory of the node between them
+	half := node.Status.Allocatable.Memory().DeepCopy()
+	half.Set(half.Value() / 2)
+	now := time.Date(2021, 1, 1, 1, 0, 0, 0, time.UTC)
+	newPod := func(name string, annotations map[string]string) v1.Pod {
+		start := metav1.NewTime(now.Add(-time.Hour))
+		return v1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, UID: types.UID(name), Annotations: annotations},
+			Spec: v1.PodSpec{
+				NodeName:   node.GetName(),
+				Containers: []v1.Container{testContainer(v1.ResourceList{v1.ResourceMemory: half}, nil)},
+			},
+			Status: v1.PodStatus{Phase: v1.PodRunning, StartTime: &start},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		pods     []v1.Pod
+		defaults simv1.PodUsage
+		want     bool
+	}{
+		{
+			name: "pods using their requests",
+			pods: []v1.Pod{newPod("a", nil), newPod("b", nil)},
+		},
+		{
+			name: "pod using more than its request",
+			pods: []v1.Pod{newPod("a", nil), newPod("b", map[string]string{MemoryUsageAnnotation: `{"type": "constant", "level": 1.1}`})},
+			want: true,
+		},
+		{
+			name:     "NodeSimulator profile above the requests",
+			pods:     []v1.Pod{newPod("a", nil), newPod("b", nil)},
+			defaults: simv1.PodUsage{Memory: `{"type": "constant", "level": 1.1}`},
+			want:     true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			tracker := NewUsageTracker()
+			samples := make(map[types.UID]PodUsage)
+			for i := range test.pods {
+				samples[test.pods[i].GetUID()] = tracker.Sample(&test.pods[i], test.defaults, now)
+			}
+			usage := tracker.SetNodeUsage(node.GetName(), samples, now)
+			if got := nodecontroller.UnderMemoryPressure(node, usage); got != test.want {
+				t.Errorf("UnderMemoryPressure(%v) = %v, want %v", usage.Memory().String(), got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package pod
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	simv1 "github.com/NJUPT-ISL/NodeSimulator/pkg/api/v1"
+	nodecontroller "github.com/NJUPT-ISL/NodeSimulator/pkg/controllers/node"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sort"
+	"time"
+)
+
+// usageUpdatePeriod is how often the usage of every pod is sampled.
+const usageUpdatePeriod = 10 * time.Second
+
+// PodUsageUpdater samples the CPU and memory usage of the running pods of every
+// simulated node, records the usage of the node in its NodeUsageAnnotation and,
+// like the kubelet, evicts a pod when the node runs out of memory.
+type PodUsageUpdater struct {
+	Client   client.Client
+	Recorder record.EventRecorder
+	Tracker  *UsageTracker
+	Queue    workqueue.RateLimitingInterface
+	StopChan chan struct{}
+}
+
+func NewPodUsageUpdater(updaterClient client.Client, recorder record.EventRecorder, tracker *UsageTracker, queue workqueue.RateLimitingInterface, stopChan chan struct{}) (*PodUsageUpdater, error) {
+	if updaterClient == nil || recorder == nil || tracker == nil || queue == nil || stopChan == nil {
+		return nil, errors.New("New PodUsageUpdater Error, parameters contains nil ")
+	}
+	return &PodUsageUpdater{
+		Client:   updaterClient,
+		Recorder: recorder,
+		Tracker:  tracker,
+		Queue:    queue,
+		StopChan: stopChan,
+	}, nil
+}
+
+func (u *PodUsageUpdater) processNextItem() bool {
+	ctx := context.TODO()
+	key, quit := u.Queue.Get()
+	if quit {
+		return false
+	}
+	defer u.Queue.Done(key)
+
+	if node, ok := key.(*v1.Node); ok {
+		u.SyncPodUsage(ctx, node)
+	} else {
+		klog.Errorf("Key in Queue is not Node Type. ")
+	}
+	return true
+}
+
+func (u *PodUsageUpdater) runWorker() {
+	for u.processNextItem() {
+	}
+}
+
+func (u *PodUsageUpdater) InitUpdater() {
+	for {
+		util.Tick(usageUpdatePeriod)
+		nodeList := &v1.NodeList{}
+		err := u.Client.List(context.TODO(), nodeList, &client.MatchingLabels{
+			nodecontroller.ManageLabelKey: nodecontroller.ManageLabelValue,
+		})
+		if err != nil {
+			klog.Errorf("List Node Error: %v", err)
+			continue
+		}
+		u.Tracker.ForgetNodes(nodeList.Items)
+		for _, node := range nodeList.Items {
+			u.Queue.Add(node.DeepCopy())
+		}
+	}
+}
+
+func (u *PodUsageUpdater) Run(threadiness int, stopCh chan struct{}) {
+	defer runtime.HandleCrash()
+
+	defer u.Queue.ShutDown()
+	klog.Info("Starting pod usage updater")
+
+	go u.InitUpdater()
+
+	for i := 0; i < threadiness; i++ {
+		go wait.Until(u.runWorker, time.Second, stopCh)
+	}
+
+	<-stopCh
+	klog.Info("Stopping pod usage updater")
+}
+
+func (u *PodUsageUpdater) SyncPodUsage(ctx context.Context, node *v1.Node) {
+	nodeName := node.GetName()
+	podList := &v1.PodList{}
+	err := u.Client.List(ctx, podList, &client.MatchingLabels{
+		nodecontroller.ManageLabelKey: nodecontroller.ManageLabelValue,
+	})
+	if err != nil {
+		klog.Errorf("List Pod Error: %v", err)
+		return
+	}
+
+	podListWithNode := make([]v1.Pod, 0)
+	for _, pod := range podList.Items {
+		if pod.Spec.NodeName == nodeName {
+			podListWithNode = append(podListWithNode, pod)
+		}
+	}
+
+	defaults := u.defaultUsage(ctx, node)
+	now := util.Now()
+	samples := make(map[types.UID]PodUsage)
+	for i := range podListWithNode {
+		pod := &podListWithNode[i]
+		if pod.Status.Phase == v1.PodRunning {
+			samples[pod.GetUID()] = u.Tracker.Sample(pod, defaults, now)
+		}
+	}
+	usage := u.Tracker.SetNodeUsage(nodeName, samples, now)
+
+	if nodecontroller.UnderMemoryPressure(node, usage) {
+		if uid, ok := u.evict(ctx, node, podListWithNode, samples); ok {
+			delete(samples, uid)
+			usage = u.Tracker.SetNodeUsage(nodeName, samples, now)
+		}
+	}
+	pods := make(map[v1.PodQOSClass]int)
+	for i := range podListWithNode {
+		if _, ok := samples[podListWithNode[i].GetUID()]; ok {
+			pods[GetPodQOS(&podListWithNode[i])]++
+		}
+	}
+	nodecontroller.RecordNodeUsage(node, usage, pods)
+
+	if old, ok := nodecontroller.GetNodeUsage(node); ok && equality.Semantic.DeepEqual(old, usage) {
+		return
+	}
+	value, err := json.Marshal(usage)
+	if err != nil {
+		klog.Errorf("Node: %v Marshal Usage Error: %v", nodeName, err)
+		return
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{nodecontroller.NodeUsageAnnotation: string(value)},
+		},
+	})
+	if err != nil {
+		klog.Errorf("Node: %v Marshal Usage Error: %v", nodeName, err)
+		return
+	}
+	if err := u.Client.Patch(ctx, node, client.RawPatch(types.MergePatchType, patch)); err != nil {
+		klog.Errorf("Node: %v Patch Usage Error: %v", nodeName, err)
+	}
+}
+
+// defaultUsage returns the usage profiles of the NodeSimulator of the node.
+func (u *PodUsageUpdater) defaultUsage(ctx context.Context, node *v1.Node) simv1.PodUsage {
+	nodeSimList := &simv1.NodeSimulatorList{}
+	if err := u.Client.List(ctx, nodeSimList); err != nil {
+		klog.Errorf("List NodeSim Error: %v", err)
+		return simv1.PodUsage{}
+	}
+	for _, nodeSim := range nodeSimList.Items {
+		if node.GetLabels()[nodecontroller.UniqueLabelKey] == nodeSim.GetNamespace()+"-"+nodeSim.GetName() {
+			return nodeSim.Spec.Usage
+		}
+	}
+	return simv1.PodUsage{}
+}
+
+// evict fails one running pod of a node under memory pressure, ranked like the kubelet
+// does: pods using more memory than they request first, then by priority, then by
+// QoS class from BestEffort to Guaranteed, then by how far their usage exceeds
+// their request.
+func (u *PodUsageUpdater) evict(ctx context.Context, node *v1.Node, pods []v1.Pod, samples map[types.UID]PodUsage) (types.UID, bool) {
+	candidates := make([]*v1.Pod, 0)
+	excess := make(map[types.UID]int64)
+	for i := range pods {
+		pod := &pods[i]
+		usage, ok := samples[pod.GetUID()]
+		if !ok || pod.GetDeletionTimestamp() != nil {
+			continue
+		}
+		request := int64(0)
+		for _, container := range pod.Spec.Containers {
+			request += container.Resources.Requests.Memory().Value()
+		}
+		total := usage.Total()
+		candidates = append(candidates, pod)
+		excess[pod.GetUID()] = total.Memory().Value() - request
+	}
+	if len(candidates) == 0 {
+		return "", false
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		ei, ej := excess[candidates[i].GetUID()], excess[candidates[j].GetUID()]
+		if (ei > 0) != (ej > 0) {
+			return ei > 0
+		}
+		if pi, pj := podPriority(candidates[i]), podPriority(candidates[j]); pi != pj {
+			return pi < pj
+		}
+		if qi, qj := qosRank(GetPodQOS(candidates[i])), qosRank(GetPodQOS(candidates[j])); qi != qj {
+			return qi < qj
+		}
+		return ei > ej
+	})
+
+	pod := candidates[0]
+	message := "The node was low on resource: memory. "
+	if usage, ok := samples[pod.GetUID()]; ok {
+		for _, container := range usage.Containers {
+			request := containerBase(containerOf(pod, container.Name), v1.ResourceMemory)
+			if container.Usage.Memory().Cmp(request) > 0 {
+				message += fmt.Sprintf("Container %v was using %v, which exceeds its request of %v. ", container.Name, container.Usage.Memory().String(), request.String())
+			}
+		}
+	}
+
+	status := failedPodStatus(pod, EvictedReason, message)
+	ops := []util.Ops{
+		{
+			Op:    "replace",
+			Path:  "/status",
+			Value: status,
+		},
+	}
+	start := time.Now()
+	err := u.Client.Status().Patch(ctx, pod, &util.Patch{PatchOps: ops})
+	nodecontroller.RecordPodStatusPatch(start)
+	if err != nil {
+		klog.Errorf("Pod: %v/%v Patch Status Error: %v", pod.GetNamespace(), pod.GetName(), err)
+		return "", false
+	}
+	pod.Status = status
+	klog.Infof("Node: %v Evicted Pod: %v/%v", node.GetName(), pod.GetNamespace(), pod.GetName())
+	u.Recorder.Event(pod, v1.EventTypeWarning, EvictedReason, message)
+
+	if _, ok := nodecontroller.GetScheduledGPUIDs(pod.GetLabels()); ok {
+		releaseGPUs(ctx, u.Client, node.GetName(), pods)
+	}
+	return pod.GetUID(), true
+}
+
+func podPriority(pod *v1.Pod) int32 {
+	if pod.Spec.Priority != nil {
+		return *pod.Spec.Priority
+	}
+	return 0
+}
+
+// containerOf returns the container or init container of the pod with the given name.
+func containerOf(pod *v1.Pod, name string) v1.Container {
+	for _, container := range pod.Spec.Containers {
+		if container.Name == name {
+			return container
+		}
+	}
+	for _, container := range pod.Spec.InitContainers {
+		if container.Name == name {
+			return container
+		}
+	}
+	return v1.Container{}
+}
@@ -0,0 +1,139 @@
+package workload
+
+import (
+	"fmt"
+	simv1 "github.com/NJUPT-ISL/NodeSimulator/pkg/api/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Distribution types
+const (
+	ConstantDistribution    = "constant"
+	UniformDistribution     = "uniform"
+	NormalDistribution      = "normal"
+	ExponentialDistribution = "exponential"
+	ChoiceDistribution      = "choice"
+)
+
+// Arrival types
+const (
+	PoissonArrival = "poisson"
+	BurstArrival   = "burst"
+)
+
+// distribution draws values of a Distribution, in the base unit of its values.
+type distribution struct {
+	spec                    simv1.Distribution
+	value, stddev, min, max float64
+	hasMin, hasMax          bool
+	values                  []float64
+}
+
+// quantityValue parses a quantity into its value, e.g. "500m" into 0.5.
+func quantityValue(value string) (float64, error) {
+	q, err := resource.ParseQuantity(value)
+	if err != nil {
+		return 0, err
+	}
+	return float64(q.MilliValue()) / 1000, nil
+}
+
+// durationValue parses a duration into seconds.
+func durationValue(value string) (float64, error) {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, err
+	}
+	return d.Seconds(), nil
+}
+
+// newDistribution parses the values of a distribution with parse. It returns nil
+// for a distribution without a type.
+func newDistribution(spec simv1.Distribution, parse func(string) (float64, error)) (*distribution, error) {
+	d := &distribution{spec: spec}
+	field := func(name, value string, target *float64, required bool) (bool, error) {
+		if value == "" {
+			if required {
+				return false, fmt.Errorf("%v distribution without %v", spec.Type, name)
+			}
+			return false, nil
+		}
+		v, err := parse(value)
+		if err != nil {
+			return false, fmt.Errorf("invalid %v %q of %v distribution", name, value, spec.Type)
+		}
+		*target = v
+		return true, nil
+	}
+
+	var err error
+	switch spec.Type {
+	case "":
+		return nil, nil
+	case ConstantDistribution, ExponentialDistribution:
+		_, err = field("value", spec.Value, &d.value, true)
+	case NormalDistribution:
+		if _, err = field("value", spec.Value, &d.value, true); err == nil {
+			_, err = field("stddev", spec.Stddev, &d.stddev, true)
+		}
+	case UniformDistribution:
+		if _, err = field("min", spec.Min, &d.min, true); err == nil {
+			_, err = field("max", spec.Max, &d.max, true)
+		}
+		if err == nil && d.max < d.min {
+			err = fmt.Errorf("max %v of uniform distribution below min %v", spec.Max, spec.Min)
+		}
+	case ChoiceDistribution:
+		if len(spec.Values) == 0 {
+			return nil, fmt.Errorf("choice distribution without values")
+		}
+		for _, value := range spec.Values {
+			v, err := parse(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid value %q of choice distribution", value)
+			}
+			d.values = append(d.values, v)
+		}
+	default:
+		return nil, fmt.Errorf("unknown distribution type %q", spec.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if spec.Type != UniformDistribution {
+		if d.hasMin, err = field("min", spec.Min, &d.min, false); err != nil {
+			return nil, err
+		}
+		if d.hasMax, err = field("max", spec.Max, &d.max, false); err != nil {
+			return nil, err
+		}
+	}
+	return d, nil
+}
+
+// sample draws a value from rnd, clamped to the bounds of the distribution and never negative.
+func (d *distribution) sample(rnd *rand.Rand) float64 {
+	var value float64
+	switch d.spec.Type {
+	case ConstantDistribution:
+		value = d.value
+	case UniformDistribution:
+		value = d.min + rnd.Float64()*(d.max-d.min)
+	case NormalDistribution:
+		value = d.value + rnd.NormFloat64()*d.stddev
+	case ExponentialDistribution:
+		value = rnd.ExpFloat64() * d.value
+	case ChoiceDistribution:
+		value = d.values[rnd.Intn(len(d.values))]
+	}
+	if d.hasMin {
+		value = math.Max(value, d.min)
+	}
+	if d.hasMax {
+		value = math.Min(value, d.max)
+	}
+	return math.Max(0, value)
+}
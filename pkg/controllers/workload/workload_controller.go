@@ -0,0 +1,266 @@
+/*
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package workload
+
+import (
+	"context"
+	"fmt"
+	simv1 "github.com/NJUPT-ISL/NodeSimulator/pkg/api/v1"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/controllers/pod"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/trace"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog"
+	"math"
+	"math/rand"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strconv"
+	"time"
+)
+
+const (
+	// GeneratorLabel holds the name of the WorkloadGenerator of a pod.
+	GeneratorLabel = "sim.k8s.io/workload-generator"
+
+	InvalidWorkloadReason = "InvalidWorkload"
+)
+
+// WorkloadGenerator phases
+const (
+	GeneratingPhase = "Generating"
+	RunningPhase    = "Running"
+	FinishedPhase   = "Finished"
+)
+
+// WorkloadGeneratorReconciler creates the pods of a WorkloadGenerator as they arrive
+type WorkloadGeneratorReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+// arrival is a pod of a workload, created offset after the WorkloadGenerator.
+type arrival struct {
+	offset time.Duration
+	job    trace.Job
+}
+
+// +kubebuilder:rbac:groups=sim.k8s.io,resources=workloadgenerators,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=sim.k8s.io,resources=workloadgenerators/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;create;update;patch;delete
+
+func (r *WorkloadGeneratorReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	generator := &simv1.WorkloadGenerator{}
+	if err := r.Client.Get(ctx, req.NamespacedName, generator); err != nil {
+		if !apierrors.IsNotFound(err) {
+			klog.Errorf("WorkloadGenerator: %v Error: %v ", req.NamespacedName.String(), err)
+		}
+		return ctrl.Result{}, nil
+	}
+	if generator.GetDeletionTimestamp() != nil {
+		return ctrl.Result{}, nil
+	}
+
+	arrivals, err := plan(generator)
+	if err != nil {
+		klog.Errorf("WorkloadGenerator: %v Invalid Spec: %v", req.NamespacedName.String(), err)
+		if r.Recorder != nil {
+			r.Recorder.Eventf(generator, v1.EventTypeWarning, InvalidWorkloadReason, "Invalid workload: %v", err)
+		}
+		return ctrl.Result{}, nil
+	}
+
+	podList := &v1.PodList{}
+	if err := r.Client.List(ctx, podList, client.InNamespace(generator.GetNamespace()), &client.MatchingLabels{
+		GeneratorLabel: generator.GetName(),
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+	created := make(map[string]bool)
+	status := simv1.WorkloadGeneratorStatus{Created: generator.Status.Created}
+	for _, item := range podList.Items {
+		created[item.GetName()] = true
+		switch item.Status.Phase {
+		case v1.PodRunning:
+			status.Running++
+		case v1.PodSucceeded:
+			status.Completed++
+		case v1.PodFailed:
+			status.Failed++
+		}
+	}
+
+	// Create the pods that arrived since the last one created, the names make it idempotent.
+	result := ctrl.Result{}
+	failed := false
+	elapsed := util.Since(util.Simulated(generator.GetCreationTimestamp().Time))
+	for i := range arrivals {
+		if arrivals[i].offset > elapsed {
+			result.RequeueAfter = util.Wall(arrivals[i].offset - elapsed)
+			break
+		}
+		status.Submitted++
+		name := fmt.Sprintf("%v-%d", generator.GetName(), i)
+		if i < status.Created {
+			continue
+		}
+		if !created[name] {
+			p := r.generatorPod(generator, name, &arrivals[i].job)
+			if err := ctrl.SetControllerReference(generator, p, r.Scheme); err != nil {
+				klog.Errorf("WorkloadGenerator: %v Set Owner of Pod: %v Error: %v", req.NamespacedName.String(), name, err)
+			}
+			if err := r.Client.Create(ctx, p); err != nil && !apierrors.IsAlreadyExists(err) {
+				klog.Errorf("WorkloadGenerator: %v Create Pod: %v Error: %v", req.NamespacedName.String(), name, err)
+				failed = true
+			}
+		}
+		if !failed {
+			status.Created = i + 1
+		}
+	}
+	if failed {
+		result.Requeue = true
+	}
+
+	switch {
+	case status.Submitted < len(arrivals):
+		status.Phase = GeneratingPhase
+	case status.Completed+status.Failed < len(arrivals):
+		status.Phase = RunningPhase
+	default:
+		status.Phase = FinishedPhase
+	}
+	if status != generator.Status {
+		ops := []util.Ops{
+			{
+				Op:    "add",
+				Path:  "/status",
+				Value: status,
+			},
+		}
+		if err := r.Client.Status().Patch(ctx, generator, &util.Patch{PatchOps: ops}); err != nil {
+			klog.Errorf("WorkloadGenerator: %v Patch Status Error: %v", req.NamespacedName.String(), err)
+		}
+	}
+	return result, nil
+}
+
+// generatorPod returns the pod of a job of the generator, failing with the job.
+func (r *WorkloadGeneratorReconciler) generatorPod(generator *simv1.WorkloadGenerator, name string, job *trace.Job) *v1.Pod {
+	p := trace.NewJobPod(job, generator.GetNamespace(), generator.Spec.SchedulerName)
+	p.SetGenerateName("")
+	p.SetName(name)
+	p.Labels[GeneratorLabel] = generator.GetName()
+	if generator.Spec.Duration.Type == "" {
+		delete(p.Annotations, pod.DurationAnnotation)
+	}
+	return p
+}
+
+// plan generates the pods of the generator with their arrivals. The arrivals and
+// demands are drawn in a fixed order from the seed of the generator, so that every
+// reconcile and every run with the same seed plans the same workload.
+func plan(generator *simv1.WorkloadGenerator) ([]arrival, error) {
+	spec := generator.Spec
+	interval, err := time.ParseDuration(spec.Arrival.Interval)
+	if err != nil || interval < 0 {
+		return nil, fmt.Errorf("invalid arrival interval %q", spec.Arrival.Interval)
+	}
+	if spec.Arrival.Type != PoissonArrival && spec.Arrival.Type != BurstArrival {
+		return nil, fmt.Errorf("unknown arrival type %q", spec.Arrival.Type)
+	}
+	if spec.Arrival.Type == BurstArrival && spec.Arrival.BurstSize <= 0 {
+		return nil, fmt.Errorf("burst arrival without burst size")
+	}
+
+	demands := make([]*distribution, 0, 5)
+	for _, d := range []struct {
+		name  string
+		spec  simv1.Distribution
+		parse func(string) (float64, error)
+	}{
+		{"gpuNumber", spec.GPUNumber, quantityValue},
+		{"gpuMemory", spec.GPUMemory, quantityValue},
+		{"cpu", spec.CPU, quantityValue},
+		{"memory", spec.Memory, quantityValue},
+		{"duration", spec.Duration, durationValue},
+	} {
+		distribution, err := newDistribution(d.spec, d.parse)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", d.name, err)
+		}
+		demands = append(demands, distribution)
+	}
+	failure := 0.0
+	if spec.FailureProbability != "" {
+		if failure, err = strconv.ParseFloat(spec.FailureProbability, 64); err != nil {
+			return nil, fmt.Errorf("invalid failure probability %q", spec.FailureProbability)
+		}
+	}
+
+	rnd := util.NewRand("workload", generator.GetNamespace(), generator.GetName())
+	if spec.Seed != 0 {
+		rnd = rand.New(rand.NewSource(spec.Seed))
+	}
+	sample := func(d *distribution) float64 {
+		if d == nil {
+			return 0
+		}
+		return d.sample(rnd)
+	}
+	arrivals := make([]arrival, 0, spec.Count)
+	offset := time.Duration(0)
+	for i := 0; i < spec.Count; i++ {
+		switch spec.Arrival.Type {
+		case PoissonArrival:
+			offset += time.Duration(rnd.ExpFloat64() * float64(interval))
+		case BurstArrival:
+			offset = time.Duration(i/spec.Arrival.BurstSize) * interval
+		}
+
+		job := trace.Job{
+			Name:      generator.GetName(),
+			Replicas:  1,
+			GPUNumber: int(math.Round(sample(demands[0]))),
+			GPUMemory: uint64(math.Round(sample(demands[1]))),
+			Labels:    spec.Labels,
+		}
+		if cpu := sample(demands[2]); cpu > 0 {
+			job.CPU = resource.NewMilliQuantity(int64(cpu*1000), resource.DecimalSI)
+		}
+		if memory := sample(demands[3]); memory > 0 {
+			job.Memory = resource.NewQuantity(int64(memory), resource.BinarySI)
+		}
+		job.Duration = time.Duration(sample(demands[4]) * float64(time.Second)).Round(time.Millisecond)
+		if failure > 0 {
+			job.Failed = rnd.Float64() < failure
+		}
+		arrivals = append(arrivals, arrival{offset: offset, job: job})
+	}
+	return arrivals, nil
+}
+
+func (r *WorkloadGeneratorReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&simv1.WorkloadGenerator{}).
+		Owns(&v1.Pod{}).
+		Complete(r)
+}
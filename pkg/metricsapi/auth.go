@@ -0,0 +1,255 @@
+package metricsapi
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"net/http"
+	"net/url"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+)
+
+// The ConfigMap the API server publishes the client CAs of the extension API servers in
+const (
+	authenticationNamespace = "kube-system"
+	authenticationConfigMap = "extension-apiserver-authentication"
+
+	clientCAKey            = "client-ca-file"
+	requestHeaderCAKey     = "requestheader-client-ca-file"
+	allowedNamesKey        = "requestheader-allowed-names"
+	usernameHeadersKey     = "requestheader-username-headers"
+	groupHeadersKey        = "requestheader-group-headers"
+	extraHeaderPrefixesKey = "requestheader-extra-headers-prefix"
+
+	bearerPrefix = "Bearer "
+)
+
+// userInfo is the authenticated client of a request.
+type userInfo struct {
+	name   string
+	uid    string
+	groups []string
+	extra  map[string][]string
+}
+
+// authenticator authenticates the clients of the API the way the aggregator expects
+// from an extension API server. The front proxy of the API server presents a
+// certificate of the requestheader client CA and passes the user in headers. Other
+// clients present a certificate of the client CA or a bearer token, which is checked
+// with a TokenReview.
+type authenticator struct {
+	client client.Client
+
+	requestHeaderCAs    *x509.CertPool
+	clientCAs           *x509.CertPool
+	allowedNames        []string
+	usernameHeaders     []string
+	groupHeaders        []string
+	extraHeaderPrefixes []string
+}
+
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get
+// +kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+// newAuthenticator reads the client CAs and the request headers of the front proxy
+// from the extension-apiserver-authentication ConfigMap. Without it, only bearer
+// tokens are accepted.
+func newAuthenticator(ctx context.Context, c client.Client, reader client.Reader) (*authenticator, error) {
+	a := &authenticator{client: c}
+	configMap := &v1.ConfigMap{}
+	if err := reader.Get(ctx, types.NamespacedName{Namespace: authenticationNamespace, Name: authenticationConfigMap}, configMap); err != nil {
+		return a, err
+	}
+
+	var err error
+	if a.clientCAs, err = certPool(configMap.Data[clientCAKey]); err != nil {
+		return a, fmt.Errorf("%v: %v", clientCAKey, err)
+	}
+	if a.requestHeaderCAs, err = certPool(configMap.Data[requestHeaderCAKey]); err != nil {
+		return a, fmt.Errorf("%v: %v", requestHeaderCAKey, err)
+	}
+	for key, list := range map[string]*[]string{
+		allowedNamesKey:        &a.allowedNames,
+		usernameHeadersKey:     &a.usernameHeaders,
+		groupHeadersKey:        &a.groupHeaders,
+		extraHeaderPrefixesKey: &a.extraHeaderPrefixes,
+	} {
+		if value, ok := configMap.Data[key]; ok && value != "" {
+			if err := json.Unmarshal([]byte(value), list); err != nil {
+				return a, fmt.Errorf("%v: %v", key, err)
+			}
+		}
+	}
+	return a, nil
+}
+
+func certPool(data string) (*x509.CertPool, error) {
+	if data == "" {
+		return nil, nil
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(data)) {
+		return nil, errors.New("no certificate found")
+	}
+	return pool, nil
+}
+
+// authenticate returns the client of the request.
+func (a *authenticator) authenticate(req *http.Request) (*userInfo, error) {
+	if req.TLS != nil && len(req.TLS.PeerCertificates) > 0 {
+		cert := req.TLS.PeerCertificates[0]
+		intermediates := x509.NewCertPool()
+		for _, c := range req.TLS.PeerCertificates[1:] {
+			intermediates.AddCert(c)
+		}
+		verify := func(roots *x509.CertPool) bool {
+			if roots == nil {
+				return false
+			}
+			_, err := cert.Verify(x509.VerifyOptions{
+				Roots:         roots,
+				Intermediates: intermediates,
+				KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			})
+			return err == nil
+		}
+
+		if verify(a.requestHeaderCAs) {
+			return a.frontProxyUser(req, cert.Subject.CommonName)
+		}
+		if verify(a.clientCAs) {
+			return &userInfo{name: cert.Subject.CommonName, groups: cert.Subject.Organization}, nil
+		}
+		return nil, errors.New("client certificate signed by an unknown authority")
+	}
+
+	if header := req.Header.Get("Authorization"); strings.HasPrefix(header, bearerPrefix) {
+		return a.tokenUser(req.Context(), strings.TrimPrefix(header, bearerPrefix))
+	}
+	return nil, errors.New("no client certificate or bearer token")
+}
+
+// frontProxyUser returns the user the front proxy passes in the request headers.
+func (a *authenticator) frontProxyUser(req *http.Request, commonName string) (*userInfo, error) {
+	if len(a.allowedNames) > 0 && !contains(a.allowedNames, commonName) {
+		return nil, fmt.Errorf("front proxy %q is not allowed", commonName)
+	}
+	user := &userInfo{extra: make(map[string][]string)}
+	for _, header := range a.usernameHeaders {
+		if user.name = req.Header.Get(header); user.name != "" {
+			break
+		}
+	}
+	if user.name == "" {
+		return nil, errors.New("front proxy passed no user")
+	}
+	for _, header := range a.groupHeaders {
+		user.groups = append(user.groups, req.Header[http.CanonicalHeaderKey(header)]...)
+	}
+	for header, values := range req.Header {
+		for _, prefix := range a.extraHeaderPrefixes {
+			if !strings.HasPrefix(strings.ToLower(header), strings.ToLower(prefix)) {
+				continue
+			}
+			key, err := url.PathUnescape(strings.ToLower(header[len(prefix):]))
+			if err != nil {
+				continue
+			}
+			user.extra[key] = append(user.extra[key], values...)
+		}
+	}
+	return user, nil
+}
+
+// tokenUser checks a bearer token with a TokenReview.
+func (a *authenticator) tokenUser(ctx context.Context, token string) (*userInfo, error) {
+	review := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: token},
+	}
+	if err := a.client.Create(ctx, review); err != nil {
+		return nil, err
+	}
+	if !review.Status.Authenticated {
+		return nil, fmt.Errorf("invalid bearer token: %v", review.Status.Error)
+	}
+	user := &userInfo{
+		name:   review.Status.User.Username,
+		uid:    review.Status.User.UID,
+		groups: review.Status.User.Groups,
+		extra:  make(map[string][]string),
+	}
+	for key, values := range review.Status.User.Extra {
+		user.extra[key] = values
+	}
+	return user, nil
+}
+
+// authorize reports whether the user may make the request, asked with a SubjectAccessReview.
+func (a *authenticator) authorize(req *http.Request, user *userInfo) (bool, error) {
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.name,
+			UID:    user.uid,
+			Groups: user.groups,
+			Extra:  make(map[string]authorizationv1.ExtraValue),
+		},
+	}
+	for key, values := range user.extra {
+		review.Spec.Extra[key] = values
+	}
+	if resource := resourceAttributes(req); resource != nil {
+		review.Spec.ResourceAttributes = resource
+	} else {
+		review.Spec.NonResourceAttributes = &authorizationv1.NonResourceAttributes{
+			Path: req.URL.Path,
+			Verb: strings.ToLower(req.Method),
+		}
+	}
+	if err := a.client.Create(req.Context(), review); err != nil {
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
+
+// resourceAttributes returns the metrics the request reads, nil for discovery.
+func resourceAttributes(req *http.Request) *authorizationv1.ResourceAttributes {
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	if len(parts) < 4 || parts[0] != "apis" || parts[1] != GroupName || parts[2] != Version {
+		return nil
+	}
+	attributes := &authorizationv1.ResourceAttributes{
+		Group:   GroupName,
+		Version: Version,
+		Verb:    "list",
+	}
+	if parts[3] == "namespaces" && len(parts) >= 6 {
+		attributes.Namespace = parts[4]
+		parts = append(parts[:3], parts[5:]...)
+	}
+	attributes.Resource = parts[3]
+	if len(parts) > 4 {
+		attributes.Name = parts[4]
+		attributes.Verb = "get"
+	}
+	if req.Method != http.MethodGet {
+		attributes.Verb = strings.ToLower(req.Method)
+	}
+	return attributes
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package metricsapi
+
+import (
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestResourceAttributes(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		want   *authorizationv1.ResourceAttributes
+	}{
+		{
+			name:   "discovery",
+			method: http.MethodGet,
+			path:   "/apis/metrics.k8s.io/v1beta1",
+		},
+		{
+			name:   "other group",
+			method: http.MethodGet,
+			path:   "/apis/apps/v1/deployments",
+		},
+		{
+			name:   "list nodes",
+			method: http.MethodGet,
+			path:   "/apis/metrics.k8s.io/v1beta1/nodes",
+			want:   &authorizationv1.ResourceAttributes{Group: GroupName, Version: Version, Resource: "nodes", Verb: "list"},
+		},
+		{
+			name:   "get node",
+			method: http.MethodGet,
+			path:   "/apis/metrics.k8s.io/v1beta1/nodes/node-0",
+			want:   &authorizationv1.ResourceAttributes{Group: GroupName, Version: Version, Resource: "nodes", Name: "node-0", Verb: "get"},
+		},
+		{
+			name:   "list pods of all namespaces",
+			method: http.MethodGet,
+			path:   "/apis/metrics.k8s.io/v1beta1/pods",
+			want:   &authorizationv1.ResourceAttributes{Group: GroupName, Version: Version, Resource: "pods", Verb: "list"},
+		},
+		{
+			name:   "list pods of a namespace",
+			method: http.MethodGet,
+			path:   "/apis/metrics.k8s.io/v1beta1/namespaces/default/pods",
+			want:   &authorizationv1.ResourceAttributes{Group: GroupName, Version: Version, Namespace: "default", Resource: "pods", Verb: "list"},
+		},
+		{
+			name:   "get pod",
+			method: http.MethodGet,
+			path:   "/apis/metrics.k8s.io/v1beta1/namespaces/default/pods/app",
+			want:   &authorizationv1.ResourceAttributes{Group: GroupName, Version: Version, Namespace: "default", Resource: "pods", Name: "app", Verb: "get"},
+		},
+		{
+			name:   "delete node",
+			method: http.MethodDelete,
+			path:   "/apis/metrics.k8s.io/v1beta1/nodes/node-0",
+			want:   &authorizationv1.ResourceAttributes{Group: GroupName, Version: Version, Resource: "nodes", Name: "node-0", Verb: "delete"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := resourceAttributes(httptest.NewRequest(test.method, test.path, nil))
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("resourceAttributes(%v %v) = %+v, want %+v", test.method, test.path, got, test.want)
+			}
+		})
+	}
+}
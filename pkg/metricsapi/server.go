@@ -0,0 +1,370 @@
+package metricsapi
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"fmt"
+	nodecontroller "github.com/NJUPT-ISL/NodeSimulator/pkg/controllers/node"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/controllers/pod"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
+	"math/big"
+	"net/http"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strings"
+	"time"
+)
+
+// metricsWindow is the sampling window reported with the usage, the period of the pod usage updater.
+const metricsWindow = 10 * time.Second
+
+// Server serves the metrics.k8s.io API for the simulated nodes and the pods running
+// on them, from the last usage sampled by the pod usage updater. It is registered
+// with the aggregator through an APIService and delegates the authentication and
+// authorization of its clients to the API server.
+type Server struct {
+	Client client.Client
+	Reader client.Reader
+	Addr   string
+	// CertFile and KeyFile are the serving certificate, self-signed when empty.
+	CertFile string
+	KeyFile  string
+
+	Tracker *pod.UsageTracker
+
+	auth *authenticator
+}
+
+func NewServer(serverClient client.Client, reader client.Reader, tracker *pod.UsageTracker, addr, certFile, keyFile string) (*Server, error) {
+	if serverClient == nil || reader == nil || tracker == nil || addr == "" {
+		return nil, errors.New("New Metrics API Server Error, parameters contains nil ")
+	}
+	return &Server{
+		Client:   serverClient,
+		Reader:   reader,
+		Addr:     addr,
+		CertFile: certFile,
+		KeyFile:  keyFile,
+		Tracker:  tracker,
+	}, nil
+}
+
+// Start serves the API until ctx is done. It implements manager.Runnable.
+func (s *Server) Start(ctx context.Context) error {
+	auth, err := newAuthenticator(ctx, s.Client, s.Reader)
+	if err != nil {
+		klog.Errorf("Metrics API Load Client CAs Error: %v", err)
+	}
+	s.auth = auth
+
+	// Client certificates are verified against the client CAs by the authenticator
+	tlsConfig := &tls.Config{ClientAuth: tls.RequestClientCert}
+	if s.CertFile == "" || s.KeyFile == "" {
+		cert, err := selfSignedCert()
+		if err != nil {
+			return err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	server := &http.Server{
+		Addr:      s.Addr,
+		Handler:   s,
+		TLSConfig: tlsConfig,
+	}
+	go func() {
+		<-ctx.Done()
+		_ = server.Shutdown(context.Background())
+	}()
+
+	klog.Infof("Starting metrics API server on %v", s.Addr)
+	err = server.ListenAndServeTLS(s.CertFile, s.KeyFile)
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	user, err := s.auth.authenticate(req)
+	if err != nil {
+		klog.V(4).Infof("Metrics API Authentication Error: %v", err)
+		writeStatus(w, apierrors.NewUnauthorized(err.Error()))
+		return
+	}
+	allowed, err := s.auth.authorize(req, user)
+	if err != nil {
+		writeStatus(w, apierrors.NewInternalError(err))
+		return
+	}
+	if !allowed {
+		resource := nodeResource()
+		if attributes := resourceAttributes(req); attributes != nil && attributes.Resource == podResource().Resource {
+			resource = podResource()
+		}
+		writeStatus(w, apierrors.NewForbidden(resource, "", fmt.Errorf("user %q cannot %v %v", user.name, strings.ToLower(req.Method), req.URL.Path)))
+		return
+	}
+
+	if req.Method != http.MethodGet {
+		writeStatus(w, apierrors.NewMethodNotSupported(nodeResource(), req.Method))
+		return
+	}
+	ctx := req.Context()
+	selector, err := labels.Parse(req.URL.Query().Get("labelSelector"))
+	if err != nil {
+		writeStatus(w, apierrors.NewBadRequest(err.Error()))
+		return
+	}
+
+	parts := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+	switch {
+	case len(parts) == 1 && parts[0] == "apis":
+		writeJSON(w, &metav1.APIGroupList{
+			TypeMeta: metav1.TypeMeta{Kind: "APIGroupList", APIVersion: "v1"},
+			Groups:   []metav1.APIGroup{apiGroup()},
+		})
+	case len(parts) == 2 && parts[1] == GroupName:
+		group := apiGroup()
+		group.TypeMeta = metav1.TypeMeta{Kind: "APIGroup", APIVersion: "v1"}
+		writeJSON(w, &group)
+	case len(parts) < 3 || parts[0] != "apis" || parts[1] != GroupName || parts[2] != Version:
+		writeStatus(w, apierrors.NewNotFound(nodeResource(), req.URL.Path))
+	case len(parts) == 3:
+		writeJSON(w, apiResources())
+	case len(parts) == 4 && parts[3] == "nodes":
+		s.listNodeMetrics(ctx, w, selector)
+	case len(parts) == 5 && parts[3] == "nodes":
+		s.getNodeMetrics(ctx, w, parts[4])
+	case len(parts) == 4 && parts[3] == "pods":
+		s.listPodMetrics(ctx, w, "", selector)
+	case len(parts) == 6 && parts[3] == "namespaces" && parts[5] == "pods":
+		s.listPodMetrics(ctx, w, parts[4], selector)
+	case len(parts) == 7 && parts[3] == "namespaces" && parts[5] == "pods":
+		s.getPodMetrics(ctx, w, parts[4], parts[6])
+	default:
+		writeStatus(w, apierrors.NewNotFound(nodeResource(), req.URL.Path))
+	}
+}
+
+func (s *Server) listNodeMetrics(ctx context.Context, w http.ResponseWriter, selector labels.Selector) {
+	nodeList := &v1.NodeList{}
+	if err := s.Client.List(ctx, nodeList, client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		writeStatus(w, apierrors.NewInternalError(err))
+		return
+	}
+	list := &NodeMetricsList{
+		TypeMeta: metav1.TypeMeta{Kind: "NodeMetricsList", APIVersion: GroupVersion},
+		Items:    make([]NodeMetrics, 0),
+	}
+	for _, node := range nodeList.Items {
+		if !isManaged(node.GetLabels()) {
+			continue
+		}
+		if metrics, ok := s.nodeMetrics(&node); ok {
+			list.Items = append(list.Items, metrics)
+		}
+	}
+	writeJSON(w, list)
+}
+
+func (s *Server) getNodeMetrics(ctx context.Context, w http.ResponseWriter, name string) {
+	node := &v1.Node{}
+	err := s.Client.Get(ctx, types.NamespacedName{Name: name}, node)
+	if err != nil {
+		writeStatus(w, err)
+		return
+	}
+	metrics, ok := s.nodeMetrics(node)
+	if !ok || !isManaged(node.GetLabels()) {
+		writeStatus(w, apierrors.NewNotFound(nodeResource(), name))
+		return
+	}
+	writeJSON(w, &metrics)
+}
+
+func (s *Server) listPodMetrics(ctx context.Context, w http.ResponseWriter, namespace string, selector labels.Selector) {
+	podList, err := s.runningPods(ctx, namespace)
+	if err != nil {
+		writeStatus(w, apierrors.NewInternalError(err))
+		return
+	}
+
+	list := &PodMetricsList{
+		TypeMeta: metav1.TypeMeta{Kind: "PodMetricsList", APIVersion: GroupVersion},
+		Items:    make([]PodMetrics, 0),
+	}
+	for i := range podList {
+		if !selector.Matches(labels.Set(podList[i].GetLabels())) {
+			continue
+		}
+		if metrics, ok := s.podMetrics(&podList[i]); ok {
+			list.Items = append(list.Items, metrics)
+		}
+	}
+	writeJSON(w, list)
+}
+
+func (s *Server) getPodMetrics(ctx context.Context, w http.ResponseWriter, namespace, name string) {
+	pod := &v1.Pod{}
+	err := s.Client.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, pod)
+	if err != nil {
+		writeStatus(w, err)
+		return
+	}
+	metrics, ok := s.podMetrics(pod)
+	if !ok || !isRunning(pod) {
+		writeStatus(w, apierrors.NewNotFound(podResource(), name))
+		return
+	}
+	writeJSON(w, &metrics)
+}
+
+// runningPods lists the running simulated pods, of all namespaces when namespace is empty.
+func (s *Server) runningPods(ctx context.Context, namespace string) ([]v1.Pod, error) {
+	podList := &v1.PodList{}
+	err := s.Client.List(ctx, podList, client.InNamespace(namespace), &client.MatchingLabels{
+		nodecontroller.ManageLabelKey: nodecontroller.ManageLabelValue,
+	})
+	if err != nil {
+		return nil, err
+	}
+	pods := make([]v1.Pod, 0, len(podList.Items))
+	for _, pod := range podList.Items {
+		if isRunning(&pod) {
+			pods = append(pods, pod)
+		}
+	}
+	return pods, nil
+}
+
+func (s *Server) nodeMetrics(node *v1.Node) (NodeMetrics, bool) {
+	usage, timestamp, ok := s.Tracker.NodeUsage(node.GetName())
+	if !ok {
+		return NodeMetrics{}, false
+	}
+	return NodeMetrics{
+		TypeMeta: metav1.TypeMeta{Kind: "NodeMetrics", APIVersion: GroupVersion},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              node.GetName(),
+			Labels:            node.GetLabels(),
+			CreationTimestamp: metav1.Now(),
+		},
+		Timestamp: metav1.Time{Time: timestamp},
+		Window:    metav1.Duration{Duration: metricsWindow},
+		Usage:     usage,
+	}, true
+}
+
+func (s *Server) podMetrics(p *v1.Pod) (PodMetrics, bool) {
+	usage, ok := s.Tracker.PodUsage(p.GetUID())
+	if !ok {
+		return PodMetrics{}, false
+	}
+	containers := make([]ContainerMetrics, 0, len(usage.Containers))
+	for _, container := range usage.Containers {
+		containers = append(containers, ContainerMetrics{Name: container.Name, Usage: container.Usage})
+	}
+	return PodMetrics{
+		TypeMeta: metav1.TypeMeta{Kind: "PodMetrics", APIVersion: GroupVersion},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              p.GetName(),
+			Namespace:         p.GetNamespace(),
+			Labels:            p.GetLabels(),
+			CreationTimestamp: metav1.Now(),
+		},
+		Timestamp:  metav1.Time{Time: usage.Timestamp},
+		Window:     metav1.Duration{Duration: metricsWindow},
+		Containers: containers,
+	}, true
+}
+
+func isManaged(labels map[string]string) bool {
+	return labels[nodecontroller.ManageLabelKey] == nodecontroller.ManageLabelValue
+}
+
+func isRunning(pod *v1.Pod) bool {
+	return isManaged(pod.GetLabels()) && pod.Status.Phase == v1.PodRunning
+}
+
+func apiGroup() metav1.APIGroup {
+	version := metav1.GroupVersionForDiscovery{GroupVersion: GroupVersion, Version: Version}
+	return metav1.APIGroup{
+		Name:             GroupName,
+		Versions:         []metav1.GroupVersionForDiscovery{version},
+		PreferredVersion: version,
+	}
+}
+
+func apiResources() *metav1.APIResourceList {
+	return &metav1.APIResourceList{
+		TypeMeta:     metav1.TypeMeta{Kind: "APIResourceList", APIVersion: "v1"},
+		GroupVersion: GroupVersion,
+		APIResources: []metav1.APIResource{
+			{Name: "nodes", Kind: "NodeMetrics", Namespaced: false, Verbs: metav1.Verbs{"get", "list"}},
+			{Name: "pods", Kind: "PodMetrics", Namespaced: true, Verbs: metav1.Verbs{"get", "list"}},
+		},
+	}
+}
+
+func nodeResource() schema.GroupResource {
+	return schema.GroupResource{Group: GroupName, Resource: "nodes"}
+}
+
+func podResource() schema.GroupResource {
+	return schema.GroupResource{Group: GroupName, Resource: "pods"}
+}
+
+func writeJSON(w http.ResponseWriter, obj interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(obj); err != nil {
+		klog.Errorf("Metrics API Write Response Error: %v", err)
+	}
+}
+
+func writeStatus(w http.ResponseWriter, err error) {
+	status := apierrors.NewInternalError(err).ErrStatus
+	if apiStatus, ok := err.(apierrors.APIStatus); ok {
+		status = apiStatus.Status()
+	}
+	status.TypeMeta = metav1.TypeMeta{Kind: "Status", APIVersion: "v1"}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(int(status.Code))
+	if err := json.NewEncoder(w).Encode(&status); err != nil {
+		klog.Errorf("Metrics API Write Response Error: %v", err)
+	}
+}
+
+// selfSignedCert returns a certificate for the serving address. It changes on every
+// start, so the aggregator cannot trust it through a caBundle: it is for local tests.
+func selfSignedCert() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "nodesimulator-metrics-api"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().AddDate(10, 0, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
@@ -0,0 +1,62 @@
+package metricsapi
+
+import (
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The types below mirror k8s.io/metrics/pkg/apis/metrics/v1beta1 as served by metrics-server.
+
+const (
+	GroupName    = "metrics.k8s.io"
+	Version      = "v1beta1"
+	GroupVersion = GroupName + "/" + Version
+)
+
+// NodeMetrics sets resource usage metrics of a node.
+type NodeMetrics struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Timestamp and Window describe the interval the usage was sampled in.
+	Timestamp metav1.Time     `json:"timestamp"`
+	Window    metav1.Duration `json:"window"`
+
+	// Usage is the memory and CPU usage of the node.
+	Usage v1.ResourceList `json:"usage"`
+}
+
+// NodeMetricsList is a list of NodeMetrics.
+type NodeMetricsList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []NodeMetrics `json:"items"`
+}
+
+// PodMetrics sets resource usage metrics of a pod.
+type PodMetrics struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// Timestamp and Window describe the interval the usage was sampled in.
+	Timestamp metav1.Time     `json:"timestamp"`
+	Window    metav1.Duration `json:"window"`
+
+	// Containers is the usage of each container of the pod.
+	Containers []ContainerMetrics `json:"containers"`
+}
+
+// PodMetricsList is a list of PodMetrics.
+type PodMetricsList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []PodMetrics `json:"items"`
+}
+
+// ContainerMetrics sets resource usage metrics of a container.
+type ContainerMetrics struct {
+	Name  string          `json:"name"`
+	Usage v1.ResourceList `json:"usage"`
+}
@@ -0,0 +1,152 @@
+package report
+
+import (
+	"context"
+	"errors"
+	nodecontroller "github.com/NJUPT-ISL/NodeSimulator/pkg/controllers/node"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sync"
+	"time"
+)
+
+// PodRecord is the life of a simulated pod: when it was created, bound by the
+// scheduler, started running and finished, zero while it has not.
+type PodRecord struct {
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	Node      string    `json:"node,omitempty"`
+	Phase     string    `json:"phase"`
+	GPUNumber int       `json:"gpuNumber"`
+	GPUMemory uint64    `json:"gpuMemory"`
+	Created   time.Time `json:"created"`
+	Scheduled time.Time `json:"scheduled"`
+	Running   time.Time `json:"running"`
+	Finished  time.Time `json:"finished"`
+}
+
+// Sample is the GPU usage of the cluster at a time.
+type Sample struct {
+	Time time.Time `json:"time"`
+	nodecontroller.ClusterUtilization
+}
+
+// Collector records the pods of the simulated nodes and samples the GPU usage of
+// the cluster every Interval, and writes the report of the experiment to Dir.
+type Collector struct {
+	Client   client.Client
+	Dir      string
+	Interval time.Duration
+
+	// lock guards pods, by UID so deleted pods are kept, and samples.
+	lock    sync.Mutex
+	start   time.Time
+	pods    map[types.UID]*PodRecord
+	samples []Sample
+}
+
+func NewCollector(collectorClient client.Client, dir string, interval time.Duration) (*Collector, error) {
+	if collectorClient == nil || dir == "" || interval <= 0 {
+		return nil, errors.New("New Report Collector Error, parameters contains nil ")
+	}
+	return &Collector{
+		Client:   collectorClient,
+		Dir:      dir,
+		Interval: interval,
+		start:    util.Now(),
+		pods:     make(map[types.UID]*PodRecord),
+	}, nil
+}
+
+// Start collects until ctx is done, writing the report after every sample and once
+// more when it stops. It implements manager.Runnable.
+func (c *Collector) Start(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			c.Collect(context.Background())
+			if err := c.Write(); err != nil {
+				klog.Errorf("Write Report Error: %v", err)
+			}
+			return nil
+		case <-time.After(util.TickPeriod(c.Interval)):
+			c.Collect(ctx)
+			if err := c.Write(); err != nil {
+				klog.Errorf("Write Report Error: %v", err)
+			}
+		}
+	}
+}
+
+// Collect records the managed pods and samples the GPU usage of the managed nodes.
+func (c *Collector) Collect(ctx context.Context) {
+	managed := &client.MatchingLabels{
+		nodecontroller.ManageLabelKey: nodecontroller.ManageLabelValue,
+	}
+	podList := &v1.PodList{}
+	if err := c.Client.List(ctx, podList, managed); err != nil {
+		klog.Errorf("List Pod Error: %v", err)
+	}
+	nodeList := &v1.NodeList{}
+	if err := c.Client.List(ctx, nodeList, managed); err != nil {
+		klog.Errorf("List Node Error: %v", err)
+		return
+	}
+	sample := Sample{
+		Time:               util.Now(),
+		ClusterUtilization: nodecontroller.GetClusterUtilization(ctx, c.Client, nodeList.Items),
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for i := range podList.Items {
+		record := podRecord(&podList.Items[i])
+		// Restarted containers report their last start, keep the first one.
+		if previous, ok := c.pods[podList.Items[i].GetUID()]; ok && !previous.Running.IsZero() &&
+			(record.Running.IsZero() || previous.Running.Before(record.Running)) {
+			record.Running = previous.Running
+		}
+		c.pods[podList.Items[i].GetUID()] = record
+	}
+	c.samples = append(c.samples, sample)
+}
+
+// podRecord reads the life of the pod from its status.
+func podRecord(p *v1.Pod) *PodRecord {
+	record := &PodRecord{
+		Namespace: p.GetNamespace(),
+		Name:      p.GetName(),
+		Node:      p.Spec.NodeName,
+		Phase:     string(p.Status.Phase),
+		Created:   util.Simulated(p.GetCreationTimestamp().Time),
+	}
+	if demand, err := nodecontroller.GetGPUDemand(p); err == nil && demand.Number > 0 {
+		record.GPUNumber, record.GPUMemory = demand.Number, demand.Memory
+	}
+	for _, condition := range p.Status.Conditions {
+		if condition.Type == v1.PodScheduled && condition.Status == v1.ConditionTrue {
+			record.Scheduled = util.Simulated(condition.LastTransitionTime.Time)
+		}
+	}
+
+	// The pod runs once its first container does, and finishes with its last one.
+	finished := p.Status.Phase == v1.PodSucceeded || p.Status.Phase == v1.PodFailed
+	for _, status := range p.Status.ContainerStatuses {
+		var startedAt time.Time
+		if status.State.Running != nil {
+			startedAt = util.Simulated(status.State.Running.StartedAt.Time)
+		} else if status.State.Terminated != nil {
+			startedAt = util.Simulated(status.State.Terminated.StartedAt.Time)
+			if finishedAt := util.Simulated(status.State.Terminated.FinishedAt.Time); finished && finishedAt.After(record.Finished) {
+				record.Finished = finishedAt
+			}
+		}
+		if !startedAt.IsZero() && (record.Running.IsZero() || startedAt.Before(record.Running)) {
+			record.Running = startedAt
+		}
+	}
+	return record
+}
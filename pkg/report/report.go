@@ -0,0 +1,240 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/util"
+	"io/ioutil"
+	v1 "k8s.io/api/core/v1"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Report files written to the directory of the Collector
+const (
+	ReportFile      = "report.json"
+	SummaryFile     = "summary.csv"
+	PodsFile        = "pods.csv"
+	UtilizationFile = "utilization.csv"
+)
+
+// Stats summarizes a set of values.
+type Stats struct {
+	Count int     `json:"count"`
+	Mean  float64 `json:"mean"`
+	P50   float64 `json:"p50"`
+	P90   float64 `json:"p90"`
+	P95   float64 `json:"p95"`
+	P99   float64 `json:"p99"`
+	Max   float64 `json:"max"`
+}
+
+// Report is the outcome of an experiment. Job completion time runs from the
+// creation to the end of a finished pod, queueing delay from its creation until it
+// runs, scheduling delay until it is bound. Makespan runs from the first creation
+// to the last end. Utilization, load and fragmentation are over the GPU samples.
+type Report struct {
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"`
+	Pods      int       `json:"pods"`
+	Pending   int       `json:"pending"`
+	Running   int       `json:"running"`
+	Succeeded int       `json:"succeeded"`
+	Failed    int       `json:"failed"`
+	// Makespan is in seconds, as are the job completion times and delays.
+	Makespan             float64 `json:"makespan"`
+	JobCompletionTime    Stats   `json:"jobCompletionTime"`
+	QueueingDelay        Stats   `json:"queueingDelay"`
+	SchedulingDelay      Stats   `json:"schedulingDelay"`
+	GPUMemoryUtilization Stats   `json:"gpuMemoryUtilization"`
+	GPULoad              Stats   `json:"gpuLoad"`
+	GPUFragmentation     Stats   `json:"gpuFragmentation"`
+}
+
+// newStats returns the mean, nearest-rank percentiles and maximum of the values.
+func newStats(values []float64) Stats {
+	if len(values) == 0 {
+		return Stats{}
+	}
+	sort.Float64s(values)
+	percentile := func(p float64) float64 {
+		rank := int(math.Ceil(p/100*float64(len(values)))) - 1
+		if rank < 0 {
+			rank = 0
+		}
+		return values[rank]
+	}
+	sum := 0.0
+	for _, value := range values {
+		sum += value
+	}
+	return Stats{
+		Count: len(values),
+		Mean:  sum / float64(len(values)),
+		P50:   percentile(50),
+		P90:   percentile(90),
+		P95:   percentile(95),
+		P99:   percentile(99),
+		Max:   values[len(values)-1],
+	}
+}
+
+// Report computes the report of the pods and samples collected so far.
+func (c *Collector) Report() *Report {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	report := &Report{Start: c.start, End: util.Now(), Pods: len(c.pods)}
+	jct, queueing, scheduling := make([]float64, 0), make([]float64, 0), make([]float64, 0)
+	var first, last time.Time
+	for _, record := range c.pods {
+		switch {
+		case record.Phase == string(v1.PodSucceeded):
+			report.Succeeded++
+		case record.Phase == string(v1.PodFailed):
+			report.Failed++
+		case !record.Running.IsZero():
+			report.Running++
+		default:
+			report.Pending++
+		}
+		if first.IsZero() || record.Created.Before(first) {
+			first = record.Created
+		}
+		if !record.Scheduled.IsZero() {
+			scheduling = append(scheduling, record.Scheduled.Sub(record.Created).Seconds())
+		}
+		if !record.Running.IsZero() {
+			queueing = append(queueing, record.Running.Sub(record.Created).Seconds())
+		}
+		if !record.Finished.IsZero() {
+			jct = append(jct, record.Finished.Sub(record.Created).Seconds())
+			if record.Finished.After(last) {
+				last = record.Finished
+			}
+		}
+	}
+	if !last.IsZero() {
+		report.Makespan = last.Sub(first).Seconds()
+	}
+	report.JobCompletionTime = newStats(jct)
+	report.QueueingDelay = newStats(queueing)
+	report.SchedulingDelay = newStats(scheduling)
+
+	memory, load, fragmentation := make([]float64, 0), make([]float64, 0), make([]float64, 0)
+	for _, sample := range c.samples {
+		memory = append(memory, sample.MemoryUtilization)
+		load = append(load, sample.Load)
+		fragmentation = append(fragmentation, sample.Fragmentation)
+	}
+	report.GPUMemoryUtilization = newStats(memory)
+	report.GPULoad = newStats(load)
+	report.GPUFragmentation = newStats(fragmentation)
+	return report
+}
+
+// Write writes the report as JSON and its summary, pods and GPU samples as CSV.
+func (c *Collector) Write() error {
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return err
+	}
+	report := c.Report()
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFile(filepath.Join(c.Dir, ReportFile), data); err != nil {
+		return err
+	}
+
+	summary := [][]string{{"metric", "count", "mean", "p50", "p90", "p95", "p99", "max"}}
+	for _, row := range []struct {
+		name  string
+		stats Stats
+	}{
+		{"makespan_seconds", newStats([]float64{report.Makespan})},
+		{"job_completion_time_seconds", report.JobCompletionTime},
+		{"queueing_delay_seconds", report.QueueingDelay},
+		{"scheduling_delay_seconds", report.SchedulingDelay},
+		{"gpu_memory_utilization", report.GPUMemoryUtilization},
+		{"gpu_load", report.GPULoad},
+		{"gpu_fragmentation", report.GPUFragmentation},
+	} {
+		summary = append(summary, append([]string{row.name, strconv.Itoa(row.stats.Count)},
+			formatFloats(row.stats.Mean, row.stats.P50, row.stats.P90, row.stats.P95, row.stats.P99, row.stats.Max)...))
+	}
+	if err := writeCSV(filepath.Join(c.Dir, SummaryFile), summary); err != nil {
+		return err
+	}
+
+	c.lock.Lock()
+	pods := [][]string{{"namespace", "name", "node", "phase", "gpu_number", "gpu_memory", "created", "scheduled", "running", "finished"}}
+	for _, record := range c.pods {
+		pods = append(pods, []string{record.Namespace, record.Name, record.Node, record.Phase,
+			strconv.Itoa(record.GPUNumber), strconv.FormatUint(record.GPUMemory, 10),
+			formatTime(record.Created), formatTime(record.Scheduled), formatTime(record.Running), formatTime(record.Finished)})
+	}
+	samples := [][]string{{"time", "gpu_memory_utilization", "gpu_load", "gpu_fragmentation", "idle_cards", "partial_cards"}}
+	for _, sample := range c.samples {
+		samples = append(samples, append([]string{formatTime(sample.Time)},
+			append(formatFloats(sample.MemoryUtilization, sample.Load, sample.Fragmentation),
+				strconv.Itoa(sample.IdleCards), strconv.Itoa(sample.PartialCards))...))
+	}
+	c.lock.Unlock()
+	rows := pods[1:]
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i][6] != rows[j][6] {
+			return rows[i][6] < rows[j][6]
+		}
+		return rows[i][0]+"/"+rows[i][1] < rows[j][0]+"/"+rows[j][1]
+	})
+	if err := writeCSV(filepath.Join(c.Dir, PodsFile), pods); err != nil {
+		return err
+	}
+	return writeCSV(filepath.Join(c.Dir, UtilizationFile), samples)
+}
+
+func formatFloats(values ...float64) []string {
+	formatted := make([]string, 0, len(values))
+	for _, value := range values {
+		formatted = append(formatted, strconv.FormatFloat(value, 'f', 4, 64))
+	}
+	return formatted
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// writeFile replaces a file through a temporary one, so readers never see it half written.
+func writeFile(path string, data []byte) error {
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func writeCSV(path string, rows [][]string) error {
+	tmp := path + ".tmp"
+	file, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	writer := csv.NewWriter(file)
+	if err := writer.WriteAll(rows); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
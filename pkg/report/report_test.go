@@ -0,0 +1,52 @@
+package report
+
+import "testing"
+
+// sequence returns the values 1 to n.
+func sequence(n int) []float64 {
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = float64(i + 1)
+	}
+	return values
+}
+
+func TestNewStats(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   Stats
+	}{
+		{
+			name: "no values",
+			want: Stats{},
+		},
+		{
+			name:   "single value",
+			values: []float64{3},
+			want:   Stats{Count: 1, Mean: 3, P50: 3, P90: 3, P95: 3, P99: 3, Max: 3},
+		},
+		{
+			name:   "unsorted values",
+			values: []float64{5, 1, 3, 2, 4},
+			want:   Stats{Count: 5, Mean: 3, P50: 3, P90: 5, P95: 5, P99: 5, Max: 5},
+		},
+		{
+			name:   "nearest rank",
+			values: sequence(10),
+			want:   Stats{Count: 10, Mean: 5.5, P50: 5, P90: 9, P95: 10, P99: 10, Max: 10},
+		},
+		{
+			name:   "hundred values",
+			values: sequence(100),
+			want:   Stats{Count: 100, Mean: 50.5, P50: 50, P90: 90, P95: 95, P99: 99, Max: 100},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := newStats(test.values); got != test.want {
+				t.Errorf("newStats(%v) = %+v, want %+v", test.values, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"time"
+)
+
+// Restore recreates the snapshot in a cluster, objects that exist already are left
+// as they are, so a restarted simulator restores nothing twice. It must run
+// before the controllers start: the Scvs keep the GPU ledger of the snapshot and the
+// pods are bound to their nodes with the GPUs in their labels, so nothing is placed
+// again. The times in the status of the pods are shifted to the current simulated
+// time, so their containers run for what was left of their durations.
+func Restore(ctx context.Context, c client.Client, snapshot *Snapshot) error {
+	failed := 0
+
+	namespaces := make(map[string]bool)
+	for _, nodeSim := range snapshot.NodeSimulators {
+		namespaces[nodeSim.GetNamespace()] = true
+	}
+	for _, pod := range snapshot.Pods {
+		namespaces[pod.GetNamespace()] = true
+	}
+	for name := range namespaces {
+		if err := ensureNamespace(ctx, c, name); err != nil {
+			klog.Errorf("Restore Namespace: %v Error: %v", name, err)
+			failed++
+		}
+	}
+
+	for i := range snapshot.NodeSimulators {
+		create(ctx, c, snapshot.NodeSimulators[i].DeepCopy(), &failed)
+	}
+
+	for i := range snapshot.Nodes {
+		node := snapshot.Nodes[i].DeepCopy()
+		status := node.Status
+		if !create(ctx, c, node, &failed) {
+			continue
+		}
+		if err := c.Status().Patch(ctx, node, &util.Patch{PatchOps: statusOps(status)}); err != nil {
+			klog.Errorf("Restore Node: %v Patch Status Error: %v", node.GetName(), err)
+			failed++
+		}
+	}
+
+	for i := range snapshot.Scvs {
+		create(ctx, c, snapshot.Scvs[i].DeepCopy(), &failed)
+	}
+
+	// A time as old in simulated time as it was at the snapshot, on the wall clock of the pod status
+	now := time.Now()
+	restored := func(t time.Time) time.Time {
+		return now.Add(-util.Wall(snapshot.Time.Sub(t)))
+	}
+	for i := range snapshot.Pods {
+		pod := snapshot.Pods[i].DeepCopy()
+		status := pod.Status.DeepCopy()
+		mapPodStatusTimes(status, restored)
+		if !create(ctx, c, pod, &failed) {
+			continue
+		}
+		if err := c.Status().Patch(ctx, pod, &util.Patch{PatchOps: statusOps(status)}); err != nil {
+			klog.Errorf("Restore Pod: %v/%v Patch Status Error: %v", pod.GetNamespace(), pod.GetName(), err)
+			failed++
+		}
+	}
+
+	klog.Infof("Restored %v NodeSims, %v Nodes, %v Scvs and %v Pods", len(snapshot.NodeSimulators), len(snapshot.Nodes), len(snapshot.Scvs), len(snapshot.Pods))
+	if failed > 0 {
+		return fmt.Errorf("%d objects of the snapshot not restored", failed)
+	}
+	return nil
+}
+
+// create creates an object of the snapshot and reports whether it did. Failures
+// other than an existing object are counted in failed.
+func create(ctx context.Context, c client.Client, obj client.Object, failed *int) bool {
+	err := c.Create(ctx, obj)
+	if err == nil {
+		return true
+	}
+	if apierrors.IsAlreadyExists(err) {
+		klog.Warningf("Restore %T: %v Already Exists", obj, client.ObjectKeyFromObject(obj))
+	} else {
+		klog.Errorf("Restore %T: %v Error: %v", obj, client.ObjectKeyFromObject(obj), err)
+		*failed++
+	}
+	return false
+}
+
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;create
+
+func ensureNamespace(ctx context.Context, c client.Client, name string) error {
+	namespace := &v1.Namespace{}
+	err := c.Get(ctx, types.NamespacedName{Name: name}, namespace)
+	if err == nil || !apierrors.IsNotFound(err) {
+		return err
+	}
+	namespace.SetName(name)
+	if err := c.Create(ctx, namespace); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+func statusOps(status interface{}) []util.Ops {
+	return []util.Ops{
+		{
+			Op:    "replace",
+			Path:  "/status",
+			Value: status,
+		},
+	}
+}
+
+// mapPodStatusTimes replaces the times of the status of a pod and its containers by
+// what mapTime returns for them.
+func mapPodStatusTimes(status *v1.PodStatus, mapTime func(t time.Time) time.Time) {
+	update := func(t *metav1.Time) {
+		if t != nil && !t.IsZero() {
+			t.Time = mapTime(t.Time)
+		}
+	}
+	update(status.StartTime)
+	for i := range status.Conditions {
+		update(&status.Conditions[i].LastProbeTime)
+		update(&status.Conditions[i].LastTransitionTime)
+	}
+	for _, statuses := range [][]v1.ContainerStatus{status.InitContainerStatuses, status.ContainerStatuses, status.EphemeralContainerStatuses} {
+		for i := range statuses {
+			for _, state := range []*v1.ContainerState{&statuses[i].State, &statuses[i].LastTerminationState} {
+				if state.Running != nil {
+					update(&state.Running.StartedAt)
+				}
+				if state.Terminated != nil {
+					update(&state.Terminated.StartedAt)
+					update(&state.Terminated.FinishedAt)
+				}
+			}
+		}
+	}
+}
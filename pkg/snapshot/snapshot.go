@@ -0,0 +1,122 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	simv1 "github.com/NJUPT-ISL/NodeSimulator/pkg/api/v1"
+	nodecontroller "github.com/NJUPT-ISL/NodeSimulator/pkg/controllers/node"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/util"
+	scv1 "github.com/NJUPT-ISL/SCV/api/v1"
+	"io/ioutil"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"os"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"time"
+)
+
+// Snapshot is the simulated state of a cluster: the NodeSimulators, their nodes, the
+// Scvs of the nodes with the card status annotation holding the GPU ledger, and the
+// managed pods with the GPUs recorded in their labels and their status. The times
+// of the pod status are kept in simulated time, so that they can be restored under
+// another clock.
+type Snapshot struct {
+	// Time is the simulated time the snapshot was taken at, in the clock of the simulator.
+	Time           time.Time             `json:"time"`
+	NodeSimulators []simv1.NodeSimulator `json:"nodeSimulators"`
+	Nodes          []v1.Node             `json:"nodes"`
+	Scvs           []scv1.Scv            `json:"scvs"`
+	Pods           []v1.Pod              `json:"pods"`
+}
+
+// Take returns the simulated state of the cluster. Pods being deleted are left out.
+func Take(ctx context.Context, c client.Client) (*Snapshot, error) {
+	snapshot := &Snapshot{Time: util.Now()}
+
+	nodeSimList := &simv1.NodeSimulatorList{}
+	if err := c.List(ctx, nodeSimList); err != nil {
+		return nil, err
+	}
+	for i := range nodeSimList.Items {
+		nodeSim := nodeSimList.Items[i].DeepCopy()
+		clean(nodeSim)
+		snapshot.NodeSimulators = append(snapshot.NodeSimulators, *nodeSim)
+	}
+
+	nodeList := &v1.NodeList{}
+	if err := c.List(ctx, nodeList, &client.MatchingLabels{
+		nodecontroller.ManageLabelKey: nodecontroller.ManageLabelValue,
+	}); err != nil {
+		return nil, err
+	}
+	for i := range nodeList.Items {
+		node := nodeList.Items[i].DeepCopy()
+		scv := &scv1.Scv{}
+		if err := c.Get(ctx, types.NamespacedName{Name: node.GetName()}, scv); err == nil {
+			clean(scv)
+			snapshot.Scvs = append(snapshot.Scvs, *scv)
+		} else if !apierrors.IsNotFound(err) {
+			return nil, err
+		}
+		clean(node)
+		snapshot.Nodes = append(snapshot.Nodes, *node)
+	}
+
+	podList := &v1.PodList{}
+	if err := c.List(ctx, podList, &client.MatchingLabels{
+		nodecontroller.ManageLabelKey: nodecontroller.ManageLabelValue,
+	}); err != nil {
+		return nil, err
+	}
+	for i := range podList.Items {
+		pod := podList.Items[i].DeepCopy()
+		if pod.GetDeletionTimestamp() != nil {
+			continue
+		}
+		clean(pod)
+		mapPodStatusTimes(&pod.Status, util.Simulated)
+		snapshot.Pods = append(snapshot.Pods, *pod)
+	}
+	return snapshot, nil
+}
+
+// clean drops the metadata set by the API server and the references to objects
+// outside the snapshot, so that the object can be created in another cluster.
+func clean(obj metav1.Object) {
+	obj.SetUID("")
+	obj.SetResourceVersion("")
+	obj.SetSelfLink("")
+	obj.SetGeneration(0)
+	obj.SetCreationTimestamp(metav1.Time{})
+	obj.SetManagedFields(nil)
+	obj.SetOwnerReferences(nil)
+	obj.SetFinalizers(nil)
+}
+
+// Write writes the snapshot as JSON through a temporary file, so readers never see it half written.
+func (s *Snapshot) Write(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Load reads a snapshot written by Write.
+func Load(path string) (*Snapshot, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := &Snapshot{}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
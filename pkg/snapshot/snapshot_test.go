@@ -0,0 +1,87 @@
+package snapshot
+
+import (
+	"io/ioutil"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMapPodStatusTimes(t *testing.T) {
+	at := func(minute int) metav1.Time {
+		return metav1.NewTime(time.Date(2021, 1, 1, 0, minute, 0, 0, time.UTC))
+	}
+	started, finished, scheduled := at(1), at(2), at(0)
+	status := v1.PodStatus{
+		StartTime:  &started,
+		Conditions: []v1.PodCondition{{Type: v1.PodScheduled, LastTransitionTime: scheduled}},
+		InitContainerStatuses: []v1.ContainerStatus{{
+			State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{StartedAt: started, FinishedAt: finished}},
+		}},
+		ContainerStatuses: []v1.ContainerStatus{{
+			State:                v1.ContainerState{Running: &v1.ContainerStateRunning{StartedAt: finished}},
+			LastTerminationState: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{StartedAt: started}},
+		}},
+	}
+
+	shifted, moved := at(11), at(12)
+	want := v1.PodStatus{
+		StartTime:  &shifted,
+		Conditions: []v1.PodCondition{{Type: v1.PodScheduled, LastTransitionTime: at(10)}},
+		InitContainerStatuses: []v1.ContainerStatus{{
+			State: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{StartedAt: shifted, FinishedAt: moved}},
+		}},
+		ContainerStatuses: []v1.ContainerStatus{{
+			State:                v1.ContainerState{Running: &v1.ContainerStateRunning{StartedAt: moved}},
+			LastTerminationState: v1.ContainerState{Terminated: &v1.ContainerStateTerminated{StartedAt: shifted}},
+		}},
+	}
+
+	mapPodStatusTimes(&status, func(t time.Time) time.Time { return t.Add(10 * time.Minute) })
+	if !reflect.DeepEqual(status, want) {
+		t.Errorf("mapPodStatusTimes() = %+v, want %+v", status, want)
+	}
+}
+
+func TestWriteLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "snapshot.json")
+
+	pod := v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace:       "default",
+		Name:            "app",
+		UID:             "uid",
+		ResourceVersion: "42",
+		Labels:          map[string]string{"scv/number": "1"},
+	}}
+	clean(&pod)
+	snapshot := &Snapshot{
+		Time: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		Pods: []v1.Pod{pod},
+	}
+	if err := snapshot.Write(path); err != nil {
+		t.Fatalf("Write() error: %v", err)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("Write() left its temporary file behind")
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if !loaded.Time.Equal(snapshot.Time) || !reflect.DeepEqual(loaded.Pods, snapshot.Pods) {
+		t.Errorf("Load() = %+v, want %+v", loaded, snapshot)
+	}
+	if got := loaded.Pods[0]; got.GetUID() != "" || got.GetResourceVersion() != "" {
+		t.Errorf("snapshot kept the UID %q and resource version %q", got.GetUID(), got.GetResourceVersion())
+	}
+}
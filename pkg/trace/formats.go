@@ -0,0 +1,250 @@
+package trace
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TraceLabelPrefix prefixes the labels carrying attributes of the trace, e.g. sim.k8s.io/trace-vc.
+const TraceLabelPrefix = "sim.k8s.io/trace-"
+
+var traceTimeLayouts = []string{"2006-01-02 15:04:05", time.RFC3339, "2006-01-02T15:04:05"}
+
+func parseTraceTime(value string) (time.Time, error) {
+	for _, layout := range traceTimeLayouts {
+		if t, err := time.Parse(layout, strings.TrimSpace(value)); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q", value)
+}
+
+// sinceEpoch returns a time as an offset, which Load makes relative to the first job.
+func sinceEpoch(t time.Time) time.Duration {
+	return t.Sub(time.Unix(0, 0))
+}
+
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
+
+// phillyJob is a job of the cluster_job_log of the Microsoft Philly trace.
+type phillyJob struct {
+	Status        string `json:"status"`
+	VC            string `json:"vc"`
+	JobID         string `json:"jobid"`
+	SubmittedTime string `json:"submitted_time"`
+	Attempts      []struct {
+		StartTime string `json:"start_time"`
+		EndTime   string `json:"end_time"`
+		Detail    []struct {
+			IP   string   `json:"ip"`
+			GPUs []string `json:"gpus"`
+		} `json:"detail"`
+	} `json:"attempts"`
+}
+
+// parsePhilly reads the cluster_job_log of the Philly trace. A job is replayed as its
+// last attempt, one pod per machine it ran on, and fails if its status is Failed.
+// Jobs that never ran are skipped.
+func parsePhilly(r io.Reader) ([]Job, error) {
+	decoder := json.NewDecoder(r)
+	if _, err := decoder.Token(); err != nil {
+		return nil, err
+	}
+
+	jobs := make([]Job, 0)
+	for decoder.More() {
+		record := phillyJob{}
+		if err := decoder.Decode(&record); err != nil {
+			return nil, err
+		}
+		if len(record.Attempts) == 0 {
+			continue
+		}
+		submitted, err := parseTraceTime(record.SubmittedTime)
+		if err != nil {
+			continue
+		}
+		attempt := record.Attempts[len(record.Attempts)-1]
+		start, err := parseTraceTime(attempt.StartTime)
+		if err != nil {
+			continue
+		}
+		end, err := parseTraceTime(attempt.EndTime)
+		if err != nil || end.Before(start) {
+			continue
+		}
+
+		job := Job{
+			Name:     record.JobID,
+			Submit:   sinceEpoch(submitted),
+			Duration: end.Sub(start),
+			Replicas: len(attempt.Detail),
+			Labels:   map[string]string{TraceLabelPrefix + "vc": record.VC},
+			Failed:   record.Status == "Failed",
+		}
+		for _, detail := range attempt.Detail {
+			if len(detail.GPUs) > job.GPUNumber {
+				job.GPUNumber = len(detail.GPUs)
+			}
+		}
+		if job.Replicas == 0 {
+			job.Replicas = 1
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// csvReader returns the reader of a CSV trace and the columns of its header. A
+// trace without a header, whose first row does not hold the first of the default
+// columns, is read with the default columns.
+func csvReader(r io.Reader, defaults []string) (*csv.Reader, map[string]int, []string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = false
+	first, err := reader.Read()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	header, pending := first, []string(nil)
+	if len(first) == 0 || strings.TrimSpace(first[0]) != defaults[0] {
+		header, pending = defaults, first
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.TrimSpace(name)] = i
+	}
+	return reader, columns, pending, nil
+}
+
+// field returns the value of a column of a CSV row, empty if missing.
+func field(row []string, columns map[string]int, name string) string {
+	if i, ok := columns[name]; ok && i < len(row) {
+		return strings.TrimSpace(row[i])
+	}
+	return ""
+}
+
+func parseFloat(value string) float64 {
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
+
+// paiColumns are the columns of the pai_task_table of the Alibaba PAI trace.
+var paiColumns = []string{"job_name", "task_name", "inst_num", "status", "start_time", "end_time", "plan_cpu", "plan_mem", "plan_gpu", "gpu_type"}
+
+// parsePAI reads the pai_task_table of the Alibaba PAI GPU trace. Every task is
+// replayed as a job of inst_num pods starting at its start time. plan_cpu and
+// plan_gpu are percents of a core and of a card, plan_mem is in GB. A fraction of
+// a card takes that fraction of the GPU memory of the options.
+func parsePAI(r io.Reader, options Options) ([]Job, error) {
+	reader, columns, row, err := csvReader(r, paiColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]Job, 0)
+	for ; ; row = nil {
+		if row == nil {
+			if row, err = reader.Read(); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		start, end := field(row, columns, "start_time"), field(row, columns, "end_time")
+		if start == "" || end == "" || parseFloat(end) < parseFloat(start) {
+			continue
+		}
+
+		job := Job{
+			Name:     field(row, columns, "job_name") + "-" + field(row, columns, "task_name"),
+			Submit:   seconds(parseFloat(start)),
+			Duration: seconds(parseFloat(end) - parseFloat(start)),
+			Replicas: int(parseFloat(field(row, columns, "inst_num"))),
+			Labels:   map[string]string{},
+			Failed:   field(row, columns, "status") == "Failed",
+		}
+		if job.Replicas <= 0 {
+			job.Replicas = 1
+		}
+		if gpuType := field(row, columns, "gpu_type"); gpuType != "" {
+			job.Labels[TraceLabelPrefix+"gpu-type"] = gpuType
+		}
+		if cpu := parseFloat(field(row, columns, "plan_cpu")); cpu > 0 {
+			job.CPU = resource.NewMilliQuantity(int64(cpu*10), resource.DecimalSI)
+		}
+		if memory := parseFloat(field(row, columns, "plan_mem")); memory > 0 {
+			job.Memory = resource.NewQuantity(int64(memory*(1<<30)), resource.BinarySI)
+		}
+		if gpu := parseFloat(field(row, columns, "plan_gpu")); gpu >= 100 {
+			job.GPUNumber = int(math.Ceil(gpu / 100))
+		} else if gpu > 0 {
+			job.GPUNumber = 1
+			job.GPUMemory = uint64(gpu / 100 * float64(options.GPUMemory))
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// heliosColumns are the columns of the cluster_log of the SenseTime Helios trace.
+var heliosColumns = []string{"job_id", "user", "vc", "gpu_num", "cpu_num", "node_num", "state", "submit_time", "start_time", "end_time", "duration"}
+
+// parseHelios reads the cluster_log of the Helios trace. A job on several nodes is
+// replayed as one pod per node sharing its GPUs, and fails if its state is FAILED
+// or NODE_FAIL.
+func parseHelios(r io.Reader) ([]Job, error) {
+	reader, columns, row, err := csvReader(r, heliosColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	jobs := make([]Job, 0)
+	for ; ; row = nil {
+		if row == nil {
+			if row, err = reader.Read(); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		submitted, err := parseTraceTime(field(row, columns, "submit_time"))
+		if err != nil {
+			continue
+		}
+		state := field(row, columns, "state")
+		job := Job{
+			Name:     field(row, columns, "job_id"),
+			Submit:   sinceEpoch(submitted),
+			Duration: seconds(parseFloat(field(row, columns, "duration"))),
+			Replicas: int(parseFloat(field(row, columns, "node_num"))),
+			Labels:   map[string]string{TraceLabelPrefix + "vc": field(row, columns, "vc")},
+			Failed:   state == "FAILED" || state == "NODE_FAIL",
+		}
+		if job.Replicas <= 0 {
+			job.Replicas = 1
+		}
+		if gpus := int(parseFloat(field(row, columns, "gpu_num"))); gpus > 0 {
+			job.GPUNumber = (gpus + job.Replicas - 1) / job.Replicas
+		}
+		if cpus := parseFloat(field(row, columns, "cpu_num")); cpus > 0 {
+			job.CPU = resource.NewMilliQuantity(int64(cpus*1000)/int64(job.Replicas), resource.DecimalSI)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
@@ -0,0 +1,158 @@
+package trace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	nodecontroller "github.com/NJUPT-ISL/NodeSimulator/pkg/controllers/node"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/controllers/pod"
+	"github.com/NJUPT-ISL/NodeSimulator/pkg/util"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// JobLabel holds the name of the trace job of a replayed pod.
+	JobLabel = "sim.k8s.io/trace-job"
+
+	scvNumberLabel = "scv/number"
+	scvMemoryLabel = "scv/memory"
+
+	replayImage     = "nodesim/trace-job"
+	replayContainer = "main"
+)
+
+// Replayer submits the jobs of a trace as pods at their arrival times. The pods run
+// for the duration of their job and complete through the pod simulator.
+type Replayer struct {
+	Client        client.Client
+	Jobs          []Job
+	Namespace     string
+	SchedulerName string
+	// Speed scales the arrival times and durations of the trace, 2 replays it twice as fast.
+	Speed float64
+}
+
+func NewReplayer(replayerClient client.Client, jobs []Job, namespace, schedulerName string, speed float64) (*Replayer, error) {
+	if replayerClient == nil || jobs == nil || namespace == "" || speed <= 0 {
+		return nil, errors.New("New Trace Replayer Error, parameters contains nil ")
+	}
+	return &Replayer{
+		Client:        replayerClient,
+		Jobs:          jobs,
+		Namespace:     namespace,
+		SchedulerName: schedulerName,
+		Speed:         speed,
+	}, nil
+}
+
+// Start submits the jobs until the trace ends or ctx is done. It implements manager.Runnable.
+func (r *Replayer) Start(ctx context.Context) error {
+	start := util.Now()
+	klog.Infof("Replaying %v Trace Jobs", len(r.Jobs))
+	for i := range r.Jobs {
+		job := &r.Jobs[i]
+		if wait := util.Until(start.Add(r.scale(job.Submit))); wait > 0 {
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(util.Wall(wait)):
+			}
+		}
+		scaled := *job
+		scaled.Duration = r.scale(job.Duration)
+		for replica := 0; replica < job.Replicas; replica++ {
+			p := NewJobPod(&scaled, r.Namespace, r.SchedulerName)
+			// Every replay names the pods alike, so that their random decisions follow the seed.
+			p.Name, p.GenerateName = fmt.Sprintf("%v%d-%d", p.GenerateName, i, replica), ""
+			if err := r.Client.Create(ctx, p); err != nil {
+				klog.Errorf("Trace Job: %v Create Pod Error: %v", job.Name, err)
+			}
+		}
+	}
+	klog.Infof("Trace Replay Finished after %v", util.Since(start))
+	return nil
+}
+
+func (r *Replayer) scale(d time.Duration) time.Duration {
+	return time.Duration(float64(d) / r.Speed)
+}
+
+// NewJobPod returns a pod of the job, requesting its GPUs with the scv labels. It
+// runs for the duration of the job and fails at its end if the job failed.
+func NewJobPod(job *Job, namespace, schedulerName string) *v1.Pod {
+	name := sanitizeName(job.Name)
+	labels := map[string]string{
+		nodecontroller.ManageLabelKey: nodecontroller.ManageLabelValue,
+		JobLabel:                      name,
+	}
+	for key, value := range job.Labels {
+		labels[key] = value
+	}
+	if job.GPUNumber > 0 {
+		labels[scvNumberLabel] = strconv.Itoa(job.GPUNumber)
+	}
+	if job.GPUMemory > 0 {
+		labels[scvMemoryLabel] = strconv.FormatUint(job.GPUMemory, 10)
+	}
+	annotations := map[string]string{
+		pod.DurationAnnotation: job.Duration.String(),
+	}
+	if job.Failed {
+		annotations[pod.FailureProbabilityAnnotation] = "1"
+	}
+
+	requests := v1.ResourceList{}
+	if job.CPU != nil {
+		requests[v1.ResourceCPU] = *job.CPU
+	}
+	if job.Memory != nil {
+		requests[v1.ResourceMemory] = *job.Memory
+	}
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: "trace-" + name + "-",
+			Namespace:    namespace,
+			Labels:       labels,
+			Annotations:  annotations,
+		},
+		Spec: v1.PodSpec{
+			SchedulerName: schedulerName,
+			RestartPolicy: v1.RestartPolicyNever,
+			Containers: []v1.Container{
+				{
+					Name:      replayContainer,
+					Image:     replayImage,
+					Resources: v1.ResourceRequirements{Requests: requests},
+				},
+			},
+		},
+	}
+}
+
+// sanitizeName turns the name of a trace job into a DNS label short enough for
+// generated pod names and label values.
+func sanitizeName(name string) string {
+	b := strings.Builder{}
+	for _, c := range strings.ToLower(name) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			b.WriteRune(c)
+		} else {
+			b.WriteRune('-')
+		}
+	}
+	sanitized := b.String()
+	if len(sanitized) > 40 {
+		sanitized = sanitized[:40]
+	}
+	sanitized = strings.Trim(sanitized, "-")
+	if sanitized == "" {
+		return "job"
+	}
+	return sanitized
+}
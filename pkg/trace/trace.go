@@ -0,0 +1,158 @@
+package trace
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Trace formats
+const (
+	NativeFormat = "native"
+	PhillyFormat = "philly"
+	PAIFormat    = "pai"
+	HeliosFormat = "helios"
+)
+
+// Job is a job of a trace, replayed as Replicas pods.
+type Job struct {
+	Name string
+	// Submit is when the job arrives, relative to the first job of the trace.
+	Submit time.Duration
+	// Duration is how long the pods of the job run.
+	Duration time.Duration
+	Replicas int
+	// GPUNumber is the number of cards of each pod, GPUMemory the memory it takes on
+	// each of them, 0 for whole cards.
+	GPUNumber int
+	GPUMemory uint64
+	CPU       *resource.Quantity
+	Memory    *resource.Quantity
+	Labels    map[string]string
+	// Failed jobs fail at the end of their run.
+	Failed bool
+}
+
+// Options adapt the jobs of a trace to the simulated cluster.
+type Options struct {
+	// GPUMemory is the memory of a card, to turn the fractional GPUs of a trace into GPU memory.
+	GPUMemory uint64
+	// Limit is the maximum number of jobs to read, 0 for all.
+	Limit int
+}
+
+// Load reads the jobs of a trace file in the given format, ordered by submission
+// and relative to the first one.
+func Load(path, format string, options Options) ([]Job, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var jobs []Job
+	switch format {
+	case NativeFormat, "":
+		jobs, err = parseNative(file)
+	case PhillyFormat:
+		jobs, err = parsePhilly(file)
+	case PAIFormat:
+		jobs, err = parsePAI(file, options)
+	case HeliosFormat:
+		jobs, err = parseHelios(file)
+	default:
+		return nil, fmt.Errorf("unknown trace format %q", format)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].Submit < jobs[j].Submit
+	})
+	if options.Limit > 0 && len(jobs) > options.Limit {
+		jobs = jobs[:options.Limit]
+	}
+	if len(jobs) > 0 {
+		first := jobs[0].Submit
+		for i := range jobs {
+			jobs[i].Submit -= first
+		}
+	}
+	return jobs, nil
+}
+
+// nativeJob is a line of the native trace format, e.g.
+// {"name": "resnet", "submit": "30s", "duration": "1h", "gpuNumber": 2, "gpuMemory": 8000, "cpu": "4", "memory": "16Gi"}
+type nativeJob struct {
+	Name      string            `json:"name"`
+	Submit    string            `json:"submit"`
+	Duration  string            `json:"duration"`
+	Replicas  int               `json:"replicas,omitempty"`
+	GPUNumber int               `json:"gpuNumber,omitempty"`
+	GPUMemory uint64            `json:"gpuMemory,omitempty"`
+	CPU       string            `json:"cpu,omitempty"`
+	Memory    string            `json:"memory,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Failed    bool              `json:"failed,omitempty"`
+}
+
+// parseNative reads one JSON job per line, empty lines and lines starting with # are skipped.
+func parseNative(r io.Reader) ([]Job, error) {
+	jobs := make([]Job, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		record := nativeJob{}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		job := Job{
+			Name:      record.Name,
+			Replicas:  record.Replicas,
+			GPUNumber: record.GPUNumber,
+			GPUMemory: record.GPUMemory,
+			Labels:    record.Labels,
+			Failed:    record.Failed,
+		}
+		var err error
+		if job.Submit, err = time.ParseDuration(record.Submit); err != nil {
+			return nil, fmt.Errorf("line %d: invalid submit %q", n, record.Submit)
+		}
+		if job.Duration, err = time.ParseDuration(record.Duration); err != nil {
+			return nil, fmt.Errorf("line %d: invalid duration %q", n, record.Duration)
+		}
+		if job.CPU, err = parseQuantity(record.CPU); err != nil {
+			return nil, fmt.Errorf("line %d: invalid cpu %q", n, record.CPU)
+		}
+		if job.Memory, err = parseQuantity(record.Memory); err != nil {
+			return nil, fmt.Errorf("line %d: invalid memory %q", n, record.Memory)
+		}
+		if job.Replicas <= 0 {
+			job.Replicas = 1
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, scanner.Err()
+}
+
+func parseQuantity(value string) (*resource.Quantity, error) {
+	if value == "" {
+		return nil, nil
+	}
+	q, err := resource.ParseQuantity(value)
+	if err != nil {
+		return nil, err
+	}
+	return &q, nil
+}
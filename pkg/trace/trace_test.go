@@ -0,0 +1,192 @@
+package trace
+
+import (
+	"io"
+	"io/ioutil"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func quantity(value string) *resource.Quantity {
+	q := resource.MustParse(value)
+	return &q
+}
+
+// sameQuantity reports whether two optional quantities are equal.
+func sameQuantity(a, b *resource.Quantity) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Cmp(*b) == 0
+}
+
+// checkJobs reports the differences between the jobs and the expected ones.
+func checkJobs(t *testing.T, got, want []Job) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d jobs %+v, want %d", len(got), got, len(want))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Name != w.Name || g.Submit != w.Submit || g.Duration != w.Duration || g.Replicas != w.Replicas ||
+			g.GPUNumber != w.GPUNumber || g.GPUMemory != w.GPUMemory || g.Failed != w.Failed ||
+			!sameQuantity(g.CPU, w.CPU) || !sameQuantity(g.Memory, w.Memory) || !reflect.DeepEqual(g.Labels, w.Labels) {
+			t.Errorf("job %d = %+v, want %+v", i, g, w)
+		}
+	}
+}
+
+func traceTime(value string) time.Duration {
+	t, err := parseTraceTime(value)
+	if err != nil {
+		panic(err)
+	}
+	return sinceEpoch(t)
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		parse   func(r io.Reader) ([]Job, error)
+		trace   string
+		want    []Job
+		wantErr bool
+	}{
+		{
+			name:  "native",
+			parse: parseNative,
+			trace: `# name submit duration
+{"name": "resnet", "submit": "30s", "duration": "1h", "gpuNumber": 2, "gpuMemory": 8000, "cpu": "4", "memory": "16Gi"}
+
+{"name": "bert", "submit": "1m", "duration": "10m", "replicas": 4, "labels": {"team": "nlp"}, "failed": true}
+`,
+			want: []Job{
+				{Name: "resnet", Submit: 30 * time.Second, Duration: time.Hour, Replicas: 1, GPUNumber: 2, GPUMemory: 8000, CPU: quantity("4"), Memory: quantity("16Gi")},
+				{Name: "bert", Submit: time.Minute, Duration: 10 * time.Minute, Replicas: 4, Labels: map[string]string{"team": "nlp"}, Failed: true},
+			},
+		},
+		{
+			name:    "native with invalid duration",
+			parse:   parseNative,
+			trace:   `{"name": "resnet", "submit": "30s", "duration": "long"}`,
+			wantErr: true,
+		},
+		{
+			name:  "philly",
+			parse: parsePhilly,
+			trace: `[
+{"status": "Pass", "vc": "vc1", "jobid": "application_1", "submitted_time": "2017-10-03 01:00:00",
+ "attempts": [{"start_time": "2017-10-03 01:00:30", "end_time": "2017-10-03 02:00:30",
+   "detail": [{"ip": "m1", "gpus": ["gpu0", "gpu1"]}, {"ip": "m2", "gpus": ["gpu0"]}]}]},
+{"status": "Killed", "vc": "vc1", "jobid": "application_2", "submitted_time": "2017-10-03 01:00:00", "attempts": []},
+{"status": "Failed", "vc": "vc2", "jobid": "application_3", "submitted_time": "2017-10-03 01:05:00",
+ "attempts": [{"start_time": "2017-10-03 01:05:00", "end_time": "2017-10-03 01:15:00", "detail": [{"ip": "m3", "gpus": ["gpu3"]}]}]}
+]`,
+			want: []Job{
+				{Name: "application_1", Submit: traceTime("2017-10-03 01:00:00"), Duration: time.Hour, Replicas: 2, GPUNumber: 2,
+					Labels: map[string]string{TraceLabelPrefix + "vc": "vc1"}},
+				{Name: "application_3", Submit: traceTime("2017-10-03 01:05:00"), Duration: 10 * time.Minute, Replicas: 1, GPUNumber: 1,
+					Labels: map[string]string{TraceLabelPrefix + "vc": "vc2"}, Failed: true},
+			},
+		},
+		{
+			name: "pai",
+			parse: func(r io.Reader) ([]Job, error) {
+				return parsePAI(r, Options{GPUMemory: 16000})
+			},
+			trace: `job_name,task_name,inst_num,status,start_time,end_time,plan_cpu,plan_mem,plan_gpu,gpu_type
+j1,worker,2,Terminated,100,700,600,32,50,V100
+j2,ps,1,Failed,200,,100,,,
+j3,worker,1,Failed,300,400,,,200,
+`,
+			want: []Job{
+				{Name: "j1-worker", Submit: 100 * time.Second, Duration: 10 * time.Minute, Replicas: 2, GPUNumber: 1, GPUMemory: 8000,
+					CPU: quantity("6"), Memory: quantity("32Gi"), Labels: map[string]string{TraceLabelPrefix + "gpu-type": "V100"}},
+				{Name: "j3-worker", Submit: 300 * time.Second, Duration: 100 * time.Second, Replicas: 1, GPUNumber: 2,
+					Labels: map[string]string{}, Failed: true},
+			},
+		},
+		{
+			name:  "helios without header",
+			parse: parseHelios,
+			trace: `1,u1,vcA,16,32,2,COMPLETED,2020-04-01 00:00:10,2020-04-01 00:01:00,2020-04-01 01:01:00,3600
+2,u2,vcB,1,4,1,NODE_FAIL,2020-04-01 00:02:00,2020-04-01 00:02:00,2020-04-01 00:03:00,60
+`,
+			want: []Job{
+				{Name: "1", Submit: traceTime("2020-04-01 00:00:10"), Duration: time.Hour, Replicas: 2, GPUNumber: 8, CPU: quantity("16"),
+					Labels: map[string]string{TraceLabelPrefix + "vc": "vcA"}},
+				{Name: "2", Submit: traceTime("2020-04-01 00:02:00"), Duration: time.Minute, Replicas: 1, GPUNumber: 1, CPU: quantity("4"),
+					Labels: map[string]string{TraceLabelPrefix + "vc": "vcB"}, Failed: true},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			jobs, err := test.parse(strings.NewReader(test.trace))
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("parse() = %+v, want an error", jobs)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parse() error: %v", err)
+			}
+			checkJobs(t, jobs, test.want)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trace")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "trace.jsonl")
+	trace := `{"name": "c", "submit": "3m", "duration": "1m"}
+{"name": "a", "submit": "1m", "duration": "1m"}
+{"name": "b", "submit": "2m", "duration": "1m"}
+`
+	if err := ioutil.WriteFile(path, []byte(trace), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	jobs, err := Load(path, NativeFormat, Options{Limit: 2})
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	checkJobs(t, jobs, []Job{
+		{Name: "a", Duration: time.Minute, Replicas: 1},
+		{Name: "b", Submit: time.Minute, Duration: time.Minute, Replicas: 1},
+	})
+
+	if _, err := Load(path, "alibaba", Options{}); err == nil {
+		t.Errorf("Load() with an unknown format, want an error")
+	}
+}
+
+func TestSanitizeName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "resnet50", want: "resnet50"},
+		{name: "application_1506638472019_1234", want: "application-1506638472019-1234"},
+		{name: "Job.A", want: "job-a"},
+		{name: "__", want: "job"},
+		{name: strings.Repeat("a", 50), want: strings.Repeat("a", 40)},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := sanitizeName(test.name); got != test.want {
+				t.Errorf("sanitizeName(%v) = %v, want %v", test.name, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package util
+
+import (
+	"time"
+)
+
+// minTickPeriod is the shortest wall clock period of the updaters, so that a fast
+// clock does not flood the API server.
+const minTickPeriod = time.Second
+
+// Clock is the time of the simulation. Pod durations, usage and load profiles, GPU
+// faults and utilization sampling advance in simulated time, while node heartbeats,
+// leases and the timestamps of pod statuses stay on the wall clock of the control plane.
+type Clock interface {
+	// Now returns the simulated time.
+	Now() time.Time
+	// Simulated returns the simulated time of a wall clock time, e.g. a timestamp set by the API server.
+	Simulated(t time.Time) time.Time
+	// Wall returns the wall clock time a simulated duration takes.
+	Wall(d time.Duration) time.Duration
+}
+
+// VirtualClock runs Speed times faster than the wall clock from its epoch, the wall
+// clock time at which it showed its origin.
+type VirtualClock struct {
+	epoch  time.Time
+	origin time.Time
+	speed  float64
+}
+
+// NewVirtualClock returns a clock starting now at the wall clock time, speed times
+// faster than the wall clock. A speed of 0 or less runs at the wall clock speed.
+func NewVirtualClock(speed float64) *VirtualClock {
+	now := time.Now()
+	return NewVirtualClockAt(now, now, speed)
+}
+
+// NewVirtualClockAt returns a clock showing origin at the wall clock time epoch, speed
+// times faster than the wall clock, e.g. to continue the timeline of an earlier run.
+func NewVirtualClockAt(epoch, origin time.Time, speed float64) *VirtualClock {
+	if speed <= 0 {
+		speed = 1
+	}
+	return &VirtualClock{epoch: epoch, origin: origin, speed: speed}
+}
+
+// Epoch returns the wall clock time at which the clock showed its origin.
+func (c *VirtualClock) Epoch() time.Time {
+	return c.epoch
+}
+
+// Origin returns the simulated time of the epoch.
+func (c *VirtualClock) Origin() time.Time {
+	return c.origin
+}
+
+// Speed returns how many times faster than the wall clock the clock runs.
+func (c *VirtualClock) Speed() float64 {
+	return c.speed
+}
+
+func (c *VirtualClock) Now() time.Time {
+	return c.Simulated(time.Now())
+}
+
+func (c *VirtualClock) Simulated(t time.Time) time.Time {
+	if c.speed == 1 && c.origin.Equal(c.epoch) {
+		return t
+	}
+	return c.origin.Add(time.Duration(float64(t.Sub(c.epoch)) * c.speed))
+}
+
+func (c *VirtualClock) Wall(d time.Duration) time.Duration {
+	return time.Duration(float64(d) / c.speed)
+}
+
+// clock is the clock of the simulator, set once at startup.
+var clock Clock = NewVirtualClock(1)
+
+// SetClock sets the clock of the simulator.
+func SetClock(c Clock) {
+	clock = c
+}
+
+// Now returns the simulated time.
+func Now() time.Time {
+	return clock.Now()
+}
+
+// Since returns the simulated time elapsed since t.
+func Since(t time.Time) time.Duration {
+	return clock.Now().Sub(t)
+}
+
+// Until returns the simulated duration until t.
+func Until(t time.Time) time.Duration {
+	return t.Sub(clock.Now())
+}
+
+// Simulated returns the simulated time of a wall clock time.
+func Simulated(t time.Time) time.Time {
+	return clock.Simulated(t)
+}
+
+// Elapsed returns the simulated time elapsed between two wall clock times, e.g. since
+// a container started.
+func Elapsed(from, to time.Time) time.Duration {
+	return clock.Simulated(to).Sub(clock.Simulated(from))
+}
+
+// Wall returns the wall clock time a simulated duration takes.
+func Wall(d time.Duration) time.Duration {
+	return clock.Wall(d)
+}
+
+// Sleep pauses for a simulated duration.
+func Sleep(d time.Duration) {
+	time.Sleep(clock.Wall(d))
+}
+
+// TickPeriod returns the wall clock period of an updater running every simulated
+// period, at least a second.
+func TickPeriod(period time.Duration) time.Duration {
+	if wall := clock.Wall(period); wall > minTickPeriod {
+		return wall
+	}
+	return minTickPeriod
+}
+
+// Tick pauses an updater running every simulated period.
+func Tick(period time.Duration) {
+	time.Sleep(TickPeriod(period))
+}
@@ -0,0 +1,96 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	v1core "k8s.io/client-go/kubernetes/typed/core/v1"
+	"strconv"
+	"time"
+)
+
+// Keys of the ConfigMap keeping the clock of the simulator
+const (
+	clockEpochKey  = "epoch"
+	clockOriginKey = "origin"
+	clockSpeedKey  = "speed"
+)
+
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;create;update
+
+// LoadClock returns the clock kept in the ConfigMap namespace/name, so that simulated
+// time goes on across restarts of the simulator instead of starting over at the wall
+// clock time. A clock running at another speed is re-anchored at its current simulated
+// time, and a missing ConfigMap is created with a clock starting now.
+func LoadClock(ctx context.Context, c v1core.ConfigMapsGetter, namespace, name string, speed float64) (*VirtualClock, error) {
+	configMap, err := c.ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		clock := NewVirtualClock(speed)
+		configMap = &v1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: namespace,
+				Name:      name,
+			},
+			Data: clockData(clock),
+		}
+		_, err = c.ConfigMaps(namespace).Create(ctx, configMap, metav1.CreateOptions{})
+		return clock, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := parseClock(configMap.Data)
+	if err != nil {
+		return nil, fmt.Errorf("ConfigMap %v/%v: %v", namespace, name, err)
+	}
+	clock := NewVirtualClockAt(stored.Epoch(), stored.Origin(), speed)
+	if clock.Speed() == stored.Speed() {
+		return clock, nil
+	}
+
+	now := time.Now()
+	clock = NewVirtualClockAt(now, stored.Simulated(now), speed)
+	configMap.Data = clockData(clock)
+	_, err = c.ConfigMaps(namespace).Update(ctx, configMap, metav1.UpdateOptions{})
+	return clock, err
+}
+
+// ReadClock returns the clock kept in the ConfigMap namespace/name by a running simulator.
+func ReadClock(ctx context.Context, c v1core.ConfigMapsGetter, namespace, name string) (*VirtualClock, error) {
+	configMap, err := c.ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	clock, err := parseClock(configMap.Data)
+	if err != nil {
+		return nil, fmt.Errorf("ConfigMap %v/%v: %v", namespace, name, err)
+	}
+	return clock, nil
+}
+
+func clockData(clock *VirtualClock) map[string]string {
+	return map[string]string{
+		clockEpochKey:  clock.Epoch().Format(time.RFC3339Nano),
+		clockOriginKey: clock.Origin().Format(time.RFC3339Nano),
+		clockSpeedKey:  strconv.FormatFloat(clock.Speed(), 'g', -1, 64),
+	}
+}
+
+func parseClock(data map[string]string) (*VirtualClock, error) {
+	epoch, err := time.Parse(time.RFC3339Nano, data[clockEpochKey])
+	if err != nil {
+		return nil, fmt.Errorf("invalid %v %q", clockEpochKey, data[clockEpochKey])
+	}
+	origin, err := time.Parse(time.RFC3339Nano, data[clockOriginKey])
+	if err != nil {
+		return nil, fmt.Errorf("invalid %v %q", clockOriginKey, data[clockOriginKey])
+	}
+	speed, err := strconv.ParseFloat(data[clockSpeedKey], 64)
+	if err != nil || speed <= 0 {
+		return nil, fmt.Errorf("invalid %v %q", clockSpeedKey, data[clockSpeedKey])
+	}
+	return NewVirtualClockAt(epoch, origin, speed), nil
+}
@@ -0,0 +1,107 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVirtualClock(t *testing.T) {
+	epoch := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+	origin := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		clock     *VirtualClock
+		wall      time.Time
+		simulated time.Time
+		duration  time.Duration
+		wallTime  time.Duration
+	}{
+		{
+			name:      "wall clock speed",
+			clock:     NewVirtualClockAt(epoch, epoch, 1),
+			wall:      epoch.Add(time.Hour),
+			simulated: epoch.Add(time.Hour),
+			duration:  time.Minute,
+			wallTime:  time.Minute,
+		},
+		{
+			name:      "ten times faster",
+			clock:     NewVirtualClockAt(epoch, epoch, 10),
+			wall:      epoch.Add(time.Hour),
+			simulated: epoch.Add(10 * time.Hour),
+			duration:  time.Minute,
+			wallTime:  6 * time.Second,
+		},
+		{
+			name:      "before the epoch",
+			clock:     NewVirtualClockAt(epoch, epoch, 10),
+			wall:      epoch.Add(-time.Minute),
+			simulated: epoch.Add(-10 * time.Minute),
+			duration:  time.Minute,
+			wallTime:  6 * time.Second,
+		},
+		{
+			name:      "continued timeline",
+			clock:     NewVirtualClockAt(epoch, origin, 2),
+			wall:      epoch.Add(time.Hour),
+			simulated: origin.Add(2 * time.Hour),
+			duration:  time.Hour,
+			wallTime:  30 * time.Minute,
+		},
+		{
+			name:      "invalid speed runs at the wall clock speed",
+			clock:     NewVirtualClockAt(epoch, epoch, 0),
+			wall:      epoch.Add(time.Hour),
+			simulated: epoch.Add(time.Hour),
+			duration:  time.Minute,
+			wallTime:  time.Minute,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := test.clock.Simulated(test.wall); !got.Equal(test.simulated) {
+				t.Errorf("Simulated(%v) = %v, want %v", test.wall, got, test.simulated)
+			}
+			if got := test.clock.Wall(test.duration); got != test.wallTime {
+				t.Errorf("Wall(%v) = %v, want %v", test.duration, got, test.wallTime)
+			}
+		})
+	}
+}
+
+func TestTickPeriod(t *testing.T) {
+	defer SetClock(clock)
+	tests := []struct {
+		name   string
+		speed  float64
+		period time.Duration
+		want   time.Duration
+	}{
+		{
+			name:   "wall clock speed",
+			speed:  1,
+			period: 10 * time.Second,
+			want:   10 * time.Second,
+		},
+		{
+			name:   "faster clock",
+			speed:  5,
+			period: 10 * time.Second,
+			want:   2 * time.Second,
+		},
+		{
+			name:   "at least a second",
+			speed:  100,
+			period: 10 * time.Second,
+			want:   time.Second,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			SetClock(NewVirtualClock(test.speed))
+			if got := TickPeriod(test.period); got != test.want {
+				t.Errorf("TickPeriod(%v) = %v, want %v", test.period, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Load profile types
+const (
+	ConstantLoad   = "constant"
+	PeriodicLoad   = "periodic"
+	RandomWalkLoad = "randomWalk"
+	TraceLoad      = "trace"
+	NormalLoad     = "normal"
+	UniformLoad    = "uniform"
+	RampLoad       = "ramp"
+)
+
+// LoadProfile describes how a load level evolves over time. Levels are fractions,
+// of a GPU share or of the requests of a pod, and never negative.
+type LoadProfile struct {
+	// Type is one of constant, periodic, randomWalk, trace, normal, uniform and ramp.
+	Type string `json:"type"`
+	// Level is the constant level, the mean of a periodic or normal load and the start of a random walk or ramp.
+	Level float64 `json:"level"`
+	// Amplitude and Period shape a periodic load: Level + Amplitude * sin(2π t / Period).
+	Amplitude float64 `json:"amplitude,omitempty"`
+	Period    string  `json:"period,omitempty"`
+	// Step is the largest change of a random walk between two samples.
+	Step float64 `json:"step,omitempty"`
+	// Trace lists the levels of a trace-driven load, one every Interval, replayed in a loop.
+	Trace    []float64 `json:"trace,omitempty"`
+	Interval string    `json:"interval,omitempty"`
+	// Stddev is the standard deviation of a normal load.
+	Stddev float64 `json:"stddev,omitempty"`
+	// Min and Max bound a uniform load.
+	Min float64 `json:"min,omitempty"`
+	Max float64 `json:"max,omitempty"`
+	// A ramp goes linearly from Level to Target in Duration, then stays at Target.
+	Target   float64 `json:"target,omitempty"`
+	Duration string  `json:"duration,omitempty"`
+	// Spike raises the load of any type periodically.
+	Spike *LoadSpike `json:"spike,omitempty"`
+
+	period   time.Duration
+	interval time.Duration
+	duration time.Duration
+}
+
+// LoadSpike raises a load to Level for Duration every Every, e.g. {"every": "10m", "duration": "30s", "level": 1.5}.
+type LoadSpike struct {
+	Every    string  `json:"every"`
+	Duration string  `json:"duration"`
+	Level    float64 `json:"level"`
+
+	every    time.Duration
+	duration time.Duration
+}
+
+// ParseLoadProfile parses a JSON load profile, e.g. {"type": "periodic", "level": 0.5, "amplitude": 0.3, "period": "10m"}.
+func ParseLoadProfile(value string) (*LoadProfile, error) {
+	profile := &LoadProfile{}
+	if err := json.Unmarshal([]byte(value), profile); err != nil {
+		return nil, err
+	}
+
+	var err error
+	switch profile.Type {
+	case ConstantLoad, RandomWalkLoad:
+	case PeriodicLoad:
+		if profile.period, err = time.ParseDuration(profile.Period); err != nil || profile.period <= 0 {
+			return nil, fmt.Errorf("invalid period %q of periodic load", profile.Period)
+		}
+	case TraceLoad:
+		if len(profile.Trace) == 0 {
+			return nil, fmt.Errorf("trace load without levels")
+		}
+		if profile.interval, err = time.ParseDuration(profile.Interval); err != nil || profile.interval <= 0 {
+			return nil, fmt.Errorf("invalid interval %q of trace load", profile.Interval)
+		}
+	case NormalLoad:
+		if profile.Stddev < 0 {
+			return nil, fmt.Errorf("negative stddev of normal load")
+		}
+	case UniformLoad:
+		if profile.Max < profile.Min {
+			return nil, fmt.Errorf("max %v of uniform load below min %v", profile.Max, profile.Min)
+		}
+	case RampLoad:
+		if profile.duration, err = time.ParseDuration(profile.Duration); err != nil || profile.duration <= 0 {
+			return nil, fmt.Errorf("invalid duration %q of ramp load", profile.Duration)
+		}
+	default:
+		return nil, fmt.Errorf("unknown load type %q", profile.Type)
+	}
+
+	if spike := profile.Spike; spike != nil {
+		if spike.every, err = time.ParseDuration(spike.Every); err != nil || spike.every <= 0 {
+			return nil, fmt.Errorf("invalid spike interval %q", spike.Every)
+		}
+		if spike.duration, err = time.ParseDuration(spike.Duration); err != nil || spike.duration <= 0 || spike.duration > spike.every {
+			return nil, fmt.Errorf("invalid spike duration %q", spike.Duration)
+		}
+	}
+	return profile, nil
+}
+
+// LevelAt returns the load level after elapsed time. A random walk moves on from
+// the last level it returned, which the caller keeps. Random loads draw from rnd.
+// A negative elapsed time, e.g. of a pod started after a clock reset, counts as 0.
+func (p *LoadProfile) LevelAt(elapsed time.Duration, last float64, rnd *rand.Rand) float64 {
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	level := p.Level
+	switch p.Type {
+	case PeriodicLoad:
+		level = p.Level + p.Amplitude*math.Sin(2*math.Pi*float64(elapsed)/float64(p.period))
+	case RandomWalkLoad:
+		if elapsed > 0 {
+			level = last + (rnd.Float64()*2-1)*p.Step
+		}
+	case TraceLoad:
+		level = p.Trace[int(elapsed/p.interval)%len(p.Trace)]
+	case NormalLoad:
+		level = p.Level + rnd.NormFloat64()*p.Stddev
+	case UniformLoad:
+		level = p.Min + rnd.Float64()*(p.Max-p.Min)
+	case RampLoad:
+		if elapsed >= p.duration {
+			level = p.Target
+		} else if elapsed > 0 {
+			level = p.Level + (p.Target-p.Level)*float64(elapsed)/float64(p.duration)
+		}
+	}
+	if p.Spike != nil && elapsed%p.Spike.every < p.Spike.duration && elapsed >= p.Spike.every {
+		level = math.Max(level, p.Spike.Level)
+	}
+	return math.Max(0, level)
+}
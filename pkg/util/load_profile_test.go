@@ -0,0 +1,197 @@
+package util
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestParseLoadProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{
+			name:  "constant",
+			value: `{"type": "constant", "level": 0.5}`,
+		},
+		{
+			name:  "periodic",
+			value: `{"type": "periodic", "level": 0.5, "amplitude": 0.3, "period": "10m"}`,
+		},
+		{
+			name:    "periodic without period",
+			value:   `{"type": "periodic", "level": 0.5, "amplitude": 0.3}`,
+			wantErr: true,
+		},
+		{
+			name:    "trace without levels",
+			value:   `{"type": "trace", "interval": "1m"}`,
+			wantErr: true,
+		},
+		{
+			name:    "trace with zero interval",
+			value:   `{"type": "trace", "trace": [0.1], "interval": "0s"}`,
+			wantErr: true,
+		},
+		{
+			name:    "spike longer than its interval",
+			value:   `{"type": "constant", "level": 0.5, "spike": {"every": "1m", "duration": "2m", "level": 1}}`,
+			wantErr: true,
+		},
+		{
+			name:    "unknown type",
+			value:   `{"type": "sawtooth"}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid JSON",
+			value:   `{"type":`,
+			wantErr: true,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ParseLoadProfile(test.value)
+			if (err != nil) != test.wantErr {
+				t.Errorf("ParseLoadProfile(%v) error = %v, wantErr %v", test.value, err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestLevelAt(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile string
+		elapsed time.Duration
+		last    float64
+		want    float64
+	}{
+		{
+			name:    "constant",
+			profile: `{"type": "constant", "level": 0.5}`,
+			elapsed: time.Hour,
+			want:    0.5,
+		},
+		{
+			name:    "periodic at a quarter period",
+			profile: `{"type": "periodic", "level": 0.5, "amplitude": 0.3, "period": "4m"}`,
+			elapsed: time.Minute,
+			want:    0.8,
+		},
+		{
+			name:    "periodic never below zero",
+			profile: `{"type": "periodic", "level": 0.1, "amplitude": 0.3, "period": "4m"}`,
+			elapsed: 3 * time.Minute,
+			want:    0,
+		},
+		{
+			name:    "trace",
+			profile: `{"type": "trace", "trace": [0.1, 0.2, 0.3], "interval": "1m"}`,
+			elapsed: 90 * time.Second,
+			want:    0.2,
+		},
+		{
+			name:    "trace replayed in a loop",
+			profile: `{"type": "trace", "trace": [0.1, 0.2, 0.3], "interval": "1m"}`,
+			elapsed: 3 * time.Minute,
+			want:    0.1,
+		},
+		{
+			name:    "trace before its start",
+			profile: `{"type": "trace", "trace": [0.1, 0.2, 0.3], "interval": "1m"}`,
+			elapsed: -90 * time.Second,
+			want:    0.1,
+		},
+		{
+			name:    "ramp before its start",
+			profile: `{"type": "ramp", "level": 0.2, "target": 0.8, "duration": "10m"}`,
+			elapsed: -time.Minute,
+			want:    0.2,
+		},
+		{
+			name:    "random walk starts at its level",
+			profile: `{"type": "randomWalk", "level": 0.4, "step": 0.1}`,
+			last:    0.9,
+			want:    0.4,
+		},
+		{
+			name:    "ramp halfway",
+			profile: `{"type": "ramp", "level": 0.2, "target": 0.8, "duration": "10m"}`,
+			elapsed: 5 * time.Minute,
+			want:    0.5,
+		},
+		{
+			name:    "ramp done",
+			profile: `{"type": "ramp", "level": 0.2, "target": 0.8, "duration": "10m"}`,
+			elapsed: time.Hour,
+			want:    0.8,
+		},
+		{
+			name:    "spike",
+			profile: `{"type": "constant", "level": 0.5, "spike": {"every": "10m", "duration": "30s", "level": 1.5}}`,
+			elapsed: 10*time.Minute + 10*time.Second,
+			want:    1.5,
+		},
+		{
+			name:    "between spikes",
+			profile: `{"type": "constant", "level": 0.5, "spike": {"every": "10m", "duration": "30s", "level": 1.5}}`,
+			elapsed: 11 * time.Minute,
+			want:    0.5,
+		},
+		{
+			name:    "no spike at the start",
+			profile: `{"type": "constant", "level": 0.5, "spike": {"every": "10m", "duration": "30s", "level": 1.5}}`,
+			want:    0.5,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			profile, err := ParseLoadProfile(test.profile)
+			if err != nil {
+				t.Fatalf("ParseLoadProfile(%v) error: %v", test.profile, err)
+			}
+			if got := profile.LevelAt(test.elapsed, test.last, rand.New(rand.NewSource(1))); math.Abs(got-test.want) > 1e-9 {
+				t.Errorf("LevelAt(%v) = %v, want %v", test.elapsed, got, test.want)
+			}
+		})
+	}
+}
+
+func TestRandomLevelsInRange(t *testing.T) {
+	tests := []struct {
+		name     string
+		profile  string
+		min, max float64
+	}{
+		{
+			name:    "random walk step",
+			profile: `{"type": "randomWalk", "level": 0.5, "step": 0.1}`,
+			min:     0.4,
+			max:     0.6,
+		},
+		{
+			name:    "uniform",
+			profile: `{"type": "uniform", "min": 0.2, "max": 0.4}`,
+			min:     0.2,
+			max:     0.4,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			profile, err := ParseLoadProfile(test.profile)
+			if err != nil {
+				t.Fatalf("ParseLoadProfile(%v) error: %v", test.profile, err)
+			}
+			rnd := rand.New(rand.NewSource(1))
+			for i := 0; i < 100; i++ {
+				if got := profile.LevelAt(time.Minute, 0.5, rnd); got < test.min || got > test.max {
+					t.Fatalf("LevelAt() = %v, want within [%v, %v]", got, test.min, test.max)
+				}
+			}
+		})
+	}
+}
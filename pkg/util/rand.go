@@ -0,0 +1,35 @@
+package util
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"math/rand"
+)
+
+// seed is the seed of the simulator, set once at startup.
+var seed int64
+
+// SetSeed sets the seed of every random decision of the simulator.
+func SetSeed(s int64) {
+	seed = s
+}
+
+// Seed returns the seed of the simulator.
+func Seed() int64 {
+	return seed
+}
+
+// NewRand returns the random source of the decision named by keys, e.g. a pod and
+// the update period it is made in. The same seed and keys give the same source in
+// every run, so decisions do not depend on the order the updaters make them in.
+func NewRand(keys ...string) *rand.Rand {
+	h := fnv.New64a()
+	b := make([]byte, 8)
+	binary.LittleEndian.PutUint64(b, uint64(seed))
+	_, _ = h.Write(b)
+	for _, key := range keys {
+		_, _ = h.Write([]byte(key))
+		_, _ = h.Write([]byte{0})
+	}
+	return rand.New(rand.NewSource(int64(h.Sum64())))
+}
@@ -0,0 +1,48 @@
+package util
+
+import "testing"
+
+func TestNewRand(t *testing.T) {
+	defer SetSeed(Seed())
+	draw := func(seed int64, keys []string) int64 {
+		SetSeed(seed)
+		return NewRand(keys...).Int63()
+	}
+
+	tests := []struct {
+		name     string
+		seeds    [2]int64
+		keys     [2][]string
+		wantSame bool
+	}{
+		{
+			name:     "same seed and keys",
+			seeds:    [2]int64{1, 1},
+			keys:     [2][]string{{"pod", "10"}, {"pod", "10"}},
+			wantSame: true,
+		},
+		{
+			name:  "other seed",
+			seeds: [2]int64{1, 2},
+			keys:  [2][]string{{"pod"}, {"pod"}},
+		},
+		{
+			name:  "other key",
+			seeds: [2]int64{1, 1},
+			keys:  [2][]string{{"pod-a"}, {"pod-b"}},
+		},
+		{
+			name:  "keys not concatenated",
+			seeds: [2]int64{1, 1},
+			keys:  [2][]string{{"ab", "c"}, {"a", "bc"}},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			a, b := draw(test.seeds[0], test.keys[0]), draw(test.seeds[1], test.keys[1])
+			if (a == b) != test.wantSame {
+				t.Errorf("NewRand(%v) drew %v, NewRand(%v) drew %v", test.keys[0], a, test.keys[1], b)
+			}
+		})
+	}
+}